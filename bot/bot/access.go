@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+)
+
+const (
+	roleContextKey = "role"
+
+	forbiddenMessage = "⛔ На это у тебя нет прав."
+)
+
+// rememberName сохраняет имя отправителя из профиля Telegram. names — имена, уже
+// сохраненные в базе: пишем в нее, только если имя сменилось.
+func rememberName(ctx context.Context, db Database, names *sync.Map, sender *telebot.User) {
+	name := displayName(sender)
+	if saved, ok := names.Load(sender.ID); name == "" || (ok && saved == name) {
+		return
+	}
+
+	err := db.SetUserName(ctx, sender.ID, name)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.SetUserName", "error", err)
+
+		return
+	}
+
+	names.Store(sender.ID, name)
+}
+
+// accessMiddleware пускает только пользователей с ролью и запоминает роль для обработчиков.
+// Незнакомому пользователю в личке бот подсказывает его ID, в группах молчит.
+func accessMiddleware(ctx context.Context, db Database) telebot.MiddlewareFunc {
+	var names sync.Map
+
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			sender := c.Sender()
+			if sender == nil {
+				return nil
+			}
+
+			role, err := db.UserRole(ctx, sender.ID)
+			if errors.Is(err, model.ErrNotFound) {
+				slog.WarnContext(ctx, "access denied", "user_id", sender.ID)
+
+				if isGroupChat(c) || c.Callback() != nil {
+					return nil
+				}
+
+				return c.Send("⛔ У тебя пока нет доступа. Передай владельцу свой ID <code>"+
+					strconv.FormatInt(sender.ID, 10)+"</code>, чтобы он добавил тебя через /users.",
+					&telebot.SendOptions{ParseMode: telebot.ModeHTML})
+			}
+
+			if err != nil {
+				slog.ErrorContext(ctx, "database.UserRole", "error", err)
+
+				return c.Send("❌ Не получилось проверить доступ, может, еще разок попробуем?")
+			}
+
+			c.Set(roleContextKey, role)
+			rememberName(ctx, db, &names, sender)
+
+			return next(c)
+		}
+	}
+}
+
+// currentRole возвращает роль, найденную accessMiddleware. Без нее прав нет.
+func currentRole(c telebot.Context) model.Role {
+	role, _ := c.Get(roleContextKey).(model.Role)
+
+	return role
+}
+
+// can сообщает, есть ли у отправителя разрешение.
+func can(c telebot.Context, permission model.Permission) bool {
+	return currentRole(c).Can(permission)
+}
+
+// requires пропускает команду, только если у отправителя есть разрешение.
+func requires(permission model.Permission, next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		if !can(c, permission) {
+			return c.Send(forbiddenMessage)
+		}
+
+		return next(c)
+	}
+}
+
+// editableExpense возвращает трату текущего проекта, если отправитель может ее исправить,
+// иначе model.ErrForbidden.
+func editableExpense(ctx context.Context, c telebot.Context, db Database, id model.ExpenseID) (model.Expense, error) {
+	expense, err := projectExpense(ctx, c, db, id)
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	if !currentRole(c).CanEdit(c.Sender().ID, expense) {
+		return model.Expense{}, model.ErrForbidden
+	}
+
+	return expense, nil
+}
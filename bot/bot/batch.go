@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+// lineErrorMessages — короткие причины для построчного ответа на пачку трат.
+var lineErrorMessages = map[error]string{ //nolint:gochecknoglobals
+	parser.ErrNotEnoughData:       "мало данных",
+	parser.ErrPaymentTypeNotFound: "не понял, как заплатил: нал или карта",
+	parser.ErrAmountNotFound:      "не нашел сумму",
+}
+
+func lineError(err error) string {
+	for target, msg := range lineErrorMessages {
+		if errors.Is(err, target) {
+			return msg
+		}
+	}
+
+	return "не понял строку"
+}
+
+func formatBatchHTML(p *message.Printer, categories *model.CategoryRegistry, lines []parser.Line,
+	loc *time.Location) string {
+	var (
+		sb         strings.Builder
+		saved      int
+		totals     = make(map[string]decimal.Decimal)
+		currencies []string
+	)
+
+	// Траты в разных валютах не складываем: итог по каждой валюте отдельно.
+	for _, line := range lines {
+		if line.Err == nil {
+			saved++
+
+			if _, ok := totals[line.Expense.Currency]; !ok {
+				currencies = append(currencies, line.Expense.Currency)
+			}
+
+			totals[line.Expense.Currency] = totals[line.Expense.Currency].Add(line.Expense.Amount)
+		}
+	}
+
+	sb.WriteString("<b>✅ Записал ")
+	sb.WriteString(strconv.Itoa(saved))
+	sb.WriteString(" из ")
+	sb.WriteString(strconv.Itoa(len(lines)))
+
+	for i, currency := range currencies {
+		if i == 0 {
+			sb.WriteString(" на ")
+		} else {
+			sb.WriteString(" + ")
+		}
+
+		sb.WriteString(html.EscapeString(formatMoney(p, totals[currency], currency)))
+	}
+
+	sb.WriteString(":</b>\n\n")
+
+	for _, line := range lines {
+		sb.WriteString(strconv.Itoa(line.Number))
+		sb.WriteString(". ")
+
+		if line.Err != nil {
+			sb.WriteString("❌ ")
+			sb.WriteString(html.EscapeString(line.Text))
+			sb.WriteString(" — ")
+			sb.WriteString(lineError(line.Err))
+			sb.WriteByte('\n')
+
+			continue
+		}
+
+		e := line.Expense
+
+		sb.WriteString("✅ ")
+		sb.WriteString(html.EscapeString(formatMoney(p, e.Amount, e.Currency)))
+		sb.WriteString(", ")
+		sb.WriteString(html.EscapeString(e.PaymentType.String()))
+		sb.WriteString(", ")
+		sb.WriteString(html.EscapeString(categories.Name(e.Category)))
+
+		if e.Description != "" {
+			sb.WriteString(" — ")
+			sb.WriteString(html.EscapeString(e.Description))
+		}
+
+		sb.WriteString(", ")
+		sb.WriteString(html.EscapeString(formatExpenseDate(e.CreatedAt, time.Now(), loc)))
+		sb.WriteString("\n<code>")
+		sb.WriteString(e.ID.String())
+		sb.WriteString("</code>\n")
+	}
+
+	return sb.String()
+}
+
+// batchBudgetAlerts проверяет бюджеты категорий, в которые попали траты пачки.
+// Траты не в валюте проекта currency пропускаются, как и в confirmExpense.
+func batchBudgetAlerts(ctx context.Context, db Database, p *message.Printer, expenses model.Expenses,
+	currency string) []string {
+	added := make(map[model.Category]decimal.Decimal)
+	order := make([]model.Category, 0, len(expenses))
+
+	for _, e := range expenses {
+		if e.Currency != currency {
+			continue
+		}
+
+		if _, ok := added[e.Category]; !ok {
+			order = append(order, e.Category)
+		}
+
+		added[e.Category] = added[e.Category].Add(e.Amount)
+	}
+
+	var alerts []string
+
+	for _, category := range order {
+		budget, err := db.Budget(ctx, expenses[0].ProjectID, category)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.Budget", "error", err)
+
+			continue
+		}
+
+		if alert := budgetAlert(p, db.CategoryRegistry(), budget, added[category], currency); alert != "" {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts
+}
+
+// saveBatch записывает все разобранные строки одной транзакцией и отвечает построчно:
+// какие строки записаны, а какие нет и почему.
+func saveBatch(ctx context.Context, c telebot.Context, db Database, p *message.Printer,
+	loc *time.Location, lines []parser.Line) error {
+	expenses := make(model.Expenses, 0, len(lines))
+
+	for i := range lines {
+		if lines[i].Err != nil {
+			continue
+		}
+
+		lines[i].Expense.UserID = c.Sender().ID
+		lines[i].Expense.ProjectID = currentProject(c).ID
+		lines[i].Expense.Currency = cmp.Or(lines[i].Expense.Currency, currentProject(c).Currency)
+		expenses = append(expenses, lines[i].Expense)
+	}
+
+	if len(expenses) > 0 {
+		err := db.InsertMany(actorContext(ctx, c), expenses)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.InsertMany", "error", err)
+
+			return c.Send("❌ Не получилось записать, может, еще разок попробуем?")
+		}
+	}
+
+	err := c.Send(formatBatchHTML(p, db.CategoryRegistry(), lines, loc), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+	if err != nil || len(expenses) == 0 {
+		return err
+	}
+
+	for _, alert := range batchBudgetAlerts(ctx, db, p, expenses, currentProject(c).Currency) {
+		err := c.Send(alert, &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
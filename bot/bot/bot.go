@@ -1,31 +1,91 @@
 package bot
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"kudadeli/model"
 	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 	"gopkg.in/telebot.v3"
-	"gopkg.in/telebot.v3/middleware"
 
+	"kudadeli/database"
 	"kudadeli/parser"
 )
 
 type Database interface {
 	Insert(ctx context.Context, expense model.Expense) error
-	List(ctx context.Context, limit int) (model.Expenses, error)
+	InsertMany(ctx context.Context, expenses model.Expenses) error
+	Get(ctx context.Context, id model.ExpenseID) (model.Expense, error)
+	Update(ctx context.Context, expense model.Expense) error
+	UpdateCategory(ctx context.Context, expenseID model.ExpenseID, category model.Category) error
 	Delete(ctx context.Context, id model.ExpenseID) error
+	GetDeleted(ctx context.Context, id model.ExpenseID) (model.Expense, error)
+	Restore(ctx context.Context, id model.ExpenseID) error
+	History(ctx context.Context, id model.ExpenseID) ([]model.HistoryEntry, error)
+	Users(ctx context.Context) ([]model.User, error)
+	UserRole(ctx context.Context, userID int64) (model.Role, error)
+	SetUserRole(ctx context.Context, userID int64, role model.Role) error
+	SetUserName(ctx context.Context, userID int64, name string) error
+	RemoveUser(ctx context.Context, userID int64) error
+	CreateAPIToken(ctx context.Context, userID int64, name string, scope model.TokenScope,
+		expiresAt *time.Time) (model.APIToken, string, error)
+	UserAPITokens(ctx context.Context, userID int64) ([]model.APIToken, error)
+	RevokeAPIToken(ctx context.Context, userID, id int64) error
+	SetBudget(ctx context.Context, projectID model.ProjectID, category model.Category, amount decimal.Decimal) error
+	Budgets(ctx context.Context, projectID model.ProjectID) (model.Budgets, error)
+	Budget(ctx context.Context, projectID model.ProjectID, category model.Category) (model.Budget, error)
+	Report(ctx context.Context, filter database.ExpenseFilter, top int) (model.Report, error)
+	SetShares(ctx context.Context, projectID model.ProjectID, shares map[int64]int64) error
+	Shares(ctx context.Context, projectID model.ProjectID) (map[int64]int64, error)
+	Settlement(ctx context.Context, projectID model.ProjectID, filter database.ExpenseFilter) (model.Settlement, error)
+	SetRates(ctx context.Context, rates []model.ExchangeRate) error
+	LatestRates(ctx context.Context, base string) ([]model.ExchangeRate, error)
+	CreateRecurring(ctx context.Context, r model.RecurringExpense) (model.RecurringExpense, error)
+	Recurring(ctx context.Context, id model.RecurringID) (model.RecurringExpense, error)
+	ProjectRecurring(ctx context.Context, projectID model.ProjectID) ([]model.RecurringExpense, error)
+	DueRecurring(ctx context.Context, now time.Time) ([]model.RecurringExpense, error)
+	UpdateRecurring(ctx context.Context, r model.RecurringExpense) error
+	SetRecurringNextRun(ctx context.Context, id model.RecurringID, next time.Time) error
+	DeleteRecurring(ctx context.Context, id model.RecurringID) error
+	SetDigest(ctx context.Context, d model.Digest) error
+	Digest(ctx context.Context, userID int64) (model.Digest, error)
+	DueDigests(ctx context.Context, now time.Time) ([]model.Digest, error)
+	SetDigestNextRun(ctx context.Context, userID int64, next time.Time) error
+	DeleteDigest(ctx context.Context, userID int64) error
+	Find(ctx context.Context, filter database.ExpenseFilter) (model.Expenses, string, error)
+	Import(ctx context.Context, expenses model.Expenses, loc *time.Location, dryRun bool) ([]int, error)
+	AddAttachment(ctx context.Context, attachment model.Attachment, data []byte) error
+	Attachments(ctx context.Context, expenseID model.ExpenseID) ([]model.Attachment, error)
+	Attachment(ctx context.Context, expenseID model.ExpenseID, id model.AttachmentID) (model.Attachment, []byte, error)
+	CreateProject(ctx context.Context, name, currency string, ownerID int64) (model.Project, error)
+	Project(ctx context.Context, id model.ProjectID) (model.Project, error)
+	UserProjects(ctx context.Context, userID int64) ([]model.Project, error)
+	AddProjectMember(ctx context.Context, projectID model.ProjectID, userID int64) error
+	IsProjectMember(ctx context.Context, projectID model.ProjectID, userID int64) (bool, error)
+	SetActiveProject(ctx context.Context, userID int64, projectID model.ProjectID) error
+	ActiveProject(ctx context.Context, userID int64) (model.Project, error)
+	LinkChat(ctx context.Context, chatID int64, projectID model.ProjectID) error
+	ChatProject(ctx context.Context, chatID int64) (model.Project, error)
+	AddCategory(ctx context.Context, category model.CategoryInfo) (model.CategoryInfo, error)
+	EditCategory(ctx context.Context, category model.CategoryInfo) error
+	CategoryRegistry() *model.CategoryRegistry
 }
 
 type Service struct {
 	bot *telebot.Bot
+	db  Database
+	p   *message.Printer
+	loc *time.Location
 }
 
 const (
@@ -39,23 +99,69 @@ const (
    👉 нал 1500 краска ванная
    👉 карта 3200 двери
    👉 нал 5000 услуги демонтаж
+   👉 карта 1,5к плитка или нал 2x750 мешки — суммы можно писать как удобно:
+      1 500, 1500р, 15 тыс, 1200+350
+   👉 карта $20 шпатель или нал 15 евро кофе — трата в другой валюте,
+      в отчетах пересчитается в валюту проекта по курсу (/rate)
+
+   Несколько трат — по одной на строку в одном сообщении.
+
+   📷 Фото чека с подписью «карта 3200 двери» — запишу трату и сохраню чек.
+   Фото в ответ на мое «Записал» — прикреплю чек к этой трате.
+
+   👥 В группе читаю только траты, записанные строго как «нал 1500 краска», и сообщения
+   для меня: с префиксом («+ карта 3200 двери»), с упоминанием или ответом на мое сообщение.
+   Трата записывается на того, кто ее прислал, в проект группы (/project [ID]).
+   Если у меня включен режим приватности, пишите /add нал 1500 краска.
 
 2. Ключевые слова:
    - "нал" или "наличные" — наличная оплата
    - "карта" — оплата по карте
-   - "услуги", "материалы", "инструменты", "мебель" — категория (опционально)
+   - "услуги", "материалы", "инструменты", "мебель", "прочее" — категория (опционально),
+     список и ключевые слова категорий — /category
+   - "вчера", "позавчера", "12.10", "12.10.2026", "в пятницу", "2 дня назад" — дата,
+     если трата была не сегодня (опционально)
    - Остальное — описание
 
 3. Команды:
    /help — показать эту справку
-   /list [N] — показать последние [N] трат`
+   /add [трата] — записать трату командой
+   /list [N] — показать последние [N] трат
+   /delete [ID] — удалить трату (она попадет в корзину)
+   /trash [N] — последние удаленные траты, /restore [ID] — вернуть трату из корзины
+   /edit [ID] [тип_оплаты] [сумма] [категория] [описание] — исправить трату
+     (или ответь на сообщение «Записал» исправленным текстом)
+   /receipt [ID] — показать чеки траты
+   /history [ID] — кто, когда и откуда менял трату
+   /report [период] — отчет: сегодня, неделя, месяц, все или 01.05.2025-31.05.2025
+   /export [csv|xlsx] [период] — выгрузить траты файлом
+   Пришли CSV-файл (колонки как в /export), чтобы импортировать траты;
+     с подписью «проверка» файл только проверится, без записи
+   /settle [период] — кто сколько заплатил и кто кому переводит, чтобы все внесли по своей доле,
+     /settle share [ID]:[доля] ... — доли участников, например 111:70 222:30 (по умолчанию поровну)
+   /budget — бюджеты по категориям
+   /budget [категория] [сумма] — задать бюджет категории (0 — убрать)
+   /rate — курсы валют, /rate [валюта] [курс] [дата] — задать курс к валюте проекта
+   /recurring — повторяющиеся траты: /recurring add [расписание] | [трата] — добавить,
+     расписание — cron («0 9 * * 5» — по пятницам в 9:00) или ежедневно, еженедельно, ежемесячно;
+     в срок пришлю трату в личку на подтверждение
+   /digest 09:00 [часовой пояс] — сводка каждое утро: вчерашние траты, итоги недели и бюджеты,
+     /digest weekly 09:00 — по понедельникам, /digest off — выключить
+   /category — категории: /category add [эмодзи] [название]: [слова через запятую],
+     /category rename|words|archive|restore [ID] ... — изменить
+   /project — проекты: /project [ID] — переключиться (в группе — привязать чат),
+     /project new [название] [валюта] — создать, /project add [ID пользователя] — добавить участника
+   /users — доступ: /users add [ID] [owner|editor|viewer|contractor] — выдать роль,
+     /users remove [ID] — забрать доступ
+   /token — API-токены для скриптов: /token new [название] [read|write] [дней] — выпустить,
+     /token revoke [ID] — отозвать (только в личке)`
 )
 
 var errorMessages = map[error]string{ //nolint:gochecknoglobals
 	parser.ErrEmptyMessage:        "❌ Ты отправил пустое сообщение. Смотри, вот пример: `нал 1500 краска ванная`",
 	parser.ErrNotEnoughData:       "❌ Тут мало данных, но вот формат, если вдруг пригодится: `[тип_оплаты] [сумма] [категория] [описание]`", //nolint:lll
 	parser.ErrPaymentTypeNotFound: "❌ Напиши, как заплатил: `нал` или `карта`",
-	parser.ErrAmountNotFound:      "❌ Сумма указана неправильно. Напиши число, например: `1500`",
+	parser.ErrAmountNotFound:      "❌ Сумма указана неправильно. Напиши число, например: `1500`, `1,5к` или `2x750`",
 }
 
 func getFriendlyError(err error) string {
@@ -66,13 +172,53 @@ func getFriendlyError(err error) string {
 	return "❌ У меня тут ошибка какая-то выскочила. Попробуй еще разок, может, прокатит."
 }
 
-func formatExpenseHTML(p *message.Printer, e model.Expense) string {
+func formatAmount(p *message.Printer, amount decimal.Decimal) string {
+	return p.Sprintf("%.2f", amount.InexactFloat64())
+}
+
+// formatMoney — сумма со знаком валюты: «1 500,00 ₽», «20,00 $».
+func formatMoney(p *message.Printer, amount decimal.Decimal, currency string) string {
+	return formatAmount(p, amount) + " " + model.CurrencySymbol(currency)
+}
+
+var weekdayNames = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"} //nolint:gochecknoglobals
+
+const recentDays = 7
+
+// formatExpenseDate показывает дату в часовом поясе loc с днем недели, а для недавних
+// трат — еще и сколько дней назад: «17.10.2026 14:05 (сб, вчера)». Так сразу видно,
+// на какой день записалась трата задним числом.
+func formatExpenseDate(t, now time.Time, loc *time.Location) string {
+	t, now = t.In(loc), now.In(loc)
+
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	label := weekdayNames[t.Weekday()]
+
+	switch days := int(day(now).Sub(day(t)) / (24 * time.Hour)); {
+	case days == 0:
+		label += ", сегодня"
+	case days == 1:
+		label += ", вчера"
+	case days == 2:
+		label += ", позавчера"
+	case days > 2 && days <= recentDays:
+		label += ", " + strconv.Itoa(days) + " дн. назад"
+	}
+
+	return t.Format("02.01.2006 15:04") + " (" + label + ")"
+}
+
+func formatExpenseHTML(p *message.Printer, categories *model.CategoryRegistry, e model.Expense,
+	loc *time.Location) string {
 	var sb strings.Builder
 
 	sb.Grow(minExpenseStrlen)
 
 	sb.WriteString("<b>Дата</b>: ")
-	sb.WriteString(html.EscapeString(e.CreatedAt.Format("02.01.2006 15:04")))
+	sb.WriteString(html.EscapeString(formatExpenseDate(e.CreatedAt, time.Now(), loc)))
 	sb.WriteByte('\n')
 
 	sb.WriteString("<b>Тип</b>: ")
@@ -80,15 +226,15 @@ func formatExpenseHTML(p *message.Printer, e model.Expense) string {
 	sb.WriteByte('\n')
 
 	sb.WriteString("<b>Сумма</b>: ")
-	sb.WriteString(html.EscapeString(p.Sprintf("%.2f", e.Amount.InexactFloat64())))
-	sb.WriteString(" ₽\n")
+	sb.WriteString(html.EscapeString(formatMoney(p, e.Amount, e.Currency)))
+	sb.WriteByte('\n')
 
 	sb.WriteString("<b>Описание</b>: ")
 	sb.WriteString(html.EscapeString(e.Description))
 	sb.WriteByte('\n')
 
 	sb.WriteString("<b>Категория</b>: ")
-	sb.WriteString(html.EscapeString(e.Category.String()))
+	sb.WriteString(html.EscapeString(categories.Name(e.Category)))
 	sb.WriteByte('\n')
 
 	sb.WriteString("<b>ID</b>: ")
@@ -98,20 +244,91 @@ func formatExpenseHTML(p *message.Printer, e model.Expense) string {
 	return sb.String()
 }
 
-func formatExpensesHTML(p *message.Printer, expenses model.Expenses) string {
+// formatExpensesHTML показывает траты вместе с тем, кто их записал: names — имена по ID.
+func formatExpensesHTML(p *message.Printer, categories *model.CategoryRegistry, expenses model.Expenses,
+	loc *time.Location, names map[int64]string) string {
 	var sb strings.Builder
 
 	sb.Grow(len(expenses) * minExpenseStrlen)
 
 	for i := range expenses {
-		sb.WriteString(formatExpenseHTML(p, expenses[i]))
+		sb.WriteString(formatExpenseHTML(p, categories, expenses[i], loc))
+		sb.WriteString("<b>Кто</b>: ")
+		sb.WriteString(html.EscapeString(userName(names, expenses[i].UserID)))
 		sb.WriteString("\n\n")
 	}
 
 	return sb.String()
 }
 
-func New(ctx context.Context, token string, database Database, allowedUsers []int64) (*Service, error) { //nolint:funlen
+// confirmExpense отвечает «Записал» на новую трату и, если она перевела категорию
+// через порог бюджета, отдельным сообщением предупреждает об этом.
+func confirmExpense(ctx context.Context, c telebot.Context, db Database, p *message.Printer,
+	loc *time.Location, expense model.Expense, note string) error {
+	categories := db.CategoryRegistry()
+
+	err := c.Send("<b>✅ Записал:</b>\n\n"+formatExpenseHTML(p, categories, expense, loc)+note, &telebot.SendOptions{
+		ParseMode:   telebot.ModeHTML,
+		ReplyMarkup: expenseMarkup(expense.ID),
+	})
+	if err != nil {
+		return err
+	}
+
+	budget, err := db.Budget(ctx, expense.ProjectID, expense.Category)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.Budget", "error", err)
+
+		return nil
+	}
+
+	// Трата в чужой валюте пересчитывается только в отчетах, поэтому порог по ней
+	// не ловим: без суммы в валюте проекта не понять, какой был расход до нее.
+	currency := currentProject(c).Currency
+	if expense.Currency != currency {
+		return nil
+	}
+
+	if alert := budgetAlert(p, categories, budget, expense.Amount, currency); alert != "" {
+		return c.Send(alert, &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	return nil
+}
+
+// recordText записывает трату или, если строк несколько, пачку трат от отправителя
+// в текущий проект.
+func recordText(ctx context.Context, c telebot.Context, db Database, p *message.Printer,
+	loc *time.Location, text string) error {
+	// Несколько строк — несколько трат, каждая строка разбирается отдельно.
+	if lines := parser.MessagesAt(db.CategoryRegistry(), text, time.Now().In(loc)); len(lines) > 1 {
+		return saveBatch(ctx, c, db, p, loc, lines)
+	}
+
+	expense, err := parser.MessageAt(db.CategoryRegistry(), text, time.Now().In(loc))
+	if err != nil {
+		return c.Send(getFriendlyError(err))
+	}
+
+	expense.UserID = c.Sender().ID
+	expense.ProjectID = currentProject(c).ID
+	expense.Currency = cmp.Or(expense.Currency, currentProject(c).Currency)
+
+	err = db.Insert(actorContext(ctx, c), expense)
+	if err != nil {
+		return c.Send("❌ Не получилось записать, может, еще разок попробуем?")
+	}
+
+	return confirmExpense(ctx, c, db, p, loc, expense, "")
+}
+
+// New создает бота. groupPrefix — префикс сообщений для бота в группах, см. groupMessages.
+//
+//nolint:funlen
+func New(ctx context.Context, token string, database Database, loc *time.Location,
+	undoWindow time.Duration, groupPrefix string) (*Service, error) {
 	pref := telebot.Settings{
 		Token:  token,
 		Poller: &telebot.LongPoller{Timeout: pollerTimeout},
@@ -124,6 +341,11 @@ func New(ctx context.Context, token string, database Database, allowedUsers []in
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
+	// С режимом приватности Telegram присылает боту из групп только команды и ответы ему.
+	slog.InfoContext(ctx, "telebot privacy mode", "enabled", !bot.Me.CanReadMessages)
+
+	categories := database.CategoryRegistry()
+
 	helpHandler := func(c telebot.Context) error {
 		return c.Send(helpMessage)
 	}
@@ -137,7 +359,10 @@ func New(ctx context.Context, token string, database Database, allowedUsers []in
 			limit = parser.Integer(tags[0], defaultListLimit)
 		}
 
-		expenses, err := database.List(ctx, limit)
+		filter := projectFilter(c)
+		filter.Limit = limit
+
+		expenses, _, err := database.Find(ctx, filter)
 		if err != nil {
 			return c.Send("❌ Не получилось получить список трат, может, еще разок попробуем?")
 		}
@@ -146,9 +371,12 @@ func New(ctx context.Context, token string, database Database, allowedUsers []in
 			return c.Send("❌ Список трат пуст.")
 		}
 
-		return c.Send("<b>📊 Список трат:</b>\n\n"+formatExpensesHTML(p, expenses), &telebot.SendOptions{
-			ParseMode: telebot.ModeHTML,
-		})
+		names := userNames(ctx, database)
+
+		return c.Send("<b>📊 Список трат:</b>\n\n"+formatExpensesHTML(p, categories, expenses, loc, names),
+			&telebot.SendOptions{
+				ParseMode: telebot.ModeHTML,
+			})
 	}
 
 	deleteHandler := func(c telebot.Context) error {
@@ -162,47 +390,87 @@ func New(ctx context.Context, token string, database Database, allowedUsers []in
 			return c.Send("❌ Укажи ID, который хочешь удалить.")
 		}
 
-		err := database.Delete(ctx, id)
+		_, err := editableExpense(ctx, c, database, id)
+		if err == nil {
+			err = database.Delete(actorContext(ctx, c), id)
+		}
+
+		if errors.Is(err, model.ErrNotFound) {
+			return c.Send("❌ Не нашел трату с таким ID.")
+		}
+
+		if errors.Is(err, model.ErrForbidden) {
+			return c.Send(forbiddenMessage)
+		}
+
 		if err != nil {
 			return c.Send("❌ Не получилось удалить, может, еще разок попробуем?")
 		}
 
-		return c.Send("✅ Удалено.")
+		return c.Send("✅ Удалено. Передумал — <code>/restore "+id.String()+"</code>", &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
 	}
 
+	access, project := accessMiddleware(ctx, database), projectMiddleware(ctx, database)
+
 	group := bot.Group()
+	group.Use(access, project)
 
-	if len(allowedUsers) > 0 {
-		group.Use(middleware.Whitelist(allowedUsers...))
-	}
+	// Обычные сообщения сначала проверяются на обращение к боту: чужую переписку группы
+	// бот пропускает молча, не проверяя доступ и проект.
+	messages := bot.Group()
+	messages.Use(groupMessages(groupPrefix), access, project)
 
 	bot.Handle("/help", helpHandler)
 	bot.Handle("/start", helpHandler)
 
 	group.Handle("/list", listHandler)
 	group.Handle("/delete", deleteHandler)
-	group.Handle(telebot.OnText, func(c telebot.Context) error {
-		sender := c.Sender()
-
-		expense, err := parser.Message(c.Text())
-		if err != nil {
-			return c.Send(getFriendlyError(err))
+	group.Handle("/trash", trashHandler(ctx, database, p, loc))
+	group.Handle("/restore", restoreHandler(ctx, database, p, loc))
+	group.Handle("/history", historyHandler(ctx, database, loc))
+	group.Handle("/edit", editHandler(ctx, database, p, loc))
+	group.Handle("/report", reportHandler(ctx, database, p, loc))
+	group.Handle("/export", exportHandler(ctx, database, loc))
+	group.Handle("/budget", budgetHandler(ctx, database, p))
+	group.Handle("/rate", rateHandler(ctx, database, p, loc))
+	group.Handle("/recurring", recurringHandler(ctx, database, p, loc))
+	group.Handle("/digest", digestHandler(ctx, database, p, loc))
+	group.Handle("/settle", settleHandler(ctx, database, p, loc))
+	group.Handle("/receipt", receiptHandler(ctx, database))
+	group.Handle("/project", projectHandler(ctx, database, groupPrefix))
+	group.Handle("/category", categoryHandler(ctx, database))
+	group.Handle("/users", requires(model.PermissionAdmin, usersHandler(ctx, database)))
+	group.Handle("/token", tokenHandler(ctx, database, loc))
+	registerButtons(ctx, group, database, p, loc, undoWindow)
+	registerRecurringButtons(ctx, group, database, p, loc)
+	messages.Handle(telebot.OnPhoto, photoHandler(ctx, database, p, loc))
+	messages.Handle(telebot.OnDocument, requires(model.PermissionRecord, importHandler(ctx, database, loc)))
+	// В группе бот отвечает только на адресованные ему сообщения, а с режимом
+	// приватности траты пишутся командой /add.
+	group.Handle("/add", requires(model.PermissionRecord, func(c telebot.Context) error {
+		if strings.TrimSpace(c.Message().Payload) == "" {
+			return c.Send("❌ Напиши трату после команды: /add нал 1500 краска")
 		}
 
-		expense.UserID = sender.ID
-
-		err = database.Insert(ctx, expense)
-		if err != nil {
-			return c.Send("❌ Не получилось записать, может, еще разок попробуем?")
+		return recordText(ctx, c, database, p, loc, c.Message().Payload)
+	}))
+	textHandler := func(c telebot.Context) error {
+		if id := replyExpenseID(c); id != uuid.Nil {
+			return editExpense(ctx, c, database, p, loc, id, messageText(c))
 		}
 
-		return c.Send("<b>✅ Записал:</b>\n\n"+formatExpenseHTML(p, expense), &telebot.SendOptions{
-			ParseMode: telebot.ModeHTML,
-		})
-	})
+		return recordText(ctx, c, database, p, loc, messageText(c))
+	}
+
+	messages.Handle(telebot.OnText, requires(model.PermissionRecord, textHandler))
 
 	return &Service{
 		bot: bot,
+		db:  database,
+		p:   p,
+		loc: loc,
 	}, nil
 }
 
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const (
+	budgetWarnPercent = 80
+	budgetOverPercent = 100
+
+	budgetUsageMessage = "❌ Формат: `/budget [категория] [сумма]`, например: `/budget материалы 500к`"
+)
+
+// formatBudgetHTML показывает бюджет в валюте проекта currency.
+func formatBudgetHTML(p *message.Printer, categories *model.CategoryRegistry, b model.Budget, currency string) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>")
+	sb.WriteString(html.EscapeString(categories.Name(b.Category)))
+	sb.WriteString("</b>: ")
+	sb.WriteString(html.EscapeString(formatAmount(p, b.Spent)))
+
+	if b.IsSet() {
+		sb.WriteString(" из ")
+		sb.WriteString(html.EscapeString(formatMoney(p, b.Amount, currency)))
+		sb.WriteString(" (")
+		sb.WriteString(b.Percent().Round(0).String())
+		sb.WriteString("%)")
+	} else {
+		sb.WriteString(" ")
+		sb.WriteString(html.EscapeString(model.CurrencySymbol(currency)))
+		sb.WriteString(", бюджет не задан")
+	}
+
+	return sb.String()
+}
+
+func formatBudgetsHTML(p *message.Printer, categories *model.CategoryRegistry, budgets model.Budgets,
+	currency string) string {
+	var sb strings.Builder
+
+	for i := range budgets {
+		sb.WriteString(formatBudgetHTML(p, categories, budgets[i], currency))
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// budgetAlert возвращает предупреждение, если трата amount в валюте проекта currency
+// перевела категорию через порог 80% или 100% бюджета, иначе пустую строку.
+func budgetAlert(p *message.Printer, categories *model.CategoryRegistry, b model.Budget, amount decimal.Decimal,
+	currency string) string {
+	if !b.IsSet() {
+		return ""
+	}
+
+	before := b
+	before.Spent = b.Spent.Sub(amount)
+
+	crossed := func(percent int64) bool {
+		threshold := decimal.NewFromInt(percent)
+
+		return before.Percent().LessThan(threshold) && !b.Percent().LessThan(threshold)
+	}
+
+	switch {
+	case crossed(budgetOverPercent):
+		return "🚨 <b>Бюджет превышен!</b>\n\n" + formatBudgetHTML(p, categories, b, currency)
+	case crossed(budgetWarnPercent):
+		return "⚠️ <b>Израсходовано больше 80% бюджета</b>\n\n" + formatBudgetHTML(p, categories, b, currency)
+	default:
+		return ""
+	}
+}
+
+func budgetHandler(ctx context.Context, database Database, p *message.Printer) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		categories := database.CategoryRegistry()
+		tags := c.Args()
+		projectID, currency := currentProject(c).ID, currentProject(c).Currency
+
+		// Бюджеты считаются по всем тратам проекта, подрядчику их не показываем.
+		if !can(c, model.PermissionViewAll) || (len(tags) > 0 && !can(c, model.PermissionBudgets)) {
+			return c.Send(forbiddenMessage)
+		}
+
+		if len(tags) == 0 {
+			budgets, err := database.Budgets(ctx, projectID)
+			if err != nil {
+				return c.Send("❌ Не получилось получить бюджеты, может, еще разок попробуем?")
+			}
+
+			return c.Send("<b>💰 Бюджеты:</b>\n\n"+formatBudgetsHTML(p, categories, budgets, currency), &telebot.SendOptions{
+				ParseMode: telebot.ModeHTML,
+			})
+		}
+
+		if len(tags) < 2 { //nolint:mnd
+			return c.Send(budgetUsageMessage)
+		}
+
+		category, ok := parser.Category(categories, tags[0])
+		if !ok {
+			return c.Send("❌ Не знаю такую категорию.")
+		}
+
+		// «0» убирает бюджет, остальное разбираем как сумму в сообщении: 500к, 1 200 000.
+		amount := decimal.Zero
+
+		if input := strings.Join(tags[1:], " "); input != "0" {
+			if amount, ok = parser.Amount(input); !ok {
+				return c.Send(budgetUsageMessage)
+			}
+		}
+
+		err := database.SetBudget(ctx, projectID, category, amount)
+		if err != nil {
+			return c.Send("❌ Не получилось сохранить бюджет, может, еще разок попробуем?")
+		}
+
+		budget, err := database.Budget(ctx, projectID, category)
+		if err != nil {
+			return c.Send("✅ Бюджет сохранен.")
+		}
+
+		return c.Send("<b>✅ Бюджет сохранен:</b>\n\n"+formatBudgetHTML(p, categories, budget, currency), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+// Кнопки под сообщением «Записал». В данных кнопки — ID траты и выбранное значение.
+const (
+	btnUndo        = "undo"
+	btnCategories  = "categories"
+	btnPayments    = "payments"
+	btnSetCategory = "set_category"
+	btnSetPayment  = "set_payment"
+	btnBack        = "back"
+
+	buttonsPerRow = 2
+)
+
+func expenseMarkup(id model.ExpenseID) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+
+	markup.Inline(
+		markup.Row(markup.Data("↩️ Отменить", btnUndo, id.String())),
+		markup.Row(
+			markup.Data("🗂 Категория", btnCategories, id.String()),
+			markup.Data("💳 Оплата", btnPayments, id.String()),
+		),
+	)
+
+	return markup
+}
+
+func categoriesMarkup(categories *model.CategoryRegistry, id model.ExpenseID) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	active := categories.Active()
+	buttons := make([]telebot.Btn, 0, len(active)+1)
+
+	for _, category := range active {
+		text := categories.Name(category)
+		if info, _ := categories.Info(category); info.Emoji != "" {
+			text = info.Emoji + " " + text
+		}
+
+		buttons = append(buttons, markup.Data(text, btnSetCategory, id.String(), strconv.Itoa(int(category))))
+	}
+
+	buttons = append(buttons, markup.Data("← Назад", btnBack, id.String()))
+	markup.Inline(markup.Split(buttonsPerRow, buttons)...)
+
+	return markup
+}
+
+func paymentsMarkup(id model.ExpenseID) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	buttons := make([]telebot.Btn, 0, len(model.PaymentTypes())+1)
+
+	for _, pt := range model.PaymentTypes() {
+		buttons = append(buttons, markup.Data(pt.String(), btnSetPayment, id.String(), strconv.Itoa(int(pt))))
+	}
+
+	buttons = append(buttons, markup.Data("← Назад", btnBack, id.String()))
+	markup.Inline(markup.Split(buttonsPerRow, buttons)...)
+
+	return markup
+}
+
+// buttonExpense проверяет нажатие кнопки: трата из текущего проекта, нажал ее автор,
+// и окно для исправлений еще не закрылось. Отвечает на callback сам, если нельзя.
+func buttonExpense(ctx context.Context, c telebot.Context, db Database,
+	window time.Duration) (model.Expense, bool) {
+	args := c.Args()
+
+	id := parser.ID(args[0])
+	if id == uuid.Nil {
+		_ = c.Respond()
+
+		return model.Expense{}, false
+	}
+
+	expense, err := projectExpense(ctx, c, db, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			_ = c.Respond(&telebot.CallbackResponse{Text: "Этой траты уже нет."})
+			_ = c.Edit(&telebot.ReplyMarkup{})
+		} else {
+			slog.ErrorContext(ctx, "projectExpense", "error", err)
+			_ = c.Respond(&telebot.CallbackResponse{Text: "Не получилось найти трату."})
+		}
+
+		return model.Expense{}, false
+	}
+
+	if expense.UserID != c.Sender().ID || !currentRole(c).CanEdit(c.Sender().ID, expense) {
+		_ = c.Respond(&telebot.CallbackResponse{Text: "Исправить трату может только тот, кто ее записал.", ShowAlert: true})
+
+		return model.Expense{}, false
+	}
+
+	if time.Since(c.Message().Time()) > window {
+		_ = c.Respond(&telebot.CallbackResponse{
+			Text:      "Время на исправление вышло, используй /edit или /delete.",
+			ShowAlert: true,
+		})
+		_ = c.Edit(&telebot.ReplyMarkup{})
+
+		return model.Expense{}, false
+	}
+
+	return expense, true
+}
+
+// editConfirmation обновляет сообщение «Записал» после изменения траты.
+func editConfirmation(c telebot.Context, p *message.Printer, categories *model.CategoryRegistry,
+	loc *time.Location, expense model.Expense, note string) error {
+	err := c.Respond(&telebot.CallbackResponse{Text: note})
+	if err != nil {
+		return err
+	}
+
+	return c.Edit("<b>✅ Записал:</b>\n\n"+formatExpenseHTML(p, categories, expense, loc), &telebot.SendOptions{
+		ParseMode:   telebot.ModeHTML,
+		ReplyMarkup: expenseMarkup(expense.ID),
+	})
+}
+
+// registerButtons подключает обработчики кнопок под сообщением «Записал».
+// Нажимать их может только автор траты и только в течение window после записи.
+func registerButtons(ctx context.Context, group *telebot.Group, db Database, p *message.Printer,
+	loc *time.Location, window time.Duration) {
+	categories := db.CategoryRegistry()
+
+	group.Handle(&telebot.Btn{Unique: btnUndo}, func(c telebot.Context) error {
+		expense, ok := buttonExpense(ctx, c, db, window)
+		if !ok {
+			return nil
+		}
+
+		err := db.Delete(actorContext(ctx, c), expense.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.Delete", "error", err)
+
+			return c.Respond(&telebot.CallbackResponse{Text: "Не получилось отменить, попробуй еще раз."})
+		}
+
+		err = c.Respond(&telebot.CallbackResponse{Text: "Отменено"})
+		if err != nil {
+			return err
+		}
+
+		return c.Edit("<b>↩️ Отменил:</b>\n\n"+formatExpenseHTML(p, categories, expense, loc), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	})
+
+	showMarkup := func(markup func(model.ExpenseID) *telebot.ReplyMarkup) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			expense, ok := buttonExpense(ctx, c, db, window)
+			if !ok {
+				return nil
+			}
+
+			err := c.Respond()
+			if err != nil {
+				return err
+			}
+
+			return c.Edit(markup(expense.ID))
+		}
+	}
+
+	group.Handle(&telebot.Btn{Unique: btnCategories}, showMarkup(func(id model.ExpenseID) *telebot.ReplyMarkup {
+		return categoriesMarkup(categories, id)
+	}))
+	group.Handle(&telebot.Btn{Unique: btnPayments}, showMarkup(paymentsMarkup))
+	group.Handle(&telebot.Btn{Unique: btnBack}, showMarkup(expenseMarkup))
+
+	group.Handle(&telebot.Btn{Unique: btnSetCategory}, func(c telebot.Context) error {
+		expense, ok := buttonExpense(ctx, c, db, window)
+		if !ok {
+			return nil
+		}
+
+		if len(c.Args()) < 2 { //nolint:mnd
+			_ = c.Respond()
+
+			return nil
+		}
+
+		category, ok := parser.Category(categories, c.Args()[1])
+		if !ok {
+			return c.Respond(&telebot.CallbackResponse{Text: "Такой категории больше нет."})
+		}
+
+		err := db.UpdateCategory(actorContext(ctx, c), expense.ID, category)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.UpdateCategory", "error", err)
+
+			return c.Respond(&telebot.CallbackResponse{Text: "Не получилось сменить категорию."})
+		}
+
+		expense.Category = category
+
+		return editConfirmation(c, p, categories, loc, expense, "Категория: "+categories.Name(category))
+	})
+
+	group.Handle(&telebot.Btn{Unique: btnSetPayment}, func(c telebot.Context) error {
+		expense, ok := buttonExpense(ctx, c, db, window)
+		if !ok {
+			return nil
+		}
+
+		if len(c.Args()) < 2 { //nolint:mnd
+			_ = c.Respond()
+
+			return nil
+		}
+
+		id, err := strconv.ParseUint(c.Args()[1], 10, 8)
+		if err != nil || !model.PaymentType(id).IsValid() {
+			return c.Respond()
+		}
+
+		expense.PaymentType = model.PaymentType(id)
+		expense.UpdatedAt = time.Now()
+
+		err = db.Update(actorContext(ctx, c), expense)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.Update", "error", err)
+
+			return c.Respond(&telebot.CallbackResponse{Text: "Не получилось сменить тип оплаты."})
+		}
+
+		return editConfirmation(c, p, categories, loc, expense, "Оплата: "+expense.PaymentType.String())
+	})
+}
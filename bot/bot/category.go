@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/database"
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const categoryUsageMessage = "❌ Формат:\n" +
+	"`/category add [эмодзи] [название]: [ключевые слова через запятую]`\n" +
+	"`/category rename [ID] [название]`\n" +
+	"`/category words [ID] [ключевые слова через запятую]`\n" +
+	"`/category archive [ID]` или `/category restore [ID]`"
+
+func formatCategoriesHTML(categories []model.CategoryInfo) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>🗂 Категории:</b>\n\n")
+
+	for _, c := range categories {
+		sb.WriteString(strconv.Itoa(int(c.ID)))
+		sb.WriteString(". ")
+
+		if c.Emoji != "" {
+			sb.WriteString(html.EscapeString(c.Emoji))
+			sb.WriteByte(' ')
+		}
+
+		sb.WriteString(html.EscapeString(c.Name))
+
+		if len(c.Keywords) > 0 {
+			sb.WriteString(" — <i>")
+			sb.WriteString(html.EscapeString(strings.Join(c.Keywords, ", ")))
+			sb.WriteString("</i>")
+		}
+
+		if c.Archived {
+			sb.WriteString(" (в архиве)")
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func splitKeywords(input string) []string {
+	return strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ';' })
+}
+
+// parseNewCategory разбирает «[эмодзи] название: слово1, слово2». Эмодзи — первое слово,
+// если в нем нет букв и цифр.
+func parseNewCategory(input string) model.CategoryInfo {
+	var c model.CategoryInfo
+
+	name, keywords, _ := strings.Cut(input, ":")
+
+	if first, rest, ok := strings.Cut(strings.TrimSpace(name), " "); ok &&
+		!strings.ContainsFunc(first, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		c.Emoji, name = first, rest
+	}
+
+	c.Name = strings.TrimSpace(name)
+	c.Keywords = splitKeywords(keywords)
+
+	return c
+}
+
+func categoryErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return "❌ Не знаю такую категорию."
+	case errors.Is(err, model.ErrCategoryExists):
+		return "❌ Категория с таким названием уже есть."
+	case errors.Is(err, model.ErrKeywordTaken):
+		return "❌ Это ключевое слово уже занято другой категорией."
+	case errors.Is(err, database.ErrTooManyCategories):
+		return "❌ Больше категорий не поместится, лучше переименуй ненужную из архива."
+	case errors.Is(err, model.ErrInvalidCategory):
+		return "❌ Название не может быть пустым, а ключевое слово — это одно слово, не число."
+	default:
+		return "❌ Не получилось сохранить категорию, может, еще разок попробуем?"
+	}
+}
+
+// editCategory меняет существующую категорию по ID или ключевому слову.
+func editCategory(ctx context.Context, c telebot.Context, db Database, args []string,
+	change func(info *model.CategoryInfo)) error {
+	category, ok := parser.Category(db.CategoryRegistry(), args[0])
+	if !ok {
+		return c.Send(categoryErrorMessage(model.ErrNotFound))
+	}
+
+	info, _ := db.CategoryRegistry().Info(category)
+
+	change(&info)
+
+	err := db.EditCategory(ctx, info)
+	if err != nil {
+		return c.Send(categoryErrorMessage(err))
+	}
+
+	return c.Send("✅ Категория сохранена.\n\n"+formatCategoriesHTML(db.CategoryRegistry().Infos()), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+}
+
+func categoryHandler(ctx context.Context, db Database) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		args := c.Args()
+
+		if len(args) == 0 {
+			return c.Send(formatCategoriesHTML(db.CategoryRegistry().Infos()), &telebot.SendOptions{
+				ParseMode: telebot.ModeHTML,
+			})
+		}
+
+		if !can(c, model.PermissionAdmin) {
+			return c.Send(forbiddenMessage)
+		}
+
+		command, args := strings.ToLower(args[0]), args[1:]
+
+		if command == "add" {
+			if len(args) == 0 {
+				return c.Send(categoryUsageMessage)
+			}
+
+			info, err := db.AddCategory(ctx, parseNewCategory(strings.Join(args, " ")))
+			if err != nil {
+				return c.Send(categoryErrorMessage(err))
+			}
+
+			return c.Send("✅ Добавил категорию «" + info.Name + "», ID " + strconv.Itoa(int(info.ID)) + ".")
+		}
+
+		if len(args) == 0 || (command != "archive" && command != "restore" && len(args) < 2) { //nolint:mnd
+			return c.Send(categoryUsageMessage)
+		}
+
+		rest := strings.Join(args[1:], " ")
+
+		switch command {
+		case "rename":
+			return editCategory(ctx, c, db, args, func(info *model.CategoryInfo) { info.Name = rest })
+		case "words":
+			return editCategory(ctx, c, db, args, func(info *model.CategoryInfo) { info.Keywords = splitKeywords(rest) })
+		case "archive":
+			return editCategory(ctx, c, db, args, func(info *model.CategoryInfo) { info.Archived = true })
+		case "restore":
+			// Ключевые слова архив не сохраняет, их задают заново: /category words.
+			return editCategory(ctx, c, db, args, func(info *model.CategoryInfo) { info.Archived = false })
+		default:
+			return c.Send(categoryUsageMessage)
+		}
+	}
+}
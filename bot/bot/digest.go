@@ -0,0 +1,377 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/database"
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const (
+	digestInterval       = time.Minute
+	digestTopLimit       = 3
+	digestYesterdayLimit = 15
+
+	digestUsageMessage = "❌ Формат: `/digest` — настройки, `/digest 09:00 [Europe/Moscow]` — каждый день, " +
+		"`/digest weekly 09:00` — по понедельникам, `/digest now` — прислать сейчас, `/digest off` — выключить. " +
+		"Сводка приходит в чат, где ее настроили"
+)
+
+func formatDigestSettings(d model.Digest) string {
+	return fmt.Sprintf("%s в %02d:%02d (%s)", d.Period, d.Hour, d.Minute, d.Timezone)
+}
+
+func writeDigestExpenses(sb *strings.Builder, p *message.Printer, categories *model.CategoryRegistry,
+	expenses model.Expenses, loc *time.Location) {
+	for _, e := range expenses {
+		sb.WriteString("• ")
+		sb.WriteString(html.EscapeString(formatMoney(p, e.Amount, e.Currency)))
+		sb.WriteString(" — ")
+		sb.WriteString(html.EscapeString(e.Description))
+		sb.WriteString(" (")
+		sb.WriteString(html.EscapeString(categories.Name(e.Category)))
+		sb.WriteString(", ")
+		sb.WriteString(e.CreatedAt.In(loc).Format("02.01"))
+		sb.WriteString(")\n")
+	}
+}
+
+// writeDigestReport добавляет в сводку итог периода и разбивку по категориям.
+func writeDigestReport(sb *strings.Builder, p *message.Printer, categories *model.CategoryRegistry, title string,
+	r model.Report, currency string) {
+	sb.WriteString("<b>")
+	sb.WriteString(html.EscapeString(title))
+	sb.WriteString("</b>: ")
+	sb.WriteString(html.EscapeString(formatMoney(p, r.Total.Amount, currency)))
+	sb.WriteString(", трат: ")
+	sb.WriteString(strconv.Itoa(r.Total.Count))
+	sb.WriteByte('\n')
+
+	for _, t := range r.ByCategory {
+		writeTotalLine(sb, p, categories.Name(t.Category), t.Total, r.Total, currency)
+	}
+}
+
+// digestReports собирает отчеты для сводки: за вчера и за неделю по вчерашний день
+// для ежедневной, за прошлую неделю — для еженедельной. Вчерашний отчет у еженедельной пустой.
+func digestReports(ctx context.Context, db Database, d model.Digest, filter database.ExpenseFilter,
+	now time.Time) (model.Report, model.Report, error) {
+	today, _, err := parser.Period([]string{"today"}, now)
+	if err != nil {
+		return model.Report{}, model.Report{}, err
+	}
+
+	yesterday := today.AddDate(0, 0, -1)
+
+	if d.Period == model.DigestWeekly {
+		monday, _, err := parser.Period([]string{"week"}, now)
+		if err != nil {
+			return model.Report{}, model.Report{}, err
+		}
+
+		filter.From, filter.To = monday.AddDate(0, 0, -7), monday
+
+		week, err := db.Report(ctx, filter, digestTopLimit)
+
+		return model.Report{}, week, err
+	}
+
+	filter.From, filter.To = yesterday, today
+
+	day, err := db.Report(ctx, filter, digestYesterdayLimit)
+	if err != nil {
+		return model.Report{}, model.Report{}, err
+	}
+
+	// Неделя считается по вчерашний день: в понедельник утром это итоги всей прошлой недели.
+	monday, _, err := parser.Period([]string{"week"}, yesterday)
+	if err != nil {
+		return model.Report{}, model.Report{}, err
+	}
+
+	filter.From, filter.To = monday, today
+
+	week, err := db.Report(ctx, filter, digestTopLimit)
+
+	return day, week, err
+}
+
+// buildDigest собирает сводку по проекту project с правами роли role: без права видеть
+// все траты в нее попадают только траты самого пользователя, а бюджеты не показываются.
+func buildDigest(ctx context.Context, db Database, p *message.Printer, d model.Digest, role model.Role,
+	project model.Project, now time.Time) (string, error) {
+	loc := d.Location()
+	now = now.In(loc)
+	categories := db.CategoryRegistry()
+
+	filter := database.ExpenseFilter{ProjectIDs: []model.ProjectID{project.ID}}
+	if !role.Can(model.PermissionViewAll) {
+		filter.UserIDs = []int64{d.UserID}
+	}
+
+	day, week, err := digestReports(ctx, db, d, filter, now)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("<b>📬 Сводка по проекту «")
+	sb.WriteString(html.EscapeString(project.Name))
+	sb.WriteString("»</b>\n\n")
+
+	if d.Period == model.DigestDaily {
+		writeDigestReport(&sb, p, categories, "Вчера, "+day.From.Format("02.01.2006"), day, project.Currency)
+
+		if len(day.Top) > 0 {
+			writeDigestExpenses(&sb, p, categories, day.Top, loc)
+
+			if rest := day.Total.Count - len(day.Top); rest > 0 {
+				sb.WriteString("…и еще ")
+				sb.WriteString(strconv.Itoa(rest))
+				sb.WriteByte('\n')
+			}
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	writeDigestReport(&sb, p, categories, "Неделя "+formatPeriod(week.From, week.To), week, project.Currency)
+
+	if len(week.Top) > 0 {
+		sb.WriteString("\n<b>Самые крупные за неделю:</b>\n")
+		writeDigestExpenses(&sb, p, categories, week.Top, loc)
+	}
+
+	if len(day.MissingRates)+len(week.MissingRates) > 0 {
+		sb.WriteString("\n⚠️ Нет курса для части трат, они не вошли в суммы. Задай курс: /rate\n")
+	}
+
+	if !role.Can(model.PermissionViewAll) {
+		return sb.String(), nil
+	}
+
+	budgets, err := db.Budgets(ctx, project.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var burn strings.Builder
+
+	for i := range budgets {
+		if budgets[i].IsSet() {
+			burn.WriteString(formatBudgetHTML(p, categories, budgets[i], project.Currency))
+			burn.WriteByte('\n')
+		}
+	}
+
+	if burn.Len() > 0 {
+		sb.WriteString("\n<b>Бюджеты:</b>\n")
+		sb.WriteString(burn.String())
+	}
+
+	return sb.String(), nil
+}
+
+// digestProject возвращает проект сводки: привязанный к групповому чату, если сводка
+// уходит в группу, иначе активный проект пользователя.
+func digestProject(ctx context.Context, db Database, d model.Digest) (model.Project, error) {
+	if d.ChatID != d.UserID {
+		project, err := db.ChatProject(ctx, d.ChatID)
+		if !errors.Is(err, model.ErrNotFound) {
+			return project, err
+		}
+	}
+
+	return db.ActiveProject(ctx, d.UserID)
+}
+
+func (s *Service) sendDigest(ctx context.Context, d model.Digest, now time.Time) error {
+	role, err := s.db.UserRole(ctx, d.UserID)
+	if err != nil {
+		return fmt.Errorf("user role: %w", err)
+	}
+
+	project, err := digestProject(ctx, s.db, d)
+	if err != nil {
+		return fmt.Errorf("digest project: %w", err)
+	}
+
+	text, err := buildDigest(ctx, s.db, s.p, d, role, project, now)
+	if err != nil {
+		return fmt.Errorf("build digest: %w", err)
+	}
+
+	_, err = s.bot.Send(telebot.ChatID(d.ChatID), text, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	if err != nil {
+		return fmt.Errorf("send digest: %w", err)
+	}
+
+	return nil
+}
+
+// nextDigest возвращает следующую отправку сводки после after.
+func nextDigest(d model.Digest, after time.Time) (time.Time, error) {
+	schedule, err := parser.ParseSchedule(d.Schedule())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next, _ := schedule.Next(after.In(d.Location()))
+
+	return next, nil
+}
+
+// RunDigests раз в digestInterval рассылает сводки, время которых наступило. Сводки,
+// пропущенные, пока бот не работал, отправляются один раз при первом же проходе.
+func (s *Service) RunDigests(ctx context.Context) error {
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+
+		due, err := s.db.DueDigests(ctx, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.DueDigests", "error", err)
+		}
+
+		for _, d := range due {
+			// Неотправленную сводку не повторяем: следующая все равно придет по расписанию.
+			err := s.sendDigest(ctx, d, now)
+			if err != nil {
+				slog.ErrorContext(ctx, "send digest", "user_id", d.UserID, "chat_id", d.ChatID, "error", err)
+			}
+
+			next, err := nextDigest(d, now)
+			if err == nil {
+				err = s.db.SetDigestNextRun(ctx, d.UserID, next)
+			}
+
+			if err != nil {
+				slog.ErrorContext(ctx, "reschedule digest", "user_id", d.UserID, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseDigest применяет к настройкам d аргументы /digest: период, время «ЧЧ:ММ»
+// и часовой пояс в любом порядке.
+func parseDigest(d model.Digest, args []string) (model.Digest, bool) {
+	for _, arg := range args {
+		switch strings.ToLower(arg) {
+		case "daily", "ежедневно":
+			d.Period = model.DigestDaily
+
+			continue
+		case "weekly", "еженедельно":
+			d.Period = model.DigestWeekly
+
+			continue
+		}
+
+		if at, err := time.Parse("15:04", arg); err == nil {
+			d.Hour, d.Minute = at.Hour(), at.Minute()
+
+			continue
+		}
+
+		if _, err := time.LoadLocation(arg); err == nil && arg != "" && arg != "Local" {
+			d.Timezone = arg
+
+			continue
+		}
+
+		return model.Digest{}, false
+	}
+
+	return d, true
+}
+
+// digestHandler настраивает сводку отправителя. Сводка приходит в тот чат, где ее настроили.
+func digestHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		args := c.Args()
+		userID := c.Sender().ID
+
+		current, err := db.Digest(ctx, userID)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			slog.ErrorContext(ctx, "database.Digest", "error", err)
+
+			return c.Send("❌ Не получилось получить настройки сводки, может, еще разок попробуем?")
+		}
+
+		enabled := err == nil
+		if !enabled {
+			current = model.Digest{UserID: userID, Hour: 9, Timezone: loc.String(), Period: model.DigestDaily} //nolint:mnd
+		}
+
+		if len(args) == 0 {
+			if !enabled {
+				return c.Send("📬 Сводка выключена. Включить: /digest 09:00")
+			}
+
+			return c.Send("📬 Сводка: " + formatDigestSettings(current) + ", следующая — " +
+				formatExpenseDate(current.NextRun, time.Now(), current.Location()))
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "off", "выкл":
+			err := db.DeleteDigest(ctx, userID)
+			if err != nil && !errors.Is(err, model.ErrNotFound) {
+				slog.ErrorContext(ctx, "database.DeleteDigest", "error", err)
+
+				return c.Send("❌ Не получилось выключить сводку, может, еще разок попробуем?")
+			}
+
+			return c.Send("✅ Сводка выключена.")
+		case "now", "сейчас":
+			text, err := buildDigest(ctx, db, p, current, currentRole(c), currentProject(c), time.Now())
+			if err != nil {
+				slog.ErrorContext(ctx, "build digest", "error", err)
+
+				return c.Send("❌ Не получилось собрать сводку, может, еще разок попробуем?")
+			}
+
+			return c.Send(text, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+
+		d, ok := parseDigest(current, args)
+		if !ok {
+			return c.Send(digestUsageMessage)
+		}
+
+		d.ChatID = c.Chat().ID
+
+		d.NextRun, err = nextDigest(d, time.Now())
+		if err == nil {
+			err = db.SetDigest(ctx, d)
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "database.SetDigest", "error", err)
+
+			return c.Send("❌ Не получилось сохранить сводку, может, еще разок попробуем?")
+		}
+
+		return c.Send("✅ Сводка: " + formatDigestSettings(d) + ", первая — " +
+			formatExpenseDate(d.NextRun, time.Now(), d.Location()))
+	}
+}
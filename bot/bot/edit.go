@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const editUsageMessage = "❌ Формат: `/edit [ID] [тип_оплаты] [сумма] [категория] [описание]`. " +
+	"Или просто ответь на мое сообщение «Записал» исправленным текстом."
+
+// replyExpenseID возвращает ID траты из сообщения бота, на которое ответил пользователь.
+func replyExpenseID(c telebot.Context) model.ExpenseID {
+	msg := c.Message()
+	if msg == nil || msg.ReplyTo == nil || msg.ReplyTo.Sender == nil {
+		return uuid.Nil
+	}
+
+	if me := c.Bot().Me; me == nil || msg.ReplyTo.Sender.ID != me.ID {
+		return uuid.Nil
+	}
+
+	for line := range strings.SplitSeq(msg.ReplyTo.Text, "\n") {
+		if id, ok := strings.CutPrefix(line, "ID: "); ok {
+			return parser.ID(id)
+		}
+	}
+
+	return uuid.Nil
+}
+
+func writeDiffLine(sb *strings.Builder, name, before, after string) {
+	if before == after {
+		return
+	}
+
+	sb.WriteString("<b>")
+	sb.WriteString(name)
+	sb.WriteString("</b>: ")
+	sb.WriteString(html.EscapeString(before))
+	sb.WriteString(" → ")
+	sb.WriteString(html.EscapeString(after))
+	sb.WriteByte('\n')
+}
+
+func formatExpenseDiffHTML(p *message.Printer, categories *model.CategoryRegistry, before, after model.Expense,
+	loc *time.Location) string {
+	var sb strings.Builder
+
+	amount := func(e model.Expense) string {
+		return formatMoney(p, e.Amount, e.Currency)
+	}
+
+	writeDiffLine(&sb, "Дата", before.CreatedAt.In(loc).Format("02.01.2006"), after.CreatedAt.In(loc).Format("02.01.2006"))
+	writeDiffLine(&sb, "Тип", before.PaymentType.String(), after.PaymentType.String())
+	writeDiffLine(&sb, "Сумма", amount(before), amount(after))
+	writeDiffLine(&sb, "Описание", before.Description, after.Description)
+	writeDiffLine(&sb, "Категория", categories.Name(before.Category), categories.Name(after.Category))
+
+	if sb.Len() == 0 {
+		return "Ничего не изменилось.\n"
+	}
+
+	return sb.String()
+}
+
+// editExpense заменяет поля траты id разобранным текстом, сохраняя автора и дату создания,
+// если в тексте нет новой даты.
+func editExpense(ctx context.Context, c telebot.Context, db Database, p *message.Printer,
+	loc *time.Location, id model.ExpenseID, text string) error {
+	now := time.Now().In(loc)
+	categories := db.CategoryRegistry()
+
+	parsed, err := parser.MessageAt(categories, text, now)
+	if err != nil {
+		return c.Send(getFriendlyError(err))
+	}
+
+	before, err := editableExpense(ctx, c, db, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return c.Send("❌ Не нашел трату с таким ID.")
+		}
+
+		if errors.Is(err, model.ErrForbidden) {
+			return c.Send(forbiddenMessage)
+		}
+
+		return c.Send("❌ Не получилось найти трату, может, еще разок попробуем?")
+	}
+
+	after := before
+	after.UpdatedAt = now
+	after.Category = parsed.Category
+	after.PaymentType = parsed.PaymentType
+	after.Description = parsed.Description
+	after.Amount = parsed.Amount
+	after.Currency = cmp.Or(parsed.Currency, currentProject(c).Currency)
+
+	// Дату меняем, только если ее написали явно, иначе трата осталась бы на сегодня.
+	if _, ok := parser.Date(text, now); ok {
+		after.CreatedAt = parsed.CreatedAt
+	}
+
+	err = db.Update(actorContext(ctx, c), after)
+	if err != nil {
+		return c.Send("❌ Не получилось исправить, может, еще разок попробуем?")
+	}
+
+	return c.Send("<b>✏️ Исправил:</b>\n\n"+formatExpenseDiffHTML(p, categories, before, after, loc)+"\n"+
+		formatExpenseHTML(p, categories, after, loc),
+		&telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+}
+
+func editHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		tags := c.Args()
+		if len(tags) < 2 { //nolint:mnd
+			return c.Send(editUsageMessage)
+		}
+
+		id := parser.ID(tags[0])
+		if id == uuid.Nil {
+			return c.Send(editUsageMessage)
+		}
+
+		return editExpense(ctx, c, db, p, loc, id, strings.Join(tags[1:], " "))
+	}
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/export"
+	"kudadeli/parser"
+)
+
+const exportUsageMessage = "❌ Формат: `/export [csv|xlsx] [период]`, например: `/export xlsx месяц`"
+
+func exportHandler(ctx context.Context, db Database, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		args := c.Args()
+		format := export.FormatCSV
+
+		if len(args) > 0 {
+			if f, ok := export.ParseFormat(strings.ToLower(args[0])); ok {
+				format = f
+				args = args[1:]
+			}
+		}
+
+		// Без периода выгружаем все траты проекта.
+		filter := projectFilter(c)
+
+		if len(args) > 0 {
+			from, to, err := parser.Period(args, time.Now().In(loc))
+			if err != nil {
+				return c.Send(exportUsageMessage)
+			}
+
+			filter.From, filter.To = from, to
+		}
+
+		expenses, _, err := db.Find(ctx, filter)
+		if err != nil {
+			return c.Send("❌ Не получилось получить список трат, может, еще разок попробуем?")
+		}
+
+		if len(expenses) == 0 {
+			return c.Send("❌ Список трат пуст.")
+		}
+
+		var buf bytes.Buffer
+
+		err = export.Write(&buf, format, expenses, db.CategoryRegistry(), loc)
+		if err != nil {
+			return c.Send("❌ Не получилось собрать файл, может, еще разок попробуем?")
+		}
+
+		return c.Send(&telebot.Document{
+			File:     telebot.FromReader(&buf),
+			FileName: format.FileName(time.Now().In(loc)),
+			MIME:     format.ContentType(),
+			Caption:  "📎 Трат: " + strconv.Itoa(len(expenses)),
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"strings"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/parser"
+)
+
+const textContextKey = "text"
+
+// addressedText возвращает текст сообщения из группы без обращения к боту, если сообщение
+// адресовано боту: это ответ на сообщение бота, в нем упомянут бот, оно начинается
+// с префикса prefix или строго записано как трата (см. parser.IsStrict). У фото и файлов
+// проверяется подпись.
+func addressedText(c telebot.Context, prefix string) (string, bool) {
+	msg := c.Message()
+	me := c.Bot().Me
+
+	if msg == nil || me == nil {
+		return "", false
+	}
+
+	text := strings.TrimSpace(c.Text())
+
+	if msg.ReplyTo != nil && msg.ReplyTo.Sender != nil && msg.ReplyTo.Sender.ID == me.ID {
+		return text, true
+	}
+
+	if me.Username != "" {
+		for _, entity := range c.Entities() {
+			mention := msg.EntityText(entity)
+
+			if entity.Type == telebot.EntityMention && strings.EqualFold(mention, "@"+me.Username) {
+				return strings.TrimSpace(strings.Replace(text, mention, "", 1)), true
+			}
+		}
+	}
+
+	if prefix != "" {
+		if rest, ok := strings.CutPrefix(text, prefix); ok {
+			return strings.TrimSpace(rest), true
+		}
+	}
+
+	return text, parser.IsStrict(text)
+}
+
+// groupMessages пропускает в группе только сообщения, адресованные боту, и запоминает
+// их текст без обращения к боту, см. messageText. Остальную переписку группы бот молча
+// пропускает. В личке пропускает все. Стоит первым, до проверок доступа и проекта:
+// иначе бот отвечал бы на чужую переписку группы отказами.
+func groupMessages(prefix string) telebot.MiddlewareFunc {
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			if !isGroupChat(c) {
+				return next(c)
+			}
+
+			text, ok := addressedText(c, prefix)
+			if !ok {
+				return nil
+			}
+
+			c.Set(textContextKey, text)
+
+			return next(c)
+		}
+	}
+}
+
+// messageText возвращает текст или подпись сообщения, очищенные groupMessages, а в личке — как есть.
+func messageText(c telebot.Context) string {
+	if text, ok := c.Get(textContextKey).(string); ok {
+		return text
+	}
+
+	return c.Text()
+}
+
+// groupHint объясняет, как записывать траты в группе. С включенным режимом приватности
+// Telegram присылает боту из группы только команды и ответы на его сообщения.
+func groupHint(c telebot.Context, prefix string) string {
+	if me := c.Bot().Me; me != nil && me.CanReadMessages {
+		hint := "\n\nВ группе пишите траты как обычно: «нал 1500 краска»"
+		if prefix != "" {
+			hint += ", или с «" + prefix + "» в начале"
+		}
+
+		if me.Username != "" {
+			hint += ", или упомянув @" + me.Username
+		}
+
+		return hint + ". Остальную переписку я пропускаю."
+	}
+
+	return "\n\nУ меня включен режим приватности, поэтому обычные сообщения группы до меня не доходят: " +
+		"записывайте траты командой /add нал 1500 краска или ответом на мое сообщение."
+}
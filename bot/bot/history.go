@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/database"
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+var (
+	actionNames = map[model.ChangeAction]string{ //nolint:gochecknoglobals
+		model.ActionCreate:   "➕ создана",
+		model.ActionUpdate:   "✏️ изменена",
+		model.ActionCategory: "🗂 сменена категория",
+		model.ActionDelete:   "🗑 удалена",
+		model.ActionRestore:  "♻️ восстановлена",
+	}
+
+	sourceNames = map[model.ChangeSource]string{ //nolint:gochecknoglobals
+		model.SourceBot:    "бот",
+		model.SourceWeb:    "веб",
+		model.SourceSystem: "система",
+	}
+
+	fieldNames = map[string]string{ //nolint:gochecknoglobals
+		"createdAt":   "Дата",
+		"paymentType": "Тип",
+		"amount":      "Сумма",
+		"currency":    "Валюта",
+		"category":    "Категория",
+		"description": "Описание",
+		"deletedAt":   "Удалена",
+	}
+)
+
+// actorContext помечает изменения трат из этого обновления автором сообщения, для журнала.
+func actorContext(ctx context.Context, c telebot.Context) context.Context {
+	actor := database.Actor{Source: model.SourceBot}
+	if sender := c.Sender(); sender != nil {
+		actor.UserID = sender.ID
+	}
+
+	return database.WithActor(ctx, actor)
+}
+
+// formatFieldValue показывает даты из журнала в часовом поясе loc, остальное — как есть.
+func formatFieldValue(field, value string, loc *time.Location) string {
+	if value == "" {
+		return "—"
+	}
+
+	if field == "createdAt" || field == "deletedAt" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t.In(loc).Format("02.01.2006 15:04")
+		}
+	}
+
+	return value
+}
+
+func formatHistoryHTML(entries []model.HistoryEntry, loc *time.Location) string {
+	var sb strings.Builder
+
+	for _, entry := range entries {
+		sb.WriteString("<b>")
+		sb.WriteString(entry.ChangedAt.In(loc).Format("02.01.2006 15:04"))
+		sb.WriteString("</b> ")
+		sb.WriteString(html.EscapeString(actionNames[entry.Action]))
+		sb.WriteString(" (")
+		sb.WriteString(html.EscapeString(sourceNames[entry.Source]))
+
+		if entry.UserID != 0 {
+			sb.WriteString(", <code>")
+			sb.WriteString(strconv.FormatInt(entry.UserID, 10))
+			sb.WriteString("</code>")
+		}
+
+		sb.WriteString(")\n")
+
+		// У созданной траты показываем только значения, без стрелок.
+		for _, change := range entry.Changes {
+			sb.WriteString("  ")
+			sb.WriteString(html.EscapeString(fieldNames[change.Field]))
+			sb.WriteString(": ")
+
+			if entry.Action != model.ActionCreate {
+				sb.WriteString(html.EscapeString(formatFieldValue(change.Field, change.Old, loc)))
+				sb.WriteString(" → ")
+			}
+
+			sb.WriteString(html.EscapeString(formatFieldValue(change.Field, change.New, loc)))
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String()
+}
+
+// historyExpense ищет трату текущего проекта, в том числе в корзине: у удаленной траты
+// журнал тоже нужен.
+func historyExpense(ctx context.Context, c telebot.Context, db Database, id model.ExpenseID) error {
+	_, err := projectExpense(ctx, c, db, id)
+	if !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+
+	expense, err := db.GetDeleted(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if expense.ProjectID != currentProject(c).ID || !currentRole(c).CanView(c.Sender().ID, expense) {
+		return model.ErrNotFound
+	}
+
+	return nil
+}
+
+// historyHandler показывает журнал изменений траты: кто, когда и откуда ее менял.
+func historyHandler(ctx context.Context, db Database, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		tags := c.Args()
+		if len(tags) == 0 {
+			return c.Send("❌ Укажи ID траты, историю которой хочешь посмотреть.")
+		}
+
+		id := parser.ID(tags[0])
+		if id == uuid.Nil {
+			return c.Send("❌ Укажи ID траты, историю которой хочешь посмотреть.")
+		}
+
+		err := historyExpense(ctx, c, db, id)
+		if errors.Is(err, model.ErrNotFound) {
+			return c.Send("❌ Не нашел трату с таким ID.")
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "historyExpense", "error", err)
+
+			return c.Send("❌ Не получилось найти трату, может, еще разок попробуем?")
+		}
+
+		entries, err := db.History(ctx, id)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.History", "error", err)
+
+			return c.Send("❌ Не получилось получить историю, может, еще разок попробуем?")
+		}
+
+		if len(entries) == 0 {
+			return c.Send("📜 У этой траты пока нет истории изменений.")
+		}
+
+		return c.Send("<b>📜 История изменений:</b>\n\n"+formatHistoryHTML(entries, loc), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/importer"
+)
+
+const maxImportFileSize = 5 << 20
+
+func isDryRunCaption(caption string) bool {
+	caption = strings.ToLower(caption)
+
+	return strings.Contains(caption, "проверка") || strings.Contains(caption, "dry")
+}
+
+// importHandler импортирует траты из присланного CSV. С подписью «проверка» файл
+// только проверяется, без записи.
+func importHandler(ctx context.Context, db Database, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		doc := c.Message().Document
+		if doc == nil || !strings.EqualFold(filepath.Ext(doc.FileName), ".csv") {
+			return c.Send("❌ Я умею импортировать только CSV-файлы.")
+		}
+
+		if doc.FileSize > maxImportFileSize {
+			return c.Send("❌ Файл слишком большой, максимум 5 МБ.")
+		}
+
+		r, err := c.Bot().File(&doc.File)
+		if err != nil {
+			return c.Send("❌ Не получилось скачать файл, может, еще разок попробуем?")
+		}
+		defer r.Close()
+
+		result, err := importer.Run(actorContext(ctx, c), db, r, importer.Options{
+			Location:  loc,
+			UserID:    c.Sender().ID,
+			ProjectID: currentProject(c).ID,
+		}, isDryRunCaption(messageText(c)))
+		if err != nil {
+			return c.Send("❌ Не получилось импортировать: " + err.Error())
+		}
+
+		return c.Send(result.String())
+	}
+}
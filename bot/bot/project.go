@@ -0,0 +1,286 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+const (
+	projectContextKey = "project"
+
+	projectUsageMessage = "❌ Формат: `/project` — список проектов, `/project [ID]` — переключиться, " +
+		"`/project new [название] [валюта]` — создать, `/project add [ID пользователя]` — добавить участника"
+)
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`) //nolint:gochecknoglobals
+
+func isGroupChat(c telebot.Context) bool {
+	chat := c.Chat()
+
+	return chat != nil && (chat.Type == telebot.ChatGroup || chat.Type == telebot.ChatSuperGroup)
+}
+
+// projectMiddleware определяет проект, с которым работает сообщение: в группе — проект,
+// привязанный к чату, иначе активный проект отправителя. В группе с проектом работают
+// только его участники.
+func projectMiddleware(ctx context.Context, db Database) telebot.MiddlewareFunc {
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			sender := c.Sender()
+			if sender == nil {
+				return nil
+			}
+
+			if isGroupChat(c) {
+				project, err := db.ChatProject(ctx, c.Chat().ID)
+				if err == nil {
+					return chatProject(ctx, c, db, project, next)
+				}
+
+				if !errors.Is(err, model.ErrNotFound) {
+					slog.ErrorContext(ctx, "database.ChatProject", "error", err)
+
+					return c.Send("❌ Не получилось определить проект, может, еще разок попробуем?")
+				}
+			}
+
+			project, err := db.ActiveProject(ctx, sender.ID)
+			if errors.Is(err, model.ErrNotFound) {
+				return c.Send("❌ Ты пока не участвуешь ни в одном проекте, попроси владельца добавить: /project add " +
+					strconv.FormatInt(sender.ID, 10))
+			}
+
+			if err != nil {
+				slog.ErrorContext(ctx, "database.ActiveProject", "error", err)
+
+				return c.Send("❌ Не получилось определить проект, может, еще разок попробуем?")
+			}
+
+			c.Set(projectContextKey, project)
+
+			return next(c)
+		}
+	}
+}
+
+// chatProject пропускает к проекту группового чата только его участников.
+func chatProject(ctx context.Context, c telebot.Context, db Database, project model.Project,
+	next telebot.HandlerFunc) error {
+	isMember, err := db.IsProjectMember(ctx, project.ID, c.Sender().ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.IsProjectMember", "error", err)
+
+		return c.Send("❌ Не получилось определить проект, может, еще разок попробуем?")
+	}
+
+	if !isMember {
+		return c.Send("❌ Ты не участник проекта «" + project.Name + "», к которому привязан этот чат. " +
+			"Попроси владельца добавить: /project add " + strconv.FormatInt(c.Sender().ID, 10))
+	}
+
+	c.Set(projectContextKey, project)
+
+	return next(c)
+}
+
+// currentProject возвращает проект, выбранный projectMiddleware.
+func currentProject(c telebot.Context) model.Project {
+	if project, ok := c.Get(projectContextKey).(model.Project); ok {
+		return project
+	}
+
+	return model.Project{ID: model.DefaultProjectID, Currency: model.DefaultCurrency}
+}
+
+// projectFilter возвращает фильтр по тратам текущего проекта, которые видит отправитель:
+// подрядчик видит только свои.
+func projectFilter(c telebot.Context) database.ExpenseFilter {
+	filter := database.ExpenseFilter{ProjectIDs: []model.ProjectID{currentProject(c).ID}}
+
+	if !can(c, model.PermissionViewAll) {
+		filter.UserIDs = []int64{c.Sender().ID}
+	}
+
+	return filter
+}
+
+// projectExpense возвращает трату, только если она из текущего проекта и отправитель может ее видеть.
+func projectExpense(ctx context.Context, c telebot.Context, db Database, id model.ExpenseID) (model.Expense, error) {
+	expense, err := db.Get(ctx, id)
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	if expense.ProjectID != currentProject(c).ID || !currentRole(c).CanView(c.Sender().ID, expense) {
+		return model.Expense{}, model.ErrNotFound
+	}
+
+	return expense, nil
+}
+
+func formatProjectsHTML(projects []model.Project, current model.Project) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>📁 Проекты:</b>\n\n")
+
+	for _, project := range projects {
+		if project.ID == current.ID {
+			sb.WriteString("👉 ")
+		}
+
+		sb.WriteString(strconv.FormatInt(project.ID, 10))
+		sb.WriteString(". ")
+		sb.WriteString(html.EscapeString(project.Name))
+		sb.WriteString(" (")
+		sb.WriteString(html.EscapeString(project.Currency))
+		sb.WriteString(")\n")
+	}
+
+	sb.WriteString("\nПереключиться: /project [ID]")
+
+	return sb.String()
+}
+
+func createProject(ctx context.Context, c telebot.Context, db Database, args []string, groupPrefix string) error {
+	currency := ""
+
+	if len(args) > 1 && currencyRe.MatchString(args[len(args)-1]) {
+		currency = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+
+	name := strings.Join(args, " ")
+	if name == "" {
+		return c.Send(projectUsageMessage)
+	}
+
+	project, err := db.CreateProject(ctx, name, currency, c.Sender().ID)
+	if err != nil {
+		return c.Send("❌ Не получилось создать проект, может, еще разок попробуем?")
+	}
+
+	hint := ""
+
+	if isGroupChat(c) {
+		err = db.LinkChat(ctx, c.Chat().ID, project.ID)
+		if err != nil {
+			return c.Send("❌ Проект создал, а привязать к чату не получилось.")
+		}
+
+		hint = groupHint(c, groupPrefix)
+	}
+
+	return c.Send("✅ Создал проект «" + project.Name + "», теперь траты пишутся в него." + hint)
+}
+
+func addProjectMember(ctx context.Context, c telebot.Context, db Database, args []string) error {
+	if len(args) != 1 {
+		return c.Send(projectUsageMessage)
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || userID <= 0 {
+		return c.Send(projectUsageMessage)
+	}
+
+	project := currentProject(c)
+
+	err = db.AddProjectMember(ctx, project.ID, userID)
+	if err != nil {
+		return c.Send("❌ Не получилось добавить участника, может, еще разок попробуем?")
+	}
+
+	return c.Send("✅ Добавил участника в проект «" + project.Name + "».")
+}
+
+// switchProject переключает активный проект пользователя, а в группе привязывает
+// к проекту весь чат.
+func switchProject(ctx context.Context, c telebot.Context, db Database, projectID model.ProjectID,
+	groupPrefix string) error {
+	var err error
+
+	if isGroupChat(c) {
+		var isMember bool
+
+		isMember, err = db.IsProjectMember(ctx, projectID, c.Sender().ID)
+		if err == nil && !isMember {
+			err = model.ErrNotFound
+		}
+
+		if err == nil {
+			err = db.LinkChat(ctx, c.Chat().ID, projectID)
+		}
+	} else {
+		err = db.SetActiveProject(ctx, c.Sender().ID, projectID)
+	}
+
+	if errors.Is(err, model.ErrNotFound) {
+		return c.Send("❌ Не нашел у тебя проект с таким ID.")
+	}
+
+	if err != nil {
+		return c.Send("❌ Не получилось переключить проект, может, еще разок попробуем?")
+	}
+
+	hint := ""
+	if isGroupChat(c) {
+		hint = groupHint(c, groupPrefix)
+	}
+
+	project, err := db.Project(ctx, projectID)
+	if err != nil {
+		return c.Send("✅ Проект переключен." + hint)
+	}
+
+	return c.Send("✅ Теперь траты пишутся в проект «" + project.Name + "»." + hint)
+}
+
+func projectHandler(ctx context.Context, db Database, groupPrefix string) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		args := c.Args()
+
+		if len(args) == 0 {
+			projects, err := db.UserProjects(ctx, c.Sender().ID)
+			if err != nil {
+				return c.Send("❌ Не получилось получить проекты, может, еще разок попробуем?")
+			}
+
+			return c.Send(formatProjectsHTML(projects, currentProject(c)), &telebot.SendOptions{
+				ParseMode: telebot.ModeHTML,
+			})
+		}
+
+		// Создавать проекты, добавлять участников и привязывать группы может только владелец.
+		switch strings.ToLower(args[0]) {
+		case "new", "новый":
+			return requires(model.PermissionAdmin, func(c telebot.Context) error {
+				return createProject(ctx, c, db, args[1:], groupPrefix)
+			})(c)
+		case "add", "добавить":
+			return requires(model.PermissionAdmin, func(c telebot.Context) error {
+				return addProjectMember(ctx, c, db, args[1:])
+			})(c)
+		}
+
+		projectID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || len(args) != 1 {
+			return c.Send(projectUsageMessage)
+		}
+
+		if isGroupChat(c) && !can(c, model.PermissionAdmin) {
+			return c.Send(forbiddenMessage)
+		}
+
+		return switchProject(ctx, c, db, projectID, groupPrefix)
+	}
+}
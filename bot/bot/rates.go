@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const rateUsageMessage = "❌ Формат: `/rate` — курсы, `/rate [валюта] [курс] [дата]` — задать курс к валюте проекта, " +
+	"например: `/rate usd 92,5` или `/rate евро 100 01.10`"
+
+// formatRateHTML — «1 USD = 92,5000 ₽ (с 01.10.2026)».
+func formatRateHTML(p *message.Printer, rate model.ExchangeRate) string {
+	return "1 " + html.EscapeString(rate.Currency) + " = " +
+		html.EscapeString(p.Sprintf("%.4f", rate.Rate.InexactFloat64())) + " " +
+		html.EscapeString(model.CurrencySymbol(rate.Base)) + " (с " + rate.Date.Format("02.01.2006") + ")"
+}
+
+func formatRatesHTML(p *message.Printer, rates []model.ExchangeRate) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>💱 Курсы:</b>\n\n")
+
+	for _, rate := range rates {
+		sb.WriteString(formatRateHTML(p, rate))
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// parseRate разбирает «[валюта] [курс] [дата]». Курс читается как есть, без суффиксов
+// тысяч: у курсов бывает и три знака после запятой.
+func parseRate(args []string, base string, now time.Time) (model.ExchangeRate, bool) {
+	if len(args) < 2 { //nolint:mnd
+		return model.ExchangeRate{}, false
+	}
+
+	currency, ok := parser.Currency(args[0])
+	if !ok {
+		return model.ExchangeRate{}, false
+	}
+
+	rate, err := decimal.NewFromString(strings.ReplaceAll(args[1], ",", "."))
+	if err != nil {
+		return model.ExchangeRate{}, false
+	}
+
+	date := now
+
+	if len(args) > 2 { //nolint:mnd
+		date, ok = parser.Date(strings.Join(args[2:], " "), now)
+		if !ok {
+			return model.ExchangeRate{}, false
+		}
+	}
+
+	return model.ExchangeRate{Date: date, Currency: currency, Base: base, Rate: rate}, true
+}
+
+// rateHandler показывает и задает курсы валют к валюте текущего проекта. Курс действует
+// с указанной даты и до следующего, по нему отчеты пересчитывают траты в других валютах.
+func rateHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		base := currentProject(c).Currency
+
+		if len(c.Args()) == 0 {
+			rates, err := db.LatestRates(ctx, base)
+			if err != nil {
+				slog.ErrorContext(ctx, "database.LatestRates", "error", err)
+
+				return c.Send("❌ Не получилось получить курсы, может, еще разок попробуем?")
+			}
+
+			if len(rates) == 0 {
+				return c.Send("💱 Курсов пока нет. Задать: /rate [валюта] [курс] [дата]")
+			}
+
+			return c.Send(formatRatesHTML(p, rates), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+
+		if !can(c, model.PermissionBudgets) {
+			return c.Send(forbiddenMessage)
+		}
+
+		rate, ok := parseRate(c.Args(), base, time.Now().In(loc))
+		if !ok {
+			return c.Send(rateUsageMessage)
+		}
+
+		err := db.SetRates(ctx, []model.ExchangeRate{rate})
+		if errors.Is(err, model.ErrInvalidCurrency) || errors.Is(err, model.ErrInvalidRate) {
+			return c.Send(rateUsageMessage)
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "database.SetRates", "error", err)
+
+			return c.Send("❌ Не получилось сохранить курс, может, еще разок попробуем?")
+		}
+
+		return c.Send("✅ Курс сохранен: "+formatRateHTML(p, rate), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	}
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const maxReceiptFileSize = 10 << 20
+
+// saveReceipt скачивает фото из Telegram и сохраняет его как вложение траты.
+func saveReceipt(ctx context.Context, c telebot.Context, db Database, expenseID model.ExpenseID,
+	photo *telebot.Photo) error {
+	if photo.FileSize > maxReceiptFileSize {
+		return fmt.Errorf("photo is too large: %d bytes", photo.FileSize)
+	}
+
+	r, err := c.Bot().File(&photo.File)
+	if err != nil {
+		return fmt.Errorf("download photo: %w", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxReceiptFileSize))
+	if err != nil {
+		return fmt.Errorf("read photo: %w", err)
+	}
+
+	createdAt := time.Now()
+
+	return db.AddAttachment(ctx, model.Attachment{
+		ID:             uuid.New(),
+		ExpenseID:      expenseID,
+		CreatedAt:      createdAt,
+		FileName:       "receipt-" + createdAt.Format("20060102-150405") + ".jpg",
+		MIMEType:       "image/jpeg",
+		TelegramFileID: photo.FileID,
+	}, data)
+}
+
+// photoHandler записывает трату из подписи к фото и сохраняет фото как чек.
+// Фото в ответ на сообщение «Записал» прикрепляется к уже записанной трате.
+func photoHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		photo := c.Message().Photo
+
+		if !can(c, model.PermissionRecord) {
+			return c.Send(forbiddenMessage)
+		}
+
+		if id := replyExpenseID(c); id != uuid.Nil {
+			_, err := editableExpense(ctx, c, db, id)
+			if err == nil {
+				err = saveReceipt(ctx, c, db, id, photo)
+			}
+
+			if errors.Is(err, model.ErrNotFound) {
+				return c.Send("❌ Не нашел трату с таким ID.")
+			}
+
+			if errors.Is(err, model.ErrForbidden) {
+				return c.Send(forbiddenMessage)
+			}
+
+			if err != nil {
+				slog.ErrorContext(ctx, "saveReceipt", "error", err)
+
+				return c.Send("❌ Не получилось сохранить чек, может, еще разок попробуем?")
+			}
+
+			return c.Send("📎 Чек прикреплен.")
+		}
+
+		expense, err := parser.MessageAt(db.CategoryRegistry(), messageText(c), time.Now().In(loc))
+		if err != nil {
+			return c.Send(getFriendlyError(err))
+		}
+
+		expense.UserID = c.Sender().ID
+		expense.ProjectID = currentProject(c).ID
+		expense.Currency = cmp.Or(expense.Currency, currentProject(c).Currency)
+
+		err = db.Insert(actorContext(ctx, c), expense)
+		if err != nil {
+			return c.Send("❌ Не получилось записать, может, еще разок попробуем?")
+		}
+
+		note := "\n📎 Чек сохранен."
+
+		err = saveReceipt(ctx, c, db, expense.ID, photo)
+		if err != nil {
+			slog.ErrorContext(ctx, "saveReceipt", "error", err)
+
+			note = "\n❌ Трату записал, а чек сохранить не получилось."
+		}
+
+		return confirmExpense(ctx, c, db, p, loc, expense, note)
+	}
+}
+
+func receiptHandler(ctx context.Context, db Database) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		tags := c.Args()
+		if len(tags) == 0 {
+			return c.Send("❌ Укажи ID траты, чек которой показать.")
+		}
+
+		id := parser.ID(tags[0])
+		if id == uuid.Nil {
+			return c.Send("❌ Укажи ID траты, чек которой показать.")
+		}
+
+		_, err := projectExpense(ctx, c, db, id)
+		if errors.Is(err, model.ErrNotFound) {
+			return c.Send("❌ Не нашел трату с таким ID.")
+		}
+
+		if err != nil {
+			return c.Send("❌ Не получилось найти трату, может, еще разок попробуем?")
+		}
+
+		attachments, err := db.Attachments(ctx, id)
+		if err != nil {
+			return c.Send("❌ Не получилось найти чеки, может, еще разок попробуем?")
+		}
+
+		if len(attachments) == 0 {
+			return c.Send("❌ К этой трате чеков нет.")
+		}
+
+		for _, a := range attachments {
+			file := telebot.File{FileID: a.TelegramFileID}
+
+			if a.TelegramFileID == "" {
+				_, data, err := db.Attachment(ctx, id, a.ID)
+				if err != nil {
+					return c.Send("❌ Не получилось достать чек, может, еще разок попробуем?")
+				}
+
+				file = telebot.FromReader(bytes.NewReader(data))
+			}
+
+			var what any = &telebot.Document{File: file, FileName: a.FileName, MIME: a.MIMEType}
+			if strings.HasPrefix(a.MIMEType, "image/") {
+				what = &telebot.Photo{File: file}
+			}
+
+			err := c.Send(what)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
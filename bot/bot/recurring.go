@@ -0,0 +1,454 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const (
+	recurringInterval = time.Minute
+	// maxCatchUpRuns ограничивает число пропущенных срабатываний, которые предлагаются
+	// после простоя: ежедневный шаблон за месяц простоя не должен завалить чат.
+	maxCatchUpRuns = 10
+
+	btnRecurringRecord = "recurring_record"
+	btnRecurringSkip   = "recurring_skip"
+
+	recurringUsageMessage = "❌ Формат: `/recurring` — шаблоны, `/recurring add [расписание] | [трата]` — добавить, " +
+		"например: `/recurring add 0 9 * * 5 | нал 15000 услуги прораб` или `/recurring add ежемесячно | карта 5000 склад`; " +
+		"`/recurring pause|resume|delete [ID]`, `/recurring catchup [ID] on|off` — догонять пропущенные после простоя"
+)
+
+func formatRecurringHTML(p *message.Printer, categories *model.CategoryRegistry, templates []model.RecurringExpense,
+	loc *time.Location) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>🔁 Повторяющиеся траты:</b>\n\n")
+
+	for _, r := range templates {
+		sb.WriteString("<b>")
+		sb.WriteString(strconv.FormatInt(r.ID, 10))
+		sb.WriteString(".</b> <code>")
+		sb.WriteString(html.EscapeString(r.Schedule))
+		sb.WriteString("</code> — ")
+		sb.WriteString(html.EscapeString(formatMoney(p, r.Amount, r.Currency)))
+		sb.WriteString(", ")
+		sb.WriteString(html.EscapeString(r.PaymentType.String()))
+		sb.WriteString(", ")
+		sb.WriteString(html.EscapeString(categories.Name(r.Category)))
+
+		if r.Description != "" {
+			sb.WriteString(" — ")
+			sb.WriteString(html.EscapeString(r.Description))
+		}
+
+		sb.WriteString("\n   ")
+
+		if r.Paused {
+			sb.WriteString("⏸ на паузе")
+		} else {
+			sb.WriteString("следующая: ")
+			sb.WriteString(html.EscapeString(formatExpenseDate(r.NextRun, time.Now(), loc)))
+		}
+
+		if r.CatchUp {
+			sb.WriteString(", догоняет пропущенные")
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func recurringMarkup(id model.RecurringID, at time.Time) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	data := []string{strconv.FormatInt(id, 10), strconv.FormatInt(at.Unix(), 10)}
+
+	markup.Inline(markup.Row(
+		markup.Data("✅ Записать", btnRecurringRecord, data...),
+		markup.Data("⏭ Пропустить", btnRecurringSkip, data...),
+	))
+
+	return markup
+}
+
+// dueRuns возвращает срабатывания шаблона с r.NextRun по now. Без CatchUp — только
+// последнее, остальные пропускаются; с CatchUp — не больше maxCatchUpRuns последних.
+func dueRuns(r model.RecurringExpense, schedule parser.Schedule, now time.Time, loc *time.Location) []time.Time {
+	var runs []time.Time
+
+	for at, ok := r.NextRun.In(loc), true; ok && !at.After(now); at, ok = schedule.Next(at) {
+		runs = append(runs, at)
+		if len(runs) > maxCatchUpRuns {
+			runs = runs[1:]
+		}
+	}
+
+	if !r.CatchUp && len(runs) > 1 {
+		runs = runs[len(runs)-1:]
+	}
+
+	return runs
+}
+
+// proposeRecurring отправляет автору шаблона предложения записать трату по каждому
+// наступившему срабатыванию и переносит следующее срабатывание. Если отправить не
+// удалось, шаблон останется должным с этого срабатывания и повторится на следующем тике.
+func (s *Service) proposeRecurring(ctx context.Context, r model.RecurringExpense, now time.Time) error {
+	schedule, err := parser.ParseSchedule(r.Schedule)
+	if err != nil {
+		// Сломанное расписание не должно срабатывать каждую минуту: ставим на паузу.
+		slog.ErrorContext(ctx, "parse recurring schedule", "id", r.ID, "error", err)
+
+		r.Paused = true
+
+		return s.db.UpdateRecurring(ctx, r)
+	}
+
+	runs := dueRuns(r, schedule, now, s.loc)
+
+	for _, at := range runs {
+		expense := r.Expense(at, now)
+
+		_, err = s.bot.Send(&telebot.User{ID: r.UserID},
+			"<b>🔁 Пора записать повторяющуюся трату</b> (шаблон "+strconv.FormatInt(r.ID, 10)+"):\n\n"+
+				formatExpenseHTML(s.p, s.db.CategoryRegistry(), expense, s.loc),
+			&telebot.SendOptions{ParseMode: telebot.ModeHTML, ReplyMarkup: recurringMarkup(r.ID, at)})
+		if err != nil {
+			slog.ErrorContext(ctx, "send recurring proposal", "id", r.ID, "user_id", r.UserID, "error", err)
+
+			return s.db.SetRecurringNextRun(ctx, r.ID, at)
+		}
+	}
+
+	next, ok := schedule.Next(now.In(s.loc))
+	if !ok {
+		r.Paused = true
+
+		return s.db.UpdateRecurring(ctx, r)
+	}
+
+	return s.db.SetRecurringNextRun(ctx, r.ID, next)
+}
+
+// RunRecurring раз в recurringInterval предлагает записать наступившие повторяющиеся траты.
+// Срабатывания, пропущенные, пока бот не работал, догоняются при первом же проходе.
+func (s *Service) RunRecurring(ctx context.Context) error {
+	ticker := time.NewTicker(recurringInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+
+		due, err := s.db.DueRecurring(ctx, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.DueRecurring", "error", err)
+		}
+
+		for _, r := range due {
+			err := s.proposeRecurring(ctx, r, now)
+			if err != nil {
+				slog.ErrorContext(ctx, "propose recurring", "id", r.ID, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// addRecurring разбирает «[расписание] | [трата]». Трата пишется как обычное сообщение,
+// дата в ней не учитывается: ее задает расписание.
+func addRecurring(ctx context.Context, c telebot.Context, db Database, p *message.Printer,
+	loc *time.Location, payload string) error {
+	if !can(c, model.PermissionRecord) {
+		return c.Send(forbiddenMessage)
+	}
+
+	scheduleText, expenseText, ok := strings.Cut(payload, "|")
+	if !ok {
+		return c.Send(recurringUsageMessage)
+	}
+
+	schedule, err := parser.ParseSchedule(scheduleText)
+	if err != nil {
+		return c.Send("❌ Не понял расписание: " + strings.TrimPrefix(err.Error(), parser.ErrInvalidSchedule.Error()+": "))
+	}
+
+	now := time.Now().In(loc)
+	categories := db.CategoryRegistry()
+
+	expense, err := parser.MessageAt(categories, expenseText, now)
+	if err != nil {
+		return c.Send(getFriendlyError(err))
+	}
+
+	next, _ := schedule.Next(now)
+
+	r, err := db.CreateRecurring(ctx, model.RecurringExpense{
+		ProjectID:   currentProject(c).ID,
+		UserID:      c.Sender().ID,
+		Schedule:    strings.TrimSpace(scheduleText),
+		Category:    expense.Category,
+		PaymentType: expense.PaymentType,
+		Amount:      expense.Amount,
+		Currency:    expense.Currency,
+		Description: expense.Description,
+		NextRun:     next,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "database.CreateRecurring", "error", err)
+
+		return c.Send("❌ Не получилось сохранить шаблон, может, еще разок попробуем?")
+	}
+
+	return c.Send("<b>✅ Шаблон сохранен.</b> Когда придет время, пришлю его в личку — останется подтвердить.\n\n"+
+		formatRecurringHTML(p, categories, []model.RecurringExpense{r}, loc),
+		&telebot.SendOptions{ParseMode: telebot.ModeHTML})
+}
+
+// managedRecurring возвращает шаблон текущего проекта, которым может управлять отправитель:
+// автор шаблона или администратор.
+func managedRecurring(ctx context.Context, c telebot.Context, db Database, arg string) (model.RecurringExpense, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return model.RecurringExpense{}, model.ErrNotFound
+	}
+
+	r, err := db.Recurring(ctx, id)
+	if err != nil {
+		return model.RecurringExpense{}, err
+	}
+
+	if r.ProjectID != currentProject(c).ID {
+		return model.RecurringExpense{}, model.ErrNotFound
+	}
+
+	if r.UserID != c.Sender().ID && !can(c, model.PermissionAdmin) {
+		return model.RecurringExpense{}, model.ErrForbidden
+	}
+
+	return r, nil
+}
+
+// changeRecurring выполняет pause, resume, delete и catchup над шаблоном из args[1].
+func changeRecurring(ctx context.Context, c telebot.Context, db Database, loc *time.Location,
+	args []string) error {
+	if len(args) < 2 { //nolint:mnd
+		return c.Send(recurringUsageMessage)
+	}
+
+	r, err := managedRecurring(ctx, c, db, args[1])
+
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return c.Send("❌ Не нашел такой шаблон.")
+	case errors.Is(err, model.ErrForbidden):
+		return c.Send(forbiddenMessage)
+	case err != nil:
+		slog.ErrorContext(ctx, "database.Recurring", "error", err)
+
+		return c.Send("❌ Не получилось найти шаблон, может, еще разок попробуем?")
+	}
+
+	var reply string
+
+	switch strings.ToLower(args[0]) {
+	case "delete":
+		err = db.DeleteRecurring(ctx, r.ID)
+		reply = "✅ Шаблон удален, записанные по нему траты остались."
+	case "pause":
+		r.Paused = true
+		err = db.UpdateRecurring(ctx, r)
+		reply = "⏸ Шаблон на паузе."
+	case "resume":
+		// Пока шаблон был на паузе, срабатывания не копились: считаем следующее от текущего момента.
+		schedule, parseErr := parser.ParseSchedule(r.Schedule)
+		if parseErr != nil {
+			return c.Send("❌ У шаблона сломано расписание, удали его и создай заново.")
+		}
+
+		r.Paused = false
+		r.NextRun, _ = schedule.Next(time.Now().In(loc))
+		err = db.UpdateRecurring(ctx, r)
+		reply = "▶️ Шаблон снова работает, следующая трата " + formatExpenseDate(r.NextRun, time.Now(), loc) + "."
+	case "catchup":
+		if len(args) != 3 || (args[2] != "on" && args[2] != "off") { //nolint:mnd
+			return c.Send(recurringUsageMessage)
+		}
+
+		r.CatchUp = args[2] == "on"
+		err = db.UpdateRecurring(ctx, r)
+		reply = "✅ После простоя пришлю только последнее срабатывание."
+
+		if r.CatchUp {
+			reply = "✅ После простоя пришлю все пропущенные срабатывания (не больше " + strconv.Itoa(maxCatchUpRuns) + ")."
+		}
+	default:
+		return c.Send(recurringUsageMessage)
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "change recurring", "id", r.ID, "error", err)
+
+		return c.Send("❌ Не получилось изменить шаблон, может, еще разок попробуем?")
+	}
+
+	return c.Send(reply)
+}
+
+// recurringHandler управляет шаблонами повторяющихся трат текущего проекта.
+func recurringHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		args := c.Args()
+
+		if len(args) == 0 {
+			templates, err := db.ProjectRecurring(ctx, currentProject(c).ID)
+			if err != nil {
+				slog.ErrorContext(ctx, "database.ProjectRecurring", "error", err)
+
+				return c.Send("❌ Не получилось получить шаблоны, может, еще разок попробуем?")
+			}
+
+			// Без права видеть все траты показываем только свои шаблоны.
+			if !can(c, model.PermissionViewAll) {
+				own := templates[:0]
+
+				for _, r := range templates {
+					if r.UserID == c.Sender().ID {
+						own = append(own, r)
+					}
+				}
+
+				templates = own
+			}
+
+			if len(templates) == 0 {
+				return c.Send("🔁 Повторяющихся трат пока нет. Добавить: /recurring add [расписание] | [трата]")
+			}
+
+			return c.Send(formatRecurringHTML(p, db.CategoryRegistry(), templates, loc),
+				&telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+
+		if strings.ToLower(args[0]) == "add" {
+			_, payload, _ := strings.Cut(c.Message().Payload, args[0])
+
+			return addRecurring(ctx, c, db, p, loc, payload)
+		}
+
+		return changeRecurring(ctx, c, db, loc, args)
+	}
+}
+
+// recurringButton разбирает кнопку предложения: шаблон и время срабатывания. Нажать ее
+// может только автор шаблона. Отвечает на callback сам, если нельзя.
+func recurringButton(ctx context.Context, c telebot.Context, db Database,
+	loc *time.Location) (model.RecurringExpense, time.Time, bool) {
+	args := c.Args()
+	if len(args) != 2 { //nolint:mnd
+		_ = c.Respond()
+
+		return model.RecurringExpense{}, time.Time{}, false
+	}
+
+	id, errID := strconv.ParseInt(args[0], 10, 64)
+	unix, errAt := strconv.ParseInt(args[1], 10, 64)
+
+	if errID != nil || errAt != nil {
+		_ = c.Respond()
+
+		return model.RecurringExpense{}, time.Time{}, false
+	}
+
+	r, err := db.Recurring(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			_ = c.Respond(&telebot.CallbackResponse{Text: "Этого шаблона уже нет."})
+			_ = c.Edit(&telebot.ReplyMarkup{})
+		} else {
+			slog.ErrorContext(ctx, "database.Recurring", "error", err)
+			_ = c.Respond(&telebot.CallbackResponse{Text: "Не получилось найти шаблон."})
+		}
+
+		return model.RecurringExpense{}, time.Time{}, false
+	}
+
+	if r.UserID != c.Sender().ID || !can(c, model.PermissionRecord) {
+		_ = c.Respond(&telebot.CallbackResponse{Text: "Подтвердить трату может только автор шаблона.", ShowAlert: true})
+
+		return model.RecurringExpense{}, time.Time{}, false
+	}
+
+	return r, time.Unix(unix, 0).In(loc), true
+}
+
+// registerRecurringButtons подключает кнопки «Записать» и «Пропустить» под предложением
+// повторяющейся траты.
+func registerRecurringButtons(ctx context.Context, group *telebot.Group, db Database, p *message.Printer,
+	loc *time.Location) {
+	categories := db.CategoryRegistry()
+
+	group.Handle(&telebot.Btn{Unique: btnRecurringRecord}, func(c telebot.Context) error {
+		r, at, ok := recurringButton(ctx, c, db, loc)
+		if !ok {
+			return nil
+		}
+
+		expense := r.Expense(at, time.Now())
+
+		// ID траты повторяется для того же срабатывания: второе нажатие ничего не запишет,
+		// в том числе если записанную трату уже удалили в корзину.
+		if _, err := db.Get(ctx, expense.ID); err == nil {
+			_ = c.Respond(&telebot.CallbackResponse{Text: "Эта трата уже записана."})
+
+			return c.Edit(&telebot.ReplyMarkup{})
+		}
+
+		if _, err := db.GetDeleted(ctx, expense.ID); err == nil {
+			_ = c.Respond(&telebot.CallbackResponse{Text: "Эта трата уже записана и удалена."})
+
+			return c.Edit("<b>🗑 Трата в корзине.</b> Вернуть: <code>/restore "+expense.ID.String()+"</code>",
+				&telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+
+		err := db.Insert(actorContext(ctx, c), expense)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.Insert", "error", err)
+
+			return c.Respond(&telebot.CallbackResponse{Text: "Не получилось записать, попробуй еще раз."})
+		}
+
+		return editConfirmation(c, p, categories, loc, expense, "Записано")
+	})
+
+	group.Handle(&telebot.Btn{Unique: btnRecurringSkip}, func(c telebot.Context) error {
+		r, at, ok := recurringButton(ctx, c, db, loc)
+		if !ok {
+			return nil
+		}
+
+		err := c.Respond(&telebot.CallbackResponse{Text: "Пропущено"})
+		if err != nil {
+			return err
+		}
+
+		return c.Edit("<b>⏭ Пропустил:</b>\n\n"+formatExpenseHTML(p, categories, r.Expense(at, time.Now()), loc),
+			&telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	})
+}
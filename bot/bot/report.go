@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const (
+	reportTopLimit = 5
+
+	reportUsageMessage = "❌ Формат: `/report [сегодня|неделя|месяц|все]` или `/report 01.05.2025-31.05.2025`"
+)
+
+func formatShare(amount, total decimal.Decimal) string {
+	if !total.IsPositive() {
+		return "0%"
+	}
+
+	return amount.Mul(decimal.NewFromInt(100)).Div(total).Round(0).String() + "%"
+}
+
+func writeTotalLine(sb *strings.Builder, p *message.Printer, name string, t, total model.Total, currency string) {
+	sb.WriteString("• ")
+	sb.WriteString(html.EscapeString(name))
+	sb.WriteString(": ")
+	sb.WriteString(html.EscapeString(formatMoney(p, t.Amount, currency)))
+	sb.WriteString(" (")
+	sb.WriteString(formatShare(t.Amount, total.Amount))
+	sb.WriteString(", ")
+	sb.WriteString(strconv.Itoa(t.Count))
+	sb.WriteString(")\n")
+}
+
+func formatPeriod(from, to time.Time) string {
+	if from.IsZero() && to.IsZero() {
+		return "все время"
+	}
+
+	const layout = "02.01.2006"
+
+	return from.Format(layout) + " — " + to.AddDate(0, 0, -1).Format(layout)
+}
+
+// formatReportHTML показывает отчет в валюте проекта currency: траты в других валютах
+// уже пересчитаны по курсу, а в списке крупных трат — в своей валюте. names — имена
+// участников для разбивки «кто платил».
+func formatReportHTML(p *message.Printer, categories *model.CategoryRegistry, r model.Report, loc *time.Location,
+	currency string, names map[int64]string) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>📈 Отчет за ")
+	sb.WriteString(formatPeriod(r.From, r.To))
+	sb.WriteString("</b>\n\n")
+
+	sb.WriteString("<b>Всего</b>: ")
+	sb.WriteString(html.EscapeString(formatMoney(p, r.Total.Amount, currency)))
+	sb.WriteString(", трат: ")
+	sb.WriteString(strconv.Itoa(r.Total.Count))
+	sb.WriteString("\n")
+
+	if len(r.MissingRates) > 0 {
+		sb.WriteString("⚠️ Нет курса для ")
+		sb.WriteString(html.EscapeString(strings.Join(r.MissingRates, ", ")))
+		sb.WriteString(" — эти траты не вошли в суммы. Задай курс: /rate\n")
+	}
+
+	if r.Total.Count == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("\n<b>По категориям:</b>\n")
+
+	for _, t := range r.ByCategory {
+		writeTotalLine(&sb, p, categories.Name(t.Category), t.Total, r.Total, currency)
+	}
+
+	sb.WriteString("\n<b>По типу оплаты:</b>\n")
+
+	for _, t := range r.ByPaymentType {
+		writeTotalLine(&sb, p, t.PaymentType.String(), t.Total, r.Total, currency)
+	}
+
+	if len(r.ByUser) > 1 {
+		sb.WriteString("\n<b>Кто платил:</b>\n")
+
+		for _, t := range r.ByUser {
+			writeTotalLine(&sb, p, userName(names, t.UserID), t.Total, r.Total, currency)
+		}
+	}
+
+	sb.WriteString("\n<b>Самые крупные:</b>\n")
+
+	for i, e := range r.Top {
+		sb.WriteString(strconv.Itoa(i + 1))
+		sb.WriteString(". ")
+		sb.WriteString(html.EscapeString(formatMoney(p, e.Amount, e.Currency)))
+		sb.WriteString(" — ")
+		sb.WriteString(html.EscapeString(e.Description))
+		sb.WriteString(" (")
+		sb.WriteString(html.EscapeString(categories.Name(e.Category)))
+		sb.WriteString(", ")
+		sb.WriteString(e.CreatedAt.In(loc).Format("02.01.2006"))
+		sb.WriteString(")\n")
+	}
+
+	return sb.String()
+}
+
+func reportHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		from, to, err := parser.Period(c.Args(), time.Now().In(loc))
+		if err != nil {
+			return c.Send(reportUsageMessage)
+		}
+
+		filter := projectFilter(c)
+		filter.From, filter.To = from, to
+
+		report, err := db.Report(ctx, filter, reportTopLimit)
+		if err != nil {
+			return c.Send("❌ Не получилось собрать отчет, может, еще разок попробуем?")
+		}
+
+		names := userNames(ctx, db)
+
+		return c.Send(formatReportHTML(p, db.CategoryRegistry(), report, loc, currentProject(c).Currency, names),
+			&telebot.SendOptions{
+				ParseMode: telebot.ModeHTML,
+			})
+	}
+}
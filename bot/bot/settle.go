@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+const settleUsageMessage = "❌ Формат: `/settle [период]` — кто кому сколько переводит, по умолчанию за месяц; " +
+	"`/settle share` — доли, `/settle share [ID]:[доля] ...` — задать, например `/settle share 111:70 222:30`, " +
+	"`/settle share off` — делить поровну"
+
+// formatSharePercent показывает долю участника в процентах от суммы долей.
+func formatSharePercent(share int64, balances []model.Balance) string {
+	var sum int64
+
+	for _, b := range balances {
+		sum += b.Share
+	}
+
+	return formatShare(decimal.NewFromInt(share), decimal.NewFromInt(sum))
+}
+
+func formatSettlementHTML(p *message.Printer, s model.Settlement, currency string, names map[int64]string) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>⚖️ Расчеты за ")
+	sb.WriteString(formatPeriod(s.From, s.To))
+	sb.WriteString("</b>\n\n<b>Всего</b>: ")
+	sb.WriteString(html.EscapeString(formatMoney(p, s.Total, currency)))
+	sb.WriteString("\n\n")
+
+	for _, b := range s.Balances {
+		sb.WriteString("• ")
+		sb.WriteString(html.EscapeString(userName(names, b.UserID)))
+		sb.WriteString(": внесено ")
+		sb.WriteString(html.EscapeString(formatMoney(p, b.Paid, currency)))
+		sb.WriteString(", доля ")
+		sb.WriteString(formatSharePercent(b.Share, s.Balances))
+		sb.WriteString(" — ")
+		sb.WriteString(html.EscapeString(formatMoney(p, b.Owed, currency)))
+		sb.WriteByte('\n')
+	}
+
+	if len(s.MissingRates) > 0 {
+		sb.WriteString("\n⚠️ Нет курса для ")
+		sb.WriteString(html.EscapeString(strings.Join(s.MissingRates, ", ")))
+		sb.WriteString(" — эти траты не вошли в расчет. Задай курс: /rate\n")
+	}
+
+	if len(s.Transfers) == 0 {
+		sb.WriteString("\n✅ Все в расчете.")
+
+		return sb.String()
+	}
+
+	sb.WriteString("\n<b>Кто кому:</b>\n")
+
+	for _, t := range s.Transfers {
+		sb.WriteString("• ")
+		sb.WriteString(html.EscapeString(userName(names, t.From)))
+		sb.WriteString(" → ")
+		sb.WriteString(html.EscapeString(userName(names, t.To)))
+		sb.WriteString(": ")
+		sb.WriteString(html.EscapeString(formatMoney(p, t.Amount, currency)))
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func formatSharesHTML(shares map[int64]int64, names map[int64]string) string {
+	if len(shares) == 0 {
+		return "⚖️ Доли не заданы: траты делятся поровну между теми, кто платил."
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("<b>⚖️ Доли в общих тратах:</b>\n\n")
+
+	for _, userID := range slices.Sorted(maps.Keys(shares)) {
+		sb.WriteString("• ")
+		sb.WriteString(html.EscapeString(userName(names, userID)))
+		sb.WriteString(" (<code>")
+		sb.WriteString(strconv.FormatInt(userID, 10))
+		sb.WriteString("</code>): ")
+		sb.WriteString(strconv.FormatInt(shares[userID], 10))
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteString("\nУчастники без доли в тратах не участвуют: заплаченное ими вернут.")
+
+	return sb.String()
+}
+
+// parseShares разбирает доли «ID:доля» или «ID=доля»: «111:70 222:30».
+func parseShares(args []string) (map[int64]int64, bool) {
+	shares := make(map[int64]int64, len(args))
+
+	for _, arg := range args {
+		id, share, ok := strings.Cut(arg, ":")
+		if !ok {
+			id, share, ok = strings.Cut(arg, "=")
+		}
+
+		if !ok {
+			return nil, false
+		}
+
+		userID, errID := strconv.ParseInt(id, 10, 64)
+		value, errShare := strconv.ParseInt(share, 10, 64)
+
+		if errID != nil || errShare != nil || userID <= 0 || value <= 0 {
+			return nil, false
+		}
+
+		shares[userID] = value
+	}
+
+	return shares, true
+}
+
+// sharesHandler показывает и задает доли участников текущего проекта. Доли — часть
+// расчетов, поэтому смотреть их можно только с правом видеть все траты, а менять — админу.
+func sharesHandler(ctx context.Context, c telebot.Context, db Database, args []string) error {
+	project := currentProject(c)
+
+	if !can(c, model.PermissionViewAll) {
+		return c.Send(forbiddenMessage)
+	}
+
+	if len(args) > 0 {
+		if !can(c, model.PermissionAdmin) {
+			return c.Send(forbiddenMessage)
+		}
+
+		// Без долей траты делятся поровну.
+		var shares map[int64]int64
+
+		if len(args) != 1 || (args[0] != "off" && args[0] != "поровну") {
+			var ok bool
+
+			shares, ok = parseShares(args)
+			if !ok {
+				return c.Send(settleUsageMessage)
+			}
+		}
+
+		err := db.SetShares(ctx, project.ID, shares)
+		if errors.Is(err, model.ErrNotFound) {
+			return c.Send("❌ Доли задаются только участникам проекта, добавить: /project add [ID]")
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "database.SetShares", "error", err)
+
+			return c.Send("❌ Не получилось сохранить доли, может, еще разок попробуем?")
+		}
+	}
+
+	shares, err := db.Shares(ctx, project.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.Shares", "error", err)
+
+		return c.Send("❌ Не получилось получить доли, может, еще разок попробуем?")
+	}
+
+	return c.Send(formatSharesHTML(shares, userNames(ctx, db)), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+}
+
+// settleHandler считает, кто кому сколько переводит, чтобы все заплатили по своим долям.
+func settleHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		args := c.Args()
+
+		if len(args) > 0 && (strings.ToLower(args[0]) == "share" || strings.ToLower(args[0]) == "доли") {
+			return sharesHandler(ctx, c, db, args[1:])
+		}
+
+		// Расчеты показывают траты всех участников.
+		if !can(c, model.PermissionViewAll) {
+			return c.Send(forbiddenMessage)
+		}
+
+		from, to, err := parser.Period(args, time.Now().In(loc))
+		if err != nil {
+			return c.Send(settleUsageMessage)
+		}
+
+		filter := projectFilter(c)
+		filter.From, filter.To = from, to
+
+		project := currentProject(c)
+
+		settlement, err := db.Settlement(ctx, project.ID, filter)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.Settlement", "error", err)
+
+			return c.Send("❌ Не получилось посчитать расчеты, может, еще разок попробуем?")
+		}
+
+		return c.Send(formatSettlementHTML(p, settlement, project.Currency, userNames(ctx, db)),
+			&telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	}
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+)
+
+const (
+	defaultTokenDays = 90
+
+	tokenUsageMessage = "❌ Формат: `/token` — мои токены, `/token new [название] [read|write] [дней]` — выпустить " +
+		"(по умолчанию read на 90 дней, 0 дней — бессрочно), `/token revoke [ID]` — отозвать"
+)
+
+func formatTokensHTML(tokens []model.APIToken, loc *time.Location) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>🔑 API-токены:</b>\n\n")
+
+	now := time.Now()
+
+	for _, token := range tokens {
+		sb.WriteString(strconv.FormatInt(token.ID, 10))
+		sb.WriteString(". ")
+		sb.WriteString(html.EscapeString(token.Name))
+		sb.WriteString(" (")
+		sb.WriteString(string(token.Scope))
+		sb.WriteString(", ")
+
+		switch {
+		case token.ExpiresAt == nil:
+			sb.WriteString("бессрочный")
+		case token.Expired(now):
+			sb.WriteString("истек ")
+			sb.WriteString(token.ExpiresAt.In(loc).Format("02.01.2006"))
+		default:
+			sb.WriteString("до ")
+			sb.WriteString(token.ExpiresAt.In(loc).Format("02.01.2006"))
+		}
+
+		sb.WriteString(")\n")
+	}
+
+	return sb.String()
+}
+
+// newToken разбирает «[название] [read|write] [дней]» и выпускает токен. Секрет
+// отправляется один раз, в базе остается только его хеш.
+func newToken(ctx context.Context, c telebot.Context, db Database, loc *time.Location, args []string) error {
+	if len(args) == 0 || len(args) > 3 {
+		return c.Send(tokenUsageMessage)
+	}
+
+	scope, days := model.ScopeRead, defaultTokenDays
+
+	if len(args) > 1 {
+		scope = model.TokenScope(strings.ToLower(args[1]))
+		if !scope.IsValid() {
+			return c.Send(tokenUsageMessage)
+		}
+	}
+
+	if len(args) > 2 { //nolint:mnd
+		var err error
+
+		days, err = strconv.Atoi(args[2])
+		if err != nil || days < 0 {
+			return c.Send(tokenUsageMessage)
+		}
+	}
+
+	var expiresAt *time.Time
+
+	if days > 0 {
+		t := time.Now().AddDate(0, 0, days)
+		expiresAt = &t
+	}
+
+	token, secret, err := db.CreateAPIToken(ctx, c.Sender().ID, args[0], scope, expiresAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.CreateAPIToken", "error", err)
+
+		return c.Send("❌ Не получилось выпустить токен, может, еще разок попробуем?")
+	}
+
+	expires := "бессрочный"
+	if token.ExpiresAt != nil {
+		expires = "до " + token.ExpiresAt.In(loc).Format("02.01.2006")
+	}
+
+	return c.Send("✅ Токен «"+html.EscapeString(token.Name)+"» ("+string(token.Scope)+", "+expires+"):\n\n<code>"+
+		secret+"</code>\n\nСохрани его сейчас — повторно я его не покажу. Передавай в заголовке "+
+		"<code>Authorization: Bearer ...</code>", &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+}
+
+// tokenHandler выпускает и отзывает персональные API-токены. Токен действует с правами
+// роли владельца, поэтому выпускать его можно только в личке с ботом.
+func tokenHandler(ctx context.Context, db Database, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		if c.Chat().Type != telebot.ChatPrivate {
+			return c.Send("❌ Токены выдаю только в личных сообщениях.")
+		}
+
+		args := c.Args()
+
+		if len(args) == 0 {
+			tokens, err := db.UserAPITokens(ctx, c.Sender().ID)
+			if err != nil {
+				return c.Send("❌ Не получилось получить токены, может, еще разок попробуем?")
+			}
+
+			if len(tokens) == 0 {
+				return c.Send("🔑 Токенов пока нет. Выпустить: /token new [название] [read|write] [дней]")
+			}
+
+			return c.Send(formatTokensHTML(tokens, loc), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "new":
+			return newToken(ctx, c, db, loc, args[1:])
+		case "revoke":
+			if len(args) != 2 { //nolint:mnd
+				return c.Send(tokenUsageMessage)
+			}
+
+			id, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return c.Send(tokenUsageMessage)
+			}
+
+			err = db.RevokeAPIToken(ctx, c.Sender().ID, id)
+			if errors.Is(err, model.ErrNotFound) {
+				return c.Send("❌ Не нашел такой токен.")
+			}
+
+			if err != nil {
+				return c.Send("❌ Не получилось отозвать токен, может, еще разок попробуем?")
+			}
+
+			return c.Send("✅ Токен отозван.")
+		default:
+			return c.Send(tokenUsageMessage)
+		}
+	}
+}
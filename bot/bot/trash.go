@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/message"
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/database"
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+func formatDeletedHTML(p *message.Printer, categories *model.CategoryRegistry, expenses model.Expenses,
+	loc *time.Location) string {
+	var sb strings.Builder
+
+	sb.Grow(len(expenses) * minExpenseStrlen)
+
+	for _, e := range expenses {
+		sb.WriteString(formatExpenseHTML(p, categories, e, loc))
+
+		if e.DeletedAt != nil {
+			sb.WriteString("<b>Удалена</b>: ")
+			sb.WriteString(html.EscapeString(formatExpenseDate(*e.DeletedAt, time.Now(), loc)))
+			sb.WriteByte('\n')
+		}
+
+		sb.WriteString("Вернуть: <code>/restore ")
+		sb.WriteString(e.ID.String())
+		sb.WriteString("</code>\n\n")
+	}
+
+	return sb.String()
+}
+
+// trashHandler показывает последние удаленные траты текущего проекта.
+func trashHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		filter := projectFilter(c)
+		filter.Deleted = true
+		filter.Sort = database.SortByDeletedAt
+		filter.Limit = defaultListLimit
+
+		if tags := c.Args(); len(tags) > 0 {
+			filter.Limit = parser.Integer(tags[0], defaultListLimit)
+		}
+
+		expenses, _, err := db.Find(ctx, filter)
+		if err != nil {
+			slog.ErrorContext(ctx, "database.Find", "error", err)
+
+			return c.Send("❌ Не получилось заглянуть в корзину, может, еще разок попробуем?")
+		}
+
+		if len(expenses) == 0 {
+			return c.Send("🗑 Корзина пуста.")
+		}
+
+		return c.Send("<b>🗑 Корзина:</b>\n\n"+formatDeletedHTML(p, db.CategoryRegistry(), expenses, loc),
+			&telebot.SendOptions{
+				ParseMode: telebot.ModeHTML,
+			})
+	}
+}
+
+// restoreHandler возвращает трату из корзины текущего проекта.
+func restoreHandler(ctx context.Context, db Database, p *message.Printer, loc *time.Location) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		tags := c.Args()
+		if len(tags) == 0 {
+			return c.Send("❌ Укажи ID траты из /trash, которую нужно вернуть.")
+		}
+
+		id := parser.ID(tags[0])
+		if id == uuid.Nil {
+			return c.Send("❌ Укажи ID траты из /trash, которую нужно вернуть.")
+		}
+
+		expense, err := db.GetDeleted(ctx, id)
+		if err == nil && (expense.ProjectID != currentProject(c).ID ||
+			!currentRole(c).CanView(c.Sender().ID, expense)) {
+			err = model.ErrNotFound
+		}
+
+		if err == nil && !currentRole(c).CanEdit(c.Sender().ID, expense) {
+			err = model.ErrForbidden
+		}
+
+		if err == nil {
+			err = db.Restore(actorContext(ctx, c), id)
+		}
+
+		if errors.Is(err, model.ErrNotFound) {
+			return c.Send("❌ Не нашел такую трату в корзине.")
+		}
+
+		if errors.Is(err, model.ErrForbidden) {
+			return c.Send(forbiddenMessage)
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "database.Restore", "error", err)
+
+			return c.Send("❌ Не получилось вернуть трату, может, еще разок попробуем?")
+		}
+
+		expense.DeletedAt = nil
+
+		return c.Send("<b>♻️ Вернул:</b>\n\n"+formatExpenseHTML(p, db.CategoryRegistry(), expense, loc), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"gopkg.in/telebot.v3"
+
+	"kudadeli/model"
+)
+
+const usersUsageMessage = "❌ Формат: `/users` — список, `/users add [ID] [роль]` — выдать или сменить роль, " +
+	"`/users remove [ID]` — забрать доступ. Роли: owner, editor, viewer, contractor"
+
+// displayName — имя из профиля Telegram: имя и фамилия, а если их нет — @username.
+func displayName(user *telebot.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" && user.Username != "" {
+		name = "@" + user.Username
+	}
+
+	return name
+}
+
+// userNames возвращает сохраненные имена пользователей по ID. Если имена получить
+// не удалось, возвращает пустой словарь: вместо имен покажем ID.
+func userNames(ctx context.Context, db Database) map[int64]string {
+	names := make(map[int64]string)
+
+	users, err := db.Users(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.Users", "error", err)
+
+		return names
+	}
+
+	for _, user := range users {
+		if user.Name != "" {
+			names[user.ID] = user.Name
+		}
+	}
+
+	return names
+}
+
+// userName возвращает имя пользователя или его ID, если имя неизвестно.
+func userName(names map[int64]string, userID int64) string {
+	if name, ok := names[userID]; ok {
+		return name
+	}
+
+	return "ID " + strconv.FormatInt(userID, 10)
+}
+
+func formatUsersHTML(users []model.User) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>👥 Пользователи:</b>\n\n")
+
+	for _, user := range users {
+		sb.WriteString("<code>")
+		sb.WriteString(strconv.FormatInt(user.ID, 10))
+		sb.WriteString("</code> ")
+
+		if user.Name != "" {
+			sb.WriteString(html.EscapeString(user.Name))
+			sb.WriteByte(' ')
+		}
+
+		sb.WriteString("— ")
+		sb.WriteString(html.EscapeString(user.Role.String()))
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteString("\nВладелец может все, редактор записывает и исправляет любые траты, ")
+	sb.WriteString("наблюдатель только смотрит, подрядчик видит и исправляет только свои траты.")
+
+	return sb.String()
+}
+
+func usersErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, model.ErrLastOwner):
+		return "❌ Это последний владелец, сначала назначь другого."
+	case errors.Is(err, model.ErrNotFound):
+		return "❌ У этого пользователя и так нет доступа."
+	default:
+		return "❌ Не получилось сохранить, может, еще разок попробуем?"
+	}
+}
+
+// addUser выдает роль и добавляет пользователя в текущий проект, чтобы он сразу мог работать.
+func addUser(ctx context.Context, c telebot.Context, db Database, userID int64, role model.Role) error {
+	err := db.SetUserRole(ctx, userID, role)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.SetUserRole", "error", err)
+
+		return c.Send(usersErrorMessage(err))
+	}
+
+	project := currentProject(c)
+
+	err = db.AddProjectMember(ctx, project.ID, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database.AddProjectMember", "error", err)
+
+		return c.Send("✅ Роль выдана, но добавить в проект не получилось: /project add " + strconv.FormatInt(userID, 10))
+	}
+
+	return c.Send("✅ " + strconv.FormatInt(userID, 10) + " — " + role.String() + " в проекте «" + project.Name + "».")
+}
+
+// usersHandler управляет доступом: кто может пользоваться ботом и API и с какой ролью.
+func usersHandler(ctx context.Context, db Database) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		args := c.Args()
+
+		if len(args) == 0 {
+			users, err := db.Users(ctx)
+			if err != nil {
+				return c.Send("❌ Не получилось получить пользователей, может, еще разок попробуем?")
+			}
+
+			return c.Send(formatUsersHTML(users), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+		}
+
+		if len(args) < 2 { //nolint:mnd
+			return c.Send(usersUsageMessage)
+		}
+
+		userID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || userID <= 0 {
+			return c.Send(usersUsageMessage)
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "add":
+			role := model.RoleViewer
+
+			if len(args) > 2 { //nolint:mnd
+				var ok bool
+
+				role, ok = model.RoleFromString(strings.ToLower(args[2]))
+				if !ok {
+					return c.Send(usersUsageMessage)
+				}
+			}
+
+			return addUser(ctx, c, db, userID, role)
+		case "remove":
+			err = db.RemoveUser(ctx, userID)
+			if err != nil {
+				return c.Send(usersErrorMessage(err))
+			}
+
+			return c.Send("✅ Доступ забран.")
+		default:
+			return c.Send(usersUsageMessage)
+		}
+	}
+}
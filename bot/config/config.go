@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,6 +16,10 @@ const (
 	defaultHTTPAddr       = ":8080"
 	defaultEnableBot      = true
 	defaultAllowedOrigins = "http://localhost:3000,http://localhost:5173"
+	defaultTimezone       = "Europe/Moscow"
+	defaultUndoWindow     = 10 * time.Minute
+	defaultTrashRetention = 30 * 24 * time.Hour
+	defaultGroupPrefix    = "+"
 )
 
 type Service struct {
@@ -31,6 +36,13 @@ type Config struct {
 	AllowedUsers   []int64
 	EnableBot      bool
 	AllowedOrigins []string
+	Location       *time.Location
+	// UndoWindow — сколько после записи автор может отменить или поправить трату кнопками.
+	UndoWindow time.Duration
+	// TrashRetention — сколько удаленные траты хранятся в корзине до окончательного удаления.
+	TrashRetention time.Duration
+	// GroupPrefix — префикс, с которого в группе начинается сообщение для бота: «+ нал 1500 краска».
+	GroupPrefix string
 }
 
 func envString(key, defaultValue string) string {
@@ -75,6 +87,32 @@ func envStringSlice(key string, defaultValue []string, sep string) []string {
 	return defaultValue
 }
 
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		parsed, err := time.ParseDuration(value)
+		if err == nil && parsed > 0 {
+			return parsed
+		}
+
+		slog.Warn("invalid duration, fallback to default", "key", key, "value", value, "default", defaultValue)
+	}
+
+	return defaultValue
+}
+
+func envLocation(key, defaultValue string) *time.Location {
+	name := envString(key, defaultValue)
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("unknown timezone, fallback to UTC", "timezone", name, "error", err)
+
+		return time.UTC
+	}
+
+	return loc
+}
+
 func parseAllowedUsers(input string) []int64 {
 	if input == "" {
 		return nil
@@ -122,5 +160,9 @@ func New(version string) *Config {
 			"http://localhost:3000",
 			"http://localhost:5173",
 		}, ","),
+		Location:       envLocation(prefix+"TIMEZONE", defaultTimezone),
+		UndoWindow:     envDuration(prefix+"UNDO_WINDOW", defaultUndoWindow),
+		TrashRetention: envDuration(prefix+"TRASH_RETENTION", defaultTrashRetention),
+		GroupPrefix:    envString(prefix+"GROUP_PREFIX", defaultGroupPrefix),
 	}
 }
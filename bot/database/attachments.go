@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kudadeli/model"
+)
+
+// AddAttachment сохраняет файл траты. Трата должна существовать и не быть удаленной.
+func (s *Service) AddAttachment(ctx context.Context, attachment model.Attachment, data []byte) error {
+	_, err := s.Get(ctx, attachment.ExpenseID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, insertAttachment,
+		attachment.ID.String(),
+		attachment.ExpenseID.String(),
+		attachment.CreatedAt.Format(time.RFC3339),
+		attachment.FileName,
+		attachment.MIMEType,
+		int64(len(data)),
+		attachment.TelegramFileID,
+		data,
+	)
+	if err != nil {
+		return fmt.Errorf("insert attachment: %w", err)
+	}
+
+	return nil
+}
+
+func scanAttachment(row scanner) (model.Attachment, error) {
+	var (
+		attachment model.Attachment
+		createdAt  string
+	)
+
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.ExpenseID,
+		&createdAt,
+		&attachment.FileName,
+		&attachment.MIMEType,
+		&attachment.Size,
+		&attachment.TelegramFileID,
+	)
+	if err != nil {
+		return model.Attachment{}, fmt.Errorf("row scan: %w", err)
+	}
+
+	attachment.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return model.Attachment{}, fmt.Errorf("parse created at: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// Attachments возвращает файлы траты без содержимого.
+func (s *Service) Attachments(ctx context.Context, expenseID model.ExpenseID) ([]model.Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, selectAttachments, expenseID.String())
+	if err != nil {
+		return nil, fmt.Errorf("select attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// Attachment возвращает файл траты вместе с содержимым.
+func (s *Service) Attachment(ctx context.Context, expenseID model.ExpenseID,
+	id model.AttachmentID) (model.Attachment, []byte, error) {
+	attachment, err := scanAttachment(s.db.QueryRowContext(ctx, selectAttachment, id.String(), expenseID.String()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Attachment{}, nil, model.ErrNotFound
+		}
+
+		return model.Attachment{}, nil, err
+	}
+
+	var data []byte
+
+	err = s.db.QueryRowContext(ctx, selectAttachmentData, id.String()).Scan(&data)
+	if err != nil {
+		return model.Attachment{}, nil, fmt.Errorf("select attachment data: %w", err)
+	}
+
+	return attachment, data, nil
+}
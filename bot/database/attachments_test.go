@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestAttachments(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_attachments.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	expense := model.Expense{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+		Category:    model.CategoryMaterials,
+		PaymentType: model.PaymentTypeCard,
+		Amount:      decimal.NewFromInt(100),
+	}
+	require.NoError(t, srv.Insert(ctx, expense))
+
+	attachment := model.Attachment{
+		ID:        uuid.New(),
+		ExpenseID: expense.ID,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		FileName:  "receipt.jpg",
+		MIMEType:  "image/jpeg",
+	}
+	data := []byte{0xff, 0xd8, 0xff, 0x00}
+
+	t.Run("Add", func(t *testing.T) {
+		require.NoError(t, srv.AddAttachment(ctx, attachment, data))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		attachments, err := srv.Attachments(ctx, expense.ID)
+		require.NoError(t, err)
+
+		require.Len(t, attachments, 1)
+		assert.Equal(t, attachment.ID, attachments[0].ID)
+		assert.Equal(t, int64(len(data)), attachments[0].Size)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		got, gotData, err := srv.Attachment(ctx, expense.ID, attachment.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, "receipt.jpg", got.FileName)
+		assert.Equal(t, data, gotData)
+
+		_, _, err = srv.Attachment(ctx, uuid.New(), attachment.ID)
+		require.ErrorIs(t, err, model.ErrNotFound)
+	})
+
+	t.Run("Unknown expense", func(t *testing.T) {
+		orphan := attachment
+		orphan.ID = uuid.New()
+		orphan.ExpenseID = uuid.New()
+
+		require.ErrorIs(t, srv.AddAttachment(ctx, orphan, data), model.ErrNotFound)
+	})
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"kudadeli/model"
+)
+
+func centsToDecimal(cents int64) decimal.Decimal {
+	return decimal.New(cents, -2)
+}
+
+// SetBudget задает бюджет категории проекта. Нулевая сумма удаляет бюджет.
+func (s *Service) SetBudget(ctx context.Context, projectID model.ProjectID, category model.Category,
+	amount decimal.Decimal) error {
+	if amount.IsZero() {
+		_, err := s.db.ExecContext(ctx, deleteBudget, projectID, int(category))
+		if err != nil {
+			return fmt.Errorf("delete budget: %w", err)
+		}
+
+		return nil
+	}
+
+	_, err := s.db.ExecContext(ctx, upsertBudget,
+		projectID,
+		int(category),
+		amount.String(),
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("upsert budget: %w", err)
+	}
+
+	return nil
+}
+
+// Budgets возвращает бюджет и фактические траты проекта по всем категориям.
+func (s *Service) Budgets(ctx context.Context, projectID model.ProjectID) (model.Budgets, error) {
+	amounts, err := s.budgetAmounts(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	spent, err := s.spentByCategory(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := s.categories.Active()
+	budgets := make(model.Budgets, 0, len(categories))
+
+	for _, category := range categories {
+		budgets = append(budgets, model.Budget{
+			Category: category,
+			Amount:   amounts[category],
+			Spent:    spent[category],
+		})
+	}
+
+	return budgets, nil
+}
+
+// Budget возвращает бюджет и фактические траты одной категории проекта.
+func (s *Service) Budget(ctx context.Context, projectID model.ProjectID, category model.Category) (model.Budget, error) {
+	budgets, err := s.Budgets(ctx, projectID)
+	if err != nil {
+		return model.Budget{}, err
+	}
+
+	for _, b := range budgets {
+		if b.Category == category {
+			return b, nil
+		}
+	}
+
+	return model.Budget{Category: category}, nil
+}
+
+func (s *Service) budgetAmounts(ctx context.Context,
+	projectID model.ProjectID) (map[model.Category]decimal.Decimal, error) {
+	rows, err := s.db.QueryContext(ctx, selectBudgets, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("select budgets: %w", err)
+	}
+	defer rows.Close()
+
+	amounts := make(map[model.Category]decimal.Decimal)
+
+	for rows.Next() {
+		var (
+			categoryID int
+			amountStr  string
+		)
+
+		err := rows.Scan(&categoryID, &amountStr)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		amount, err := decimal.NewFromString(amountStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse amount: %w", err)
+		}
+
+		amounts[model.Category(categoryID)] = amount
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return amounts, nil
+}
+
+func (s *Service) spentByCategory(ctx context.Context,
+	projectID model.ProjectID) (map[model.Category]decimal.Decimal, error) {
+	query := "SELECT category_id, " + s.sumAmountCents() +
+		" FROM expenses WHERE deleted_at IS NULL AND project_id = ? GROUP BY category_id"
+
+	rows, err := s.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("select spent by category: %w", err)
+	}
+	defer rows.Close()
+
+	spent := make(map[model.Category]decimal.Decimal)
+
+	for rows.Next() {
+		var (
+			categoryID int
+			cents      int64
+		)
+
+		err := rows.Scan(&categoryID, &cents)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		spent[model.Category(categoryID)] = centsToDecimal(cents)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return spent, nil
+}
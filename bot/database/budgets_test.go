@@ -0,0 +1,116 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_budgets.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	for _, amount := range []string{"100.10", "0.20", "50"} {
+		err := srv.Insert(ctx, model.Expense{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now().UTC().Truncate(time.Second),
+			UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+			Category:    model.CategoryMaterials,
+			PaymentType: model.PaymentTypeCash,
+			Amount:      decimal.RequireFromString(amount),
+			UserID:      1,
+		})
+		require.NoError(t, err, "insert failed")
+	}
+
+	t.Run("Without budget", func(t *testing.T) {
+		budget, err := srv.Budget(ctx, model.DefaultProjectID, model.CategoryMaterials)
+		require.NoError(t, err)
+
+		assert.False(t, budget.IsSet())
+		assert.True(t, budget.Spent.Equal(decimal.RequireFromString("150.30")), "spent mismatch: %s", budget.Spent)
+	})
+
+	t.Run("SetBudget", func(t *testing.T) {
+		err := srv.SetBudget(ctx, model.DefaultProjectID, model.CategoryMaterials, decimal.NewFromInt(200))
+		require.NoError(t, err)
+
+		budget, err := srv.Budget(ctx, model.DefaultProjectID, model.CategoryMaterials)
+		require.NoError(t, err)
+
+		assert.True(t, budget.Amount.Equal(decimal.NewFromInt(200)))
+		assert.True(t, budget.Percent().Equal(decimal.RequireFromString("75.15")), "percent mismatch: %s", budget.Percent())
+	})
+
+	t.Run("Budgets lists all categories", func(t *testing.T) {
+		budgets, err := srv.Budgets(ctx, model.DefaultProjectID)
+		require.NoError(t, err)
+
+		assert.Len(t, budgets, len(srv.CategoryRegistry().Active()))
+	})
+
+	t.Run("Remove budget", func(t *testing.T) {
+		err := srv.SetBudget(ctx, model.DefaultProjectID, model.CategoryMaterials, decimal.Zero)
+		require.NoError(t, err)
+
+		budget, err := srv.Budget(ctx, model.DefaultProjectID, model.CategoryMaterials)
+		require.NoError(t, err)
+
+		assert.False(t, budget.IsSet())
+	})
+}
+
+func TestBudgetsPerProject(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_budgets_projects.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	project, err := srv.CreateProject(ctx, "Дача", "", 1)
+	require.NoError(t, err)
+
+	err = srv.Insert(ctx, model.Expense{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+		Category:    model.CategoryMaterials,
+		PaymentType: model.PaymentTypeCash,
+		Amount:      decimal.NewFromInt(100),
+		UserID:      1,
+		ProjectID:   project.ID,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, srv.SetBudget(ctx, project.ID, model.CategoryMaterials, decimal.NewFromInt(500)))
+
+	budget, err := srv.Budget(ctx, project.ID, model.CategoryMaterials)
+	require.NoError(t, err)
+	assert.True(t, budget.Amount.Equal(decimal.NewFromInt(500)))
+	assert.True(t, budget.Spent.Equal(decimal.NewFromInt(100)))
+
+	budget, err = srv.Budget(ctx, model.DefaultProjectID, model.CategoryMaterials)
+	require.NoError(t, err)
+	assert.False(t, budget.IsSet(), "budget leaked into default project")
+	assert.True(t, budget.Spent.IsZero(), "spent leaked into default project")
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"kudadeli/model"
+)
+
+var ErrTooManyCategories = errors.New("too many categories")
+
+// Categories возвращает все категории, включая архивные, с ключевыми словами.
+func (s *Service) Categories(ctx context.Context) ([]model.CategoryInfo, error) {
+	rows, err := s.db.QueryContext(ctx, selectCategories)
+	if err != nil {
+		return nil, fmt.Errorf("select categories: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		categories []model.CategoryInfo
+		index      = make(map[model.Category]int)
+	)
+
+	for rows.Next() {
+		var c model.CategoryInfo
+
+		err := rows.Scan(&c.ID, &c.Name, &c.Emoji, &c.SortOrder, &c.Archived)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		c.Keywords = []string{}
+		index[c.ID] = len(categories)
+		categories = append(categories, c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	keywords, err := s.db.QueryContext(ctx, selectCategoryKeywords)
+	if err != nil {
+		return nil, fmt.Errorf("select category keywords: %w", err)
+	}
+	defer keywords.Close()
+
+	for keywords.Next() {
+		var (
+			keyword  string
+			category model.Category
+		)
+
+		err := keywords.Scan(&keyword, &category)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		if i, ok := index[category]; ok {
+			categories[i].Keywords = append(categories[i].Keywords, keyword)
+		}
+	}
+
+	err = keywords.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return categories, nil
+}
+
+// CategoryRegistry возвращает категории этой базы для парсера, бота и API.
+// Реестр обновляется после каждого изменения категорий.
+func (s *Service) CategoryRegistry() *model.CategoryRegistry {
+	return s.categories
+}
+
+// loadCategories обновляет реестр категорий.
+func (s *Service) loadCategories(ctx context.Context) error {
+	categories, err := s.Categories(ctx)
+	if err != nil {
+		return fmt.Errorf("load categories: %w", err)
+	}
+
+	s.categories.Set(categories)
+
+	return nil
+}
+
+// normalizeCategory чистит название и ключевые слова. Архивная категория ключевых слов
+// не держит: иначе их нельзя было бы отдать новой категории, а парсер их все равно не ищет.
+func normalizeCategory(c model.CategoryInfo) model.CategoryInfo {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Keywords = model.NormalizeKeywords(c.Keywords)
+
+	if c.Archived {
+		c.Keywords = []string{}
+	}
+
+	return c
+}
+
+// AddCategory создает категорию. Без порядка сортировки она встает в конец списка.
+// Категорий может быть не больше 255: ID хранится в байте, дальше — ErrTooManyCategories.
+func (s *Service) AddCategory(ctx context.Context, c model.CategoryInfo) (model.CategoryInfo, error) {
+	c = normalizeCategory(c)
+
+	err := c.Validate()
+	if err != nil {
+		return model.CategoryInfo{}, err
+	}
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		err := checkCategoryName(ctx, tx, 0, c.Name)
+		if err != nil {
+			return err
+		}
+
+		if c.SortOrder == 0 {
+			err = tx.QueryRowContext(ctx, selectMaxCategorySortOrder).Scan(&c.SortOrder)
+			if err != nil {
+				return fmt.Errorf("select max sort order: %w", err)
+			}
+
+			c.SortOrder++
+		}
+
+		res, err := tx.ExecContext(ctx, insertCategory, c.Name, c.Emoji, c.SortOrder, c.Archived)
+		if err != nil {
+			return fmt.Errorf("insert category: %w", err)
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("last insert id: %w", err)
+		}
+
+		// ID категории хранится в байте, как и в старых записях.
+		if id > math.MaxUint8 {
+			return ErrTooManyCategories
+		}
+
+		c.ID = model.Category(id)
+
+		err = replaceCategoryKeywords(ctx, tx, c.ID, c.Keywords)
+		if err != nil {
+			return err
+		}
+
+		return touchAllProjects(ctx, tx)
+	})
+	if err != nil {
+		return model.CategoryInfo{}, err
+	}
+
+	return c, s.loadCategories(ctx)
+}
+
+// EditCategory сохраняет название, эмодзи, порядок, архивность и ключевые слова категории.
+// При архивации ключевые слова освобождаются, после возврата из архива их задают заново.
+func (s *Service) EditCategory(ctx context.Context, c model.CategoryInfo) error {
+	c = normalizeCategory(c)
+
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		err := checkCategoryName(ctx, tx, c.ID, c.Name)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, updateCategory, c.Name, c.Emoji, c.SortOrder, c.Archived, c.ID)
+		if err != nil {
+			return fmt.Errorf("update category: %w", err)
+		}
+
+		err = checkAffected(res)
+		if err != nil {
+			return err
+		}
+
+		err = replaceCategoryKeywords(ctx, tx, c.ID, c.Keywords)
+		if err != nil {
+			return err
+		}
+
+		return touchAllProjects(ctx, tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.loadCategories(ctx)
+}
+
+func (s *Service) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// checkCategoryName проверяет, что названия нет у другой категории. Сравнение без учета
+// регистра делается в Go: NOCASE в SQLite не работает для кириллицы.
+func checkCategoryName(ctx context.Context, tx *sql.Tx, id model.Category, name string) error {
+	rows, err := tx.QueryContext(ctx, selectCategoryNames)
+	if err != nil {
+		return fmt.Errorf("select category names: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			otherID   model.Category
+			otherName string
+		)
+
+		err := rows.Scan(&otherID, &otherName)
+		if err != nil {
+			return fmt.Errorf("row scan: %w", err)
+		}
+
+		if otherID != id && strings.EqualFold(otherName, name) {
+			return model.ErrCategoryExists
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	return nil
+}
+
+func replaceCategoryKeywords(ctx context.Context, tx *sql.Tx, id model.Category, keywords []string) error {
+	_, err := tx.ExecContext(ctx, deleteCategoryKeywords, id)
+	if err != nil {
+		return fmt.Errorf("delete category keywords: %w", err)
+	}
+
+	for _, keyword := range keywords {
+		var other model.Category
+
+		err := tx.QueryRowContext(ctx, selectKeywordCategory, keyword).Scan(&other)
+		if err == nil {
+			return fmt.Errorf("%w: %s", model.ErrKeywordTaken, keyword)
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("select keyword category: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, insertCategoryKeyword, keyword, id)
+		if err != nil {
+			return fmt.Errorf("insert category keyword: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// touchAllProjects сдвигает время изменения всех проектов, см. ProjectChangedAt.
+func touchAllProjects(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, touchProjects)
+	if err != nil {
+		return fmt.Errorf("touch projects: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package database_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_categories.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	categories := srv.CategoryRegistry()
+
+	t.Run("Built-in categories keep their IDs", func(t *testing.T) {
+		list, err := srv.Categories(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, model.DefaultCategories(), list)
+		assert.Equal(t, "прочее/непредвиденное", categories.Name(model.CategoryUnexpected))
+	})
+
+	var plumbing model.CategoryInfo
+
+	t.Run("AddCategory", func(t *testing.T) {
+		plumbing, err = srv.AddCategory(ctx, model.CategoryInfo{
+			Name:     " Сантехника ",
+			Emoji:    "🚿",
+			Keywords: []string{"Сантехника", "трубы", "трубы"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, model.Category(6), plumbing.ID)
+		assert.Equal(t, 6, plumbing.SortOrder)
+		assert.Equal(t, []string{"сантехника", "трубы"}, plumbing.Keywords)
+
+		expense, err := parser.Message(categories, "карта 1200 трубы ванная")
+		require.NoError(t, err)
+		assert.Equal(t, plumbing.ID, expense.Category)
+		assert.Equal(t, "ванная", expense.Description)
+	})
+
+	t.Run("Duplicates are rejected", func(t *testing.T) {
+		_, err := srv.AddCategory(ctx, model.CategoryInfo{Name: "САНТЕХНИКА"})
+		require.ErrorIs(t, err, model.ErrCategoryExists)
+
+		_, err = srv.AddCategory(ctx, model.CategoryInfo{Name: "Краска", Keywords: []string{"мебель"}})
+		require.ErrorIs(t, err, model.ErrKeywordTaken)
+
+		_, err = srv.AddCategory(ctx, model.CategoryInfo{Name: "Краска", Keywords: []string{"1500"}})
+		require.ErrorIs(t, err, model.ErrInvalidCategory)
+	})
+
+	t.Run("Rename and archive", func(t *testing.T) {
+		plumbing.Name = "Сантехника и трубы"
+		plumbing.Archived = true
+		require.NoError(t, srv.EditCategory(ctx, plumbing))
+
+		assert.Equal(t, "Сантехника и трубы", categories.Name(plumbing.ID))
+		assert.True(t, categories.IsValid(plumbing.ID), "archived category must stay valid for stored rows")
+		assert.NotContains(t, categories.Active(), plumbing.ID)
+
+		_, ok := categories.ByKeyword("трубы")
+		assert.False(t, ok, "archived category keyword must not match")
+	})
+
+	t.Run("Archive releases keywords", func(t *testing.T) {
+		info, ok := categories.Info(plumbing.ID)
+		require.True(t, ok)
+		assert.Empty(t, info.Keywords)
+
+		pipes, err := srv.AddCategory(ctx, model.CategoryInfo{Name: "Трубы", Keywords: []string{"трубы"}})
+		require.NoError(t, err, "keyword of archived category must be free")
+
+		category, ok := categories.ByKeyword("трубы")
+		require.True(t, ok)
+		assert.Equal(t, pipes.ID, category)
+
+		plumbing.Archived = false
+		plumbing.Keywords = []string{"трубы"}
+		require.ErrorIs(t, srv.EditCategory(ctx, plumbing), model.ErrKeywordTaken)
+	})
+
+	t.Run("Edit unknown category", func(t *testing.T) {
+		err := srv.EditCategory(ctx, model.CategoryInfo{ID: 200, Name: "нет"})
+		require.ErrorIs(t, err, model.ErrNotFound)
+	})
+}
+
+func TestCategoriesLimit(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_categories_limit.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	last := len(model.DefaultCategories())
+
+	for last < math.MaxUint8 {
+		info, err := srv.AddCategory(ctx, model.CategoryInfo{Name: "категория " + strconv.Itoa(last+1)})
+		require.NoError(t, err)
+
+		last = int(info.ID)
+	}
+
+	_, err = srv.AddCategory(ctx, model.CategoryInfo{Name: "лишняя"})
+	require.ErrorIs(t, err, database.ErrTooManyCategories)
+
+	list, err := srv.Categories(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, math.MaxUint8, "rejected category must not be stored")
+}
+
+func TestCategoryRegistryPerDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	first, second := "test_categories_first.db", "test_categories_second.db"
+	defer os.Remove(first)
+	defer os.Remove(second)
+
+	a, err := database.New(ctx, first, time.UTC)
+	require.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := database.New(ctx, second, time.UTC)
+	require.NoError(t, err)
+
+	defer b.Close()
+
+	info, err := a.AddCategory(ctx, model.CategoryInfo{Name: "Окна", Keywords: []string{"окна"}})
+	require.NoError(t, err)
+
+	assert.True(t, a.CategoryRegistry().IsValid(info.ID))
+	assert.False(t, b.CategoryRegistry().IsValid(info.ID), "categories of one database must not leak into another")
+
+	_, ok := b.CategoryRegistry().ByKeyword("окна")
+	assert.False(t, ok)
+}
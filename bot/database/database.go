@@ -3,54 +3,124 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"log/slog"
-	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/shopspring/decimal"
-	// Import SQLite driver anonymously for side-effects (registration with database/sql).
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 
 	"kudadeli/model"
 )
 
+type scanner interface {
+	Scan(dest ...any) error
+}
+
 type Service struct {
-	db *sql.DB
+	db         *sql.DB
+	categories *model.CategoryRegistry
+	// loc — часовой пояс, в котором трата относится к календарному дню.
+	loc *time.Location
 }
 
-func fileExists(filename string) bool {
-	_, err := os.Stat(filename)
-	if err == nil {
-		return true
+// registerFunctions регистрирует в драйвере функции, которых нет в SQLite. Регистрация
+// общая для процесса, поэтому выполняется один раз.
+var registerFunctions = sync.OnceValue(func() error { //nolint:gochecknoglobals
+	err := sqlite.RegisterDeterministicScalarFunction("unicode_lower", 1, unicodeLower)
+	if err != nil {
+		return err
+	}
+
+	var locations sync.Map
+
+	return sqlite.RegisterDeterministicScalarFunction("local_date", 2, //nolint:mnd
+		func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return localDate(&locations, args)
+		})
+})
+
+// unicodeLower — lower() для любых букв: встроенная в SQLite меняет регистр только у латиницы,
+// и поиск «Краска» по «краска» не находил.
+func unicodeLower(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	switch v := args[0].(type) {
+	case string:
+		return strings.ToLower(v), nil
+	case []byte:
+		return strings.ToLower(string(v)), nil
+	default:
+		return v, nil
+	}
+}
+
+// localDate — date() в часовом поясе: local_date(время в RFC 3339, имя пояса). Пояса
+// кешируются в locations, чтобы не читать базу поясов на каждую строку.
+func localDate(locations *sync.Map, args []driver.Value) (driver.Value, error) {
+	value, ok := args[0].(string)
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	name, _ := args[1].(string)
+
+	cached, ok := locations.Load(name)
+	if !ok {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("local_date: %w", err)
+		}
+
+		cached, _ = locations.LoadOrStore(name, loc)
 	}
 
-	if os.IsNotExist(err) {
-		return false
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, nil //nolint:nilnil
 	}
 
-	return false
+	loc, _ := cached.(*time.Location)
+
+	return t.In(loc).Format(time.DateOnly), nil
 }
 
-func New(ctx context.Context, database string) (*Service, error) {
+// New открывает базу. Календарные дни трат (для курсов валют) считаются в поясе loc.
+// Пояс передается в SQL по имени, поэтому он должен загружаться через time.LoadLocation.
+func New(ctx context.Context, database string, loc *time.Location) (*Service, error) {
 	slog.InfoContext(ctx, "open database", "path", database)
 
-	isExist := fileExists(database)
-	slog.Debug("fileExists", "isExist", isExist)
+	_, err := time.LoadLocation(loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("database timezone: %w", err)
+	}
+
+	err = registerFunctions()
+	if err != nil {
+		return nil, fmt.Errorf("register sql functions: %w", err)
+	}
 
 	db, err := sql.Open("sqlite", database+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout=5000")
 	if err != nil {
 		return nil, fmt.Errorf("sql open error: %w", err)
 	}
 
-	srv := &Service{db: db}
+	srv := &Service{db: db, categories: model.NewCategoryRegistry(model.DefaultCategories()), loc: loc}
 
-	if !isExist {
-		err := srv.create(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("create database: %w", err)
-		}
+	err = srv.migrate(ctx)
+	if err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+
+	err = srv.loadCategories(ctx)
+	if err != nil {
+		_ = db.Close()
+
+		return nil, err
 	}
 
 	return srv, nil
@@ -65,159 +135,339 @@ func (s *Service) Close() error {
 	return nil
 }
 
-func (s *Service) LatestUpdatedAt(ctx context.Context) (time.Time, error) {
-	var updatedAt string
+// ProjectChangedAt возвращает время последнего изменения трат проекта: записи, правки,
+// удаления в корзину и окончательного удаления, а также правки категорий, чьи названия
+// есть в тратах. Время трат ставят триггеры на expenses, поэтому учитываются и траты,
+// которых больше нет.
+func (s *Service) ProjectChangedAt(ctx context.Context, projectID model.ProjectID) (time.Time, error) {
+	var changedAt string
 
-	err := s.db.QueryRowContext(ctx, latestUpdatedAt).Scan(&updatedAt)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return time.Time{}, nil // Нет записей
-		}
+	err := s.db.QueryRowContext(ctx, selectProjectChangedAt, projectID).Scan(&changedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, model.ErrNotFound
+	}
 
-		return time.Time{}, fmt.Errorf("failed to get latest updated_at: %w", err)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("select project changed_at: %w", err)
 	}
 
-	t, err := time.Parse(time.RFC3339, updatedAt)
+	t, err := time.Parse(time.RFC3339, changedAt)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to parse updated_at: %w", err)
+		return time.Time{}, fmt.Errorf("parse changed_at: %w", err)
 	}
 
 	return t, nil
 }
 
 func (s *Service) Insert(ctx context.Context, expense model.Expense) error {
-	_, err := s.db.ExecContext(ctx, insertExpense,
-		expense.ID.String(),
-		expense.CreatedAt.Format(time.RFC3339),
-		expense.UpdatedAt.Format(time.RFC3339),
-		int(expense.Category),
-		expense.Description,
-		expense.Amount.String(),
-		int(expense.PaymentType),
-		expense.UserID,
-	)
-	if err != nil {
-		return fmt.Errorf("insert expense: %w", err)
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.insertTx(ctx, tx, expense)
+	})
+}
+
+// expenseCurrency проверяет валюту траты. Пустая валюта — валюта ее проекта.
+func expenseCurrency(ctx context.Context, tx *sql.Tx, expense model.Expense) (string, error) {
+	if expense.Currency != "" {
+		currency, ok := model.NormalizeCurrency(expense.Currency)
+		if !ok {
+			return "", model.ErrInvalidCurrency
+		}
+
+		return currency, nil
 	}
 
-	return nil
-}
+	var currency string
 
-func (s *Service) Update(ctx context.Context, expense model.Expense) error {
-	_, err := s.db.ExecContext(ctx, updateExpense,
-		expense.UpdatedAt.Format(time.RFC3339),
-		int(expense.Category),
-		expense.Description,
-		expense.Amount.String(),
-		int(expense.PaymentType),
-		expense.ID.String(),
-	)
+	err := tx.QueryRowContext(ctx, selectProjectCurrency, projectOrDefault(expense.ProjectID)).Scan(&currency)
 	if err != nil {
-		return fmt.Errorf("update expense: %w", err)
+		return "", fmt.Errorf("select project currency: %w", err)
 	}
 
-	return nil
+	return currency, nil
 }
 
-func (s *Service) UpdateCategory(ctx context.Context, expenseID model.ExpenseID, category model.Category) error {
-	_, err := s.db.ExecContext(ctx, updateExpenseCategory,
-		time.Now().Format(time.RFC3339),
-		int(category),
-		expenseID.String(),
-	)
+// insertTx вставляет трату и записывает ее создание в журнал.
+func (s *Service) insertTx(ctx context.Context, tx *sql.Tx, expense model.Expense) error {
+	var err error
+
+	expense.Currency, err = expenseCurrency(ctx, tx, expense)
 	if err != nil {
-		return fmt.Errorf("update expense category: %w", err)
+		return err
 	}
 
-	return nil
-}
-
-func (s *Service) Delete(ctx context.Context, id model.ExpenseID) error {
-	_, err := s.db.ExecContext(ctx, deleteExpense, id.String())
+	_, err = tx.ExecContext(ctx, insertExpense, insertArgs(expense)...)
 	if err != nil {
-		return fmt.Errorf("delete expense: %w", err)
+		return fmt.Errorf("insert expense: %w", err)
 	}
 
-	return nil
+	return recordHistory(ctx, tx, expense.ID, model.ActionCreate, model.ExpenseChanges(s.categories, nil, expense))
 }
 
-func (s *Service) List(ctx context.Context, limit int) (model.Expenses, error) {
-	query := selectExpenses
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", limit)
-	}
+// InsertMany вставляет траты одной транзакцией: либо все, либо ни одной.
+func (s *Service) InsertMany(ctx context.Context, expenses model.Expenses) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for i := range expenses {
+			err := s.insertTx(ctx, tx, expenses[i])
+			if err != nil {
+				return fmt.Errorf("expense %d: %w", i, err)
+			}
+		}
 
-	slog.DebugContext(ctx, "list expenses", "limit", limit)
+		return nil
+	})
+}
 
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("select expenses: %w", err)
-	}
-	defer rows.Close()
+func (s *Service) Update(ctx context.Context, expense model.Expense) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := txExpense(ctx, tx, expense.ID)
+		if err != nil {
+			return err
+		}
 
-	var expenses model.Expenses
+		// Без валюты в запросе трата остается в прежней валюте.
+		if expense.Currency == "" {
+			expense.Currency = before.Currency
+		}
 
-	for rows.Next() {
-		var (
-			expense                   model.Expense
-			createdAt, updatedAt      string
-			amountStr                 string
-			categoryID, paymentTypeID int
-			userID                    int64
-		)
+		expense.Currency, err = expenseCurrency(ctx, tx, expense)
+		if err != nil {
+			return err
+		}
 
-		err := rows.Scan(
-			&expense.ID,
-			&createdAt,
-			&updatedAt,
-			&categoryID,
-			&expense.Description,
-			&amountStr,
-			&paymentTypeID,
-			&userID,
+		res, err := tx.ExecContext(ctx, updateExpense,
+			expense.CreatedAt.Format(time.RFC3339),
+			expense.UpdatedAt.Format(time.RFC3339),
+			int(expense.Category),
+			expense.Description,
+			expense.Amount.String(),
+			expense.Currency,
+			int(expense.PaymentType),
+			expense.ID.String(),
 		)
 		if err != nil {
-			return nil, fmt.Errorf("row scan: %w", err)
+			return fmt.Errorf("update expense: %w", err)
+		}
+
+		err = checkAffected(res)
+		if err != nil {
+			return err
 		}
 
-		expense.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+		changes := model.ExpenseChanges(s.categories, &before, expense)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		return recordHistory(ctx, tx, expense.ID, model.ActionUpdate, changes)
+	})
+}
+
+func (s *Service) UpdateCategory(ctx context.Context, expenseID model.ExpenseID, category model.Category) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := txExpense(ctx, tx, expenseID)
 		if err != nil {
-			return nil, fmt.Errorf("parse created at: %w", err)
+			return err
 		}
 
-		expense.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt)
+		res, err := tx.ExecContext(ctx, updateExpenseCategory,
+			time.Now().Format(time.RFC3339),
+			int(category),
+			expenseID.String(),
+		)
 		if err != nil {
-			return nil, fmt.Errorf("parse updated at: %w", err)
+			return fmt.Errorf("update expense category: %w", err)
+		}
+
+		err = checkAffected(res)
+		if err != nil {
+			return err
+		}
+
+		after := before
+		after.Category = category
+
+		changes := model.ExpenseChanges(s.categories, &before, after)
+		if len(changes) == 0 {
+			return nil
 		}
 
-		expense.Category = model.Category(categoryID)
-		expense.PaymentType = model.PaymentType(paymentTypeID)
+		return recordHistory(ctx, tx, expenseID, model.ActionCategory, changes)
+	})
+}
+
+// Delete переносит трату в корзину, откуда ее можно восстановить до очистки.
+func (s *Service) Delete(ctx context.Context, id model.ExpenseID) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now().UTC().Format(time.RFC3339)
 
-		expense.Amount, err = decimal.NewFromString(amountStr)
+		res, err := tx.ExecContext(ctx, deleteExpense, now, now, id.String())
 		if err != nil {
-			return nil, fmt.Errorf("parse amount: %w", err)
+			return fmt.Errorf("delete expense: %w", err)
 		}
 
-		expense.UserID = userID
+		err = checkAffected(res)
+		if err != nil {
+			return err
+		}
+
+		return recordHistory(ctx, tx, id, model.ActionDelete, []model.FieldChange{
+			{Field: "deletedAt", Old: "", New: now},
+		})
+	})
+}
+
+func (s *Service) List(ctx context.Context, limit int) (model.Expenses, error) {
+	expenses, _, err := s.Find(ctx, ExpenseFilter{Limit: limit})
+
+	return expenses, err
+}
+
+// Find возвращает страницу трат по фильтру и курсор следующей страницы
+// (пустой, если страница последняя).
+func (s *Service) Find(ctx context.Context, filter ExpenseFilter) (model.Expenses, string, error) {
+	query, args, err := filter.query(s.projectAmountExpr())
+	if err != nil {
+		return nil, "", err
+	}
+
+	slog.DebugContext(ctx, "find expenses", "limit", filter.Limit, "query", query)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("select expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var expenses model.Expenses
+
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, "", err
+		}
 
 		expenses = append(expenses, expense)
 	}
 
 	err = rows.Err()
 	if err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
+		return nil, "", fmt.Errorf("rows error: %w", err)
+	}
+
+	var next string
+
+	if filter.Limit > 0 && len(expenses) > filter.Limit {
+		expenses = expenses[:filter.Limit]
+		next = EncodeCursor(expenses[len(expenses)-1].ID)
+	}
+
+	return expenses, next, nil
+}
+
+func (s *Service) Get(ctx context.Context, id model.ExpenseID) (model.Expense, error) {
+	expense, err := scanExpense(s.db.QueryRowContext(ctx, selectExpense, id.String()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Expense{}, model.ErrNotFound
+		}
+
+		return model.Expense{}, err
 	}
 
-	return expenses, nil
+	return expense, nil
+}
+
+func insertArgs(expense model.Expense) []any {
+	return []any{
+		expense.ID.String(),
+		expense.CreatedAt.Format(time.RFC3339),
+		expense.UpdatedAt.Format(time.RFC3339),
+		int(expense.Category),
+		expense.Description,
+		expense.Amount.String(),
+		expense.Currency,
+		int(expense.PaymentType),
+		expense.UserID,
+		projectOrDefault(expense.ProjectID),
+	}
 }
 
-func (s *Service) create(ctx context.Context) error {
-	slog.InfoContext(ctx, "create database")
+func projectOrDefault(id model.ProjectID) model.ProjectID {
+	if id == 0 {
+		return model.DefaultProjectID
+	}
 
-	_, err := s.db.ExecContext(ctx, createExpenses)
+	return id
+}
+
+func checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("createExpenses: %w", err)
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if affected == 0 {
+		return model.ErrNotFound
 	}
 
 	return nil
 }
+
+func scanExpense(row scanner) (model.Expense, error) {
+	var (
+		expense                   model.Expense
+		createdAt, updatedAt      string
+		deletedAt                 sql.NullString
+		amountStr                 string
+		categoryID, paymentTypeID int
+		userID                    int64
+	)
+
+	err := row.Scan(
+		&expense.ID,
+		&createdAt,
+		&updatedAt,
+		&categoryID,
+		&expense.Description,
+		&amountStr,
+		&expense.Currency,
+		&paymentTypeID,
+		&userID,
+		&expense.ProjectID,
+		&deletedAt,
+	)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("row scan: %w", err)
+	}
+
+	if deletedAt.Valid {
+		t, err := parseDeletedAt(deletedAt.String)
+		if err != nil {
+			return model.Expense{}, err
+		}
+
+		expense.DeletedAt = &t
+	}
+
+	expense.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("parse created at: %w", err)
+	}
+
+	expense.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("parse updated at: %w", err)
+	}
+
+	expense.Category = model.Category(categoryID)
+	expense.PaymentType = model.PaymentType(paymentTypeID)
+
+	expense.Amount, err = decimal.NewFromString(amountStr)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("parse amount: %w", err)
+	}
+
+	expense.UserID = userID
+
+	return expense, nil
+}
@@ -20,7 +20,7 @@ func TestExpenseCRUD(t *testing.T) {
 	tmpFile := "test_expenses.db"
 	defer os.Remove(tmpFile)
 
-	srv, err := database.New(ctx, tmpFile)
+	srv, err := database.New(ctx, tmpFile, time.UTC)
 	require.NoError(t, err, "failed to create database")
 
 	defer srv.Close()
@@ -52,13 +52,27 @@ func TestExpenseCRUD(t *testing.T) {
 		assert.True(t, got.Amount.Equal(expense.Amount), "amount mismatch")
 	})
 
+	t.Run("Get", func(t *testing.T) {
+		got, err := srv.Get(ctx, expense.ID)
+		require.NoError(t, err, "get failed")
+
+		assert.Equal(t, expense.ID, got.ID, "ID mismatch")
+		assert.Equal(t, expense.UserID, got.UserID, "user ID mismatch")
+		assert.True(t, got.CreatedAt.Equal(expense.CreatedAt), "created at mismatch")
+	})
+
 	t.Run("Update", func(t *testing.T) {
 		expense.Description = "Updated description"
 		expense.Amount = decimal.NewFromFloat(222.22)
 		expense.UpdatedAt = expense.UpdatedAt.Add(1 * time.Hour)
+		expense.CreatedAt = expense.CreatedAt.Add(-24 * time.Hour)
 
 		err := srv.Update(ctx, expense)
 		require.NoError(t, err, "update failed")
+
+		got, err := srv.Get(ctx, expense.ID)
+		require.NoError(t, err, "get after update failed")
+		assert.True(t, got.CreatedAt.Equal(expense.CreatedAt), "created at not updated")
 	})
 
 	t.Run("List and Check Update", func(t *testing.T) {
@@ -77,6 +91,12 @@ func TestExpenseCRUD(t *testing.T) {
 		require.NoError(t, err, "list after delete failed")
 
 		assert.Empty(t, items, "expected 0 items after delete")
+
+		_, err = srv.Get(ctx, expense.ID)
+		require.ErrorIs(t, err, model.ErrNotFound, "deleted expense must not be found")
+
+		err = srv.Update(ctx, expense)
+		require.ErrorIs(t, err, model.ErrNotFound, "deleted expense must not be updated")
 	})
 
 	t.Run("UpdateCategory", func(t *testing.T) {
@@ -110,5 +130,34 @@ func TestExpenseCRUD(t *testing.T) {
 		require.NoError(t, err, "delete after UpdateCategory failed")
 	})
 
+	t.Run("InsertMany is atomic", func(t *testing.T) {
+		first := model.Expense{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now().UTC().Truncate(time.Second),
+			UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+			Category:    model.CategoryTools,
+			PaymentType: model.PaymentTypeCash,
+			Description: "batch",
+			Amount:      decimal.NewFromInt(10),
+			UserID:      3,
+		}
+		second := first
+		second.ID = uuid.New()
+
+		// Повтор ID во второй трате откатывает и первую.
+		err := srv.InsertMany(ctx, model.Expenses{first, first})
+		require.Error(t, err, "duplicate ID must fail")
+
+		_, err = srv.Get(ctx, first.ID)
+		require.ErrorIs(t, err, model.ErrNotFound, "failed batch must be rolled back")
+
+		err = srv.InsertMany(ctx, model.Expenses{first, second})
+		require.NoError(t, err, "insert many failed")
+
+		items, _, err := srv.Find(ctx, database.ExpenseFilter{UserIDs: []int64{3}})
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+	})
+
 	t.Log("TODO: add LatestUpdatedAt")
 }
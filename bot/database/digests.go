@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kudadeli/model"
+)
+
+func scanDigest(row scanner) (model.Digest, error) {
+	var (
+		d               model.Digest
+		period, nextRun string
+	)
+
+	err := row.Scan(&d.UserID, &d.ChatID, &d.Hour, &d.Minute, &d.Timezone, &period, &nextRun)
+	if err != nil {
+		return model.Digest{}, fmt.Errorf("row scan: %w", err)
+	}
+
+	d.Period = model.DigestPeriod(period)
+
+	d.NextRun, err = time.Parse(time.RFC3339, nextRun)
+	if err != nil {
+		return model.Digest{}, fmt.Errorf("parse next run: %w", err)
+	}
+
+	return d, nil
+}
+
+// SetDigest сохраняет настройки сводки пользователя. Следующую отправку NextRun
+// считает вызывающий.
+func (s *Service) SetDigest(ctx context.Context, d model.Digest) error {
+	err := d.Validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, upsertDigest, d.UserID, d.ChatID, d.Hour, d.Minute, d.Timezone,
+		string(d.Period), d.NextRun.UTC().Format(time.RFC3339), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("upsert digest: %w", err)
+	}
+
+	return nil
+}
+
+// Digest возвращает настройки сводки пользователя или model.ErrNotFound, если он ее не включал.
+func (s *Service) Digest(ctx context.Context, userID int64) (model.Digest, error) {
+	d, err := scanDigest(s.db.QueryRowContext(ctx, selectDigest, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Digest{}, model.ErrNotFound
+	}
+
+	return d, err
+}
+
+// DueDigests возвращает сводки пользователей с доступом, время которых наступило к now.
+func (s *Service) DueDigests(ctx context.Context, now time.Time) ([]model.Digest, error) {
+	rows, err := s.db.QueryContext(ctx, selectDueDigests, now.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("select due digests: %w", err)
+	}
+	defer rows.Close()
+
+	var digests []model.Digest
+
+	for rows.Next() {
+		d, err := scanDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		digests = append(digests, d)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return digests, nil
+}
+
+// SetDigestNextRun переносит следующую отправку сводки.
+func (s *Service) SetDigestNextRun(ctx context.Context, userID int64, next time.Time) error {
+	res, err := s.db.ExecContext(ctx, updateDigestNextRun, next.UTC().Format(time.RFC3339), userID)
+	if err != nil {
+		return fmt.Errorf("update digest next run: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// DeleteDigest выключает сводку пользователя.
+func (s *Service) DeleteDigest(ctx context.Context, userID int64) error {
+	res, err := s.db.ExecContext(ctx, deleteDigest, userID)
+	if err != nil {
+		return fmt.Errorf("delete digest: %w", err)
+	}
+
+	return checkAffected(res)
+}
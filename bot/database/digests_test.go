@@ -0,0 +1,80 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestDigests(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_digests.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	require.NoError(t, srv.EnsureOwners(ctx, []int64{1}))
+
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	_, err = srv.Digest(ctx, 1)
+	require.ErrorIs(t, err, model.ErrNotFound)
+
+	err = srv.SetDigest(ctx, model.Digest{UserID: 1, ChatID: 1, Hour: 25, Timezone: "UTC", Period: model.DigestDaily})
+	require.ErrorIs(t, err, model.ErrInvalidDigest)
+
+	err = srv.SetDigest(ctx, model.Digest{UserID: 1, ChatID: 1, Hour: 9, Timezone: "Mars/Olympus", Period: model.DigestDaily})
+	require.ErrorIs(t, err, model.ErrInvalidDigest)
+
+	digest := model.Digest{
+		UserID:   1,
+		ChatID:   1,
+		Hour:     9,
+		Minute:   30,
+		Timezone: "Europe/Moscow",
+		Period:   model.DigestDaily,
+		NextRun:  now.Add(-time.Hour),
+	}
+	require.NoError(t, srv.SetDigest(ctx, digest))
+
+	// Пользователь без доступа сводку не получает.
+	require.NoError(t, srv.SetDigest(ctx, model.Digest{
+		UserID: 2, ChatID: -100, Timezone: "UTC", Period: model.DigestWeekly, NextRun: now.Add(-time.Hour),
+	}))
+
+	t.Run("get", func(t *testing.T) {
+		got, err := srv.Digest(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "30 9 * * *", got.Schedule())
+		assert.True(t, digest.NextRun.Equal(got.NextRun))
+	})
+
+	t.Run("due", func(t *testing.T) {
+		due, err := srv.DueDigests(ctx, now)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, int64(1), due[0].UserID)
+
+		require.NoError(t, srv.SetDigestNextRun(ctx, 1, now.Add(time.Hour)))
+
+		due, err = srv.DueDigests(ctx, now)
+		require.NoError(t, err)
+		assert.Empty(t, due)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, srv.DeleteDigest(ctx, 1))
+		require.ErrorIs(t, srv.DeleteDigest(ctx, 1), model.ErrNotFound)
+	})
+}
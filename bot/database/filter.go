@@ -0,0 +1,237 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"kudadeli/model"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type SortField byte
+
+const (
+	SortByCreatedAt SortField = iota
+	SortByUpdatedAt
+	SortByAmount
+	SortByDeletedAt
+	// SortByProjectAmount сортирует по сумме в валюте проекта, траты без курса — как нулевые.
+	SortByProjectAmount
+)
+
+// ParseSortField принимает имена полей в том виде, в каком они отдаются в JSON.
+func ParseSortField(input string) (SortField, bool) {
+	switch input {
+	case "", "createdAt":
+		return SortByCreatedAt, true
+	case "updatedAt":
+		return SortByUpdatedAt, true
+	case "amount":
+		return SortByAmount, true
+	case "deletedAt":
+		return SortByDeletedAt, true
+	default:
+		return 0, false
+	}
+}
+
+// column возвращает выражение сортировки. projectAmount — сумма траты в валюте проекта,
+// ее строит Service: курс зависит от часового пояса базы.
+func (f SortField) column(projectAmount string) string {
+	switch f {
+	case SortByProjectAmount:
+		return "COALESCE(" + projectAmount + ", 0)"
+	case SortByUpdatedAt:
+		return "datetime(updated_at)"
+	case SortByAmount:
+		return "CAST(amount AS REAL)"
+	case SortByDeletedAt:
+		return "datetime(deleted_at)"
+	case SortByCreatedAt:
+		fallthrough
+	default:
+		return "datetime(created_at)"
+	}
+}
+
+// ExpenseFilter описывает выборку трат. Нулевые значения полей означают «без ограничения».
+type ExpenseFilter struct {
+	// From включительно, To не включительно.
+	From time.Time
+	To   time.Time
+
+	Categories   []model.Category
+	PaymentTypes []model.PaymentType
+	UserIDs      []int64
+	ProjectIDs   []model.ProjectID
+
+	MinAmount decimal.NullDecimal
+	MaxAmount decimal.NullDecimal
+
+	// Description — подстрока описания без учета регистра.
+	Description string
+
+	// Deleted выбирает траты из корзины вместо действующих.
+	Deleted bool
+
+	Sort SortField
+	Asc  bool
+
+	// Limit <= 0 означает «без лимита».
+	Limit int
+	// Cursor — непрозрачный курсор из предыдущей страницы.
+	Cursor string
+}
+
+// EncodeCursor возвращает курсор, указывающий на трату id.
+func EncodeCursor(id model.ExpenseID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func decodeCursor(cursor string) (model.ExpenseID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	id, err := uuid.FromBytes(raw)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return id, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func anySlice[T any](values []T) []any {
+	result := make([]any, len(values))
+	for i := range values {
+		result[i] = values[i]
+	}
+
+	return result
+}
+
+type whereBuilder struct {
+	conditions []string
+	args       []any
+}
+
+func (b *whereBuilder) add(condition string, args ...any) {
+	b.conditions = append(b.conditions, condition)
+	b.args = append(b.args, args...)
+}
+
+func (b *whereBuilder) in(column string, values []any) {
+	b.add(column+" IN ("+placeholders(len(values))+")", values...)
+}
+
+func (b *whereBuilder) String() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+func escapeLike(input string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(input)
+}
+
+// where собирает условия фильтра (без курсора) в параметризованный WHERE.
+func (f ExpenseFilter) where() *whereBuilder {
+	b := f.whereWithDeleted()
+
+	if f.Deleted {
+		b.add("deleted_at IS NOT NULL")
+	} else {
+		b.add("deleted_at IS NULL")
+	}
+
+	return b
+}
+
+// whereWithDeleted — условия фильтра без отбора по корзине: и действующие, и удаленные траты.
+func (f ExpenseFilter) whereWithDeleted() *whereBuilder {
+	b := &whereBuilder{}
+
+	if !f.From.IsZero() {
+		b.add("datetime(created_at) >= datetime(?)", f.From.UTC().Format(time.RFC3339))
+	}
+
+	if !f.To.IsZero() {
+		b.add("datetime(created_at) < datetime(?)", f.To.UTC().Format(time.RFC3339))
+	}
+
+	if len(f.Categories) > 0 {
+		b.in("category_id", anySlice(f.Categories))
+	}
+
+	if len(f.PaymentTypes) > 0 {
+		b.in("payment_type_id", anySlice(f.PaymentTypes))
+	}
+
+	if len(f.UserIDs) > 0 {
+		b.in("user_id", anySlice(f.UserIDs))
+	}
+
+	if len(f.ProjectIDs) > 0 {
+		b.in("project_id", anySlice(f.ProjectIDs))
+	}
+
+	if f.MinAmount.Valid {
+		b.add("CAST(amount AS REAL) >= ?", f.MinAmount.Decimal.InexactFloat64())
+	}
+
+	if f.MaxAmount.Valid {
+		b.add("CAST(amount AS REAL) <= ?", f.MaxAmount.Decimal.InexactFloat64())
+	}
+
+	if f.Description != "" {
+		b.add(`unicode_lower(description) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(f.Description))+"%")
+	}
+
+	return b
+}
+
+// query строит SELECT страницы с учетом сортировки и курсора. Выбирается на одну
+// строку больше лимита, чтобы понять, есть ли следующая страница.
+func (f ExpenseFilter) query(projectAmount string) (string, []any, error) {
+	b := f.where()
+
+	column := f.Sort.column(projectAmount)
+
+	direction, compare := "DESC", "<"
+	if f.Asc {
+		direction, compare = "ASC", ">"
+	}
+
+	if f.Cursor != "" {
+		id, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+
+		b.add(fmt.Sprintf("(%[1]s, id) %[2]s (SELECT %[1]s, id FROM expenses WHERE id = ?)", column, compare), id.String())
+	}
+
+	query := selectExpenseColumns + b.String() + fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+	args := b.args
+
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit+1)
+	}
+
+	return query, args, nil
+}
@@ -0,0 +1,146 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_find.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	base := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	fixtures := []model.Expense{
+		{Category: model.CategoryMaterials, PaymentType: model.PaymentTypeCash, Amount: decimal.NewFromInt(100), Description: "краска белая", UserID: 1},
+		{Category: model.CategoryMaterials, PaymentType: model.PaymentTypeCard, Amount: decimal.NewFromInt(2500), Description: "плитка 50%", UserID: 2},
+		{Category: model.CategoryLabor, PaymentType: model.PaymentTypeCash, Amount: decimal.NewFromInt(5000), Description: "демонтаж", UserID: 1},
+		{Category: model.CategoryTools, PaymentType: model.PaymentTypeCard, Amount: decimal.NewFromInt(700), Description: "шпатель", UserID: 2},
+		{Category: model.CategoryLabor, PaymentType: model.PaymentTypeCard, Amount: decimal.NewFromInt(1200), Description: "Краска стен", UserID: 1},
+	}
+
+	for i := range fixtures {
+		fixtures[i].ID = uuid.New()
+		fixtures[i].CreatedAt = base.AddDate(0, 0, i)
+		fixtures[i].UpdatedAt = base.AddDate(0, 0, i)
+
+		require.NoError(t, srv.Insert(ctx, fixtures[i]), "insert failed")
+	}
+
+	descriptions := func(expenses model.Expenses) []string {
+		result := make([]string, len(expenses))
+		for i := range expenses {
+			result[i] = expenses[i].Description
+		}
+
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		filter database.ExpenseFilter
+		want   []string
+	}{
+		{
+			name:   "default order is newest first",
+			filter: database.ExpenseFilter{},
+			want:   []string{"Краска стен", "шпатель", "демонтаж", "плитка 50%", "краска белая"},
+		},
+		{
+			name:   "date range",
+			filter: database.ExpenseFilter{From: base.AddDate(0, 0, 1), To: base.AddDate(0, 0, 3)},
+			want:   []string{"демонтаж", "плитка 50%"},
+		},
+		{
+			name:   "category and payment type",
+			filter: database.ExpenseFilter{Categories: []model.Category{model.CategoryLabor}, PaymentTypes: []model.PaymentType{model.PaymentTypeCard}},
+			want:   []string{"Краска стен"},
+		},
+		{
+			name:   "user and amount range",
+			filter: database.ExpenseFilter{UserIDs: []int64{2}, MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(1000))},
+			want:   []string{"плитка 50%"},
+		},
+		{
+			name:   "description substring",
+			filter: database.ExpenseFilter{Description: "Краска"},
+			want:   []string{"Краска стен", "краска белая"},
+		},
+		{
+			name:   "description ignores cyrillic case",
+			filter: database.ExpenseFilter{Description: "краска"},
+			want:   []string{"Краска стен", "краска белая"},
+		},
+		{
+			name:   "like wildcards are escaped",
+			filter: database.ExpenseFilter{Description: "%"},
+			want:   []string{"плитка 50%"},
+		},
+		{
+			name:   "sort by amount ascending",
+			filter: database.ExpenseFilter{Sort: database.SortByAmount, Asc: true, MaxAmount: decimal.NewNullDecimal(decimal.NewFromInt(2000))},
+			want:   []string{"краска белая", "шпатель", "Краска стен"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenses, next, err := srv.Find(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Empty(t, next)
+			assert.Equal(t, tt.want, descriptions(expenses))
+		})
+	}
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		filter := database.ExpenseFilter{Sort: database.SortByAmount, Limit: 2}
+
+		var got []string
+
+		for {
+			expenses, next, err := srv.Find(ctx, filter)
+			require.NoError(t, err)
+
+			got = append(got, descriptions(expenses)...)
+
+			if next == "" {
+				break
+			}
+
+			filter.Cursor = next
+		}
+
+		assert.Equal(t, []string{"демонтаж", "плитка 50%", "Краска стен", "шпатель", "краска белая"}, got)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, _, err := srv.Find(ctx, database.ExpenseFilter{Cursor: "!!!"})
+		require.ErrorIs(t, err, database.ErrInvalidCursor)
+	})
+
+	t.Run("ProjectChangedAt is project-wide", func(t *testing.T) {
+		changedAt, err := srv.ProjectChangedAt(ctx, model.DefaultProjectID)
+		require.NoError(t, err)
+		assert.False(t, changedAt.Before(base), "changed at: %s", changedAt)
+
+		_, err = srv.ProjectChangedAt(ctx, 42)
+		require.ErrorIs(t, err, model.ErrNotFound)
+	})
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"kudadeli/model"
+)
+
+// Actor — кто меняет траты. Передается через контекст, чтобы журнал заполнялся
+// без лишних параметров у каждого метода.
+type Actor struct {
+	UserID int64
+	Source model.ChangeSource
+}
+
+type actorKey struct{}
+
+// WithActor запоминает в контексте, кто и откуда меняет траты.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+
+	return Actor{Source: model.SourceSystem}
+}
+
+// recordHistory дописывает запись в журнал в той же транзакции, что и само изменение.
+func recordHistory(ctx context.Context, tx *sql.Tx, id model.ExpenseID, action model.ChangeAction,
+	changes []model.FieldChange) error {
+	if changes == nil {
+		changes = []model.FieldChange{}
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("marshal changes: %w", err)
+	}
+
+	actor := actorFromContext(ctx)
+
+	_, err = tx.ExecContext(ctx, insertHistory,
+		id.String(),
+		time.Now().UTC().Format(time.RFC3339),
+		string(action),
+		actor.UserID,
+		string(actor.Source),
+		string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("insert history: %w", err)
+	}
+
+	return nil
+}
+
+// txExpense читает трату внутри транзакции, чтобы сравнить ее с новой версией.
+func txExpense(ctx context.Context, tx *sql.Tx, id model.ExpenseID) (model.Expense, error) {
+	expense, err := scanExpense(tx.QueryRowContext(ctx, selectExpense, id.String()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Expense{}, model.ErrNotFound
+		}
+
+		return model.Expense{}, err
+	}
+
+	return expense, nil
+}
+
+// History возвращает журнал изменений траты от старых записей к новым.
+func (s *Service) History(ctx context.Context, id model.ExpenseID) ([]model.HistoryEntry, error) {
+	rows, err := s.db.QueryContext(ctx, selectHistory, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("select history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.HistoryEntry
+
+	for rows.Next() {
+		var (
+			entry             model.HistoryEntry
+			changedAt, action string
+			source, changes   string
+		)
+
+		err := rows.Scan(&entry.ID, &entry.ExpenseID, &changedAt, &action, &entry.UserID, &source, &changes)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		entry.ChangedAt, err = time.Parse(time.RFC3339, changedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse changed at: %w", err)
+		}
+
+		err = json.Unmarshal([]byte(changes), &entry.Changes)
+		if err != nil {
+			return nil, fmt.Errorf("parse changes: %w", err)
+		}
+
+		entry.Action = model.ChangeAction(action)
+		entry.Source = model.ChangeSource(source)
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return entries, nil
+}
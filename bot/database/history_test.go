@@ -0,0 +1,95 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_history.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	botCtx := database.WithActor(ctx, database.Actor{UserID: 42, Source: model.SourceBot})
+	webCtx := database.WithActor(ctx, database.Actor{UserID: 7, Source: model.SourceWeb})
+
+	expense := model.Expense{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+		Category:    model.CategoryMaterials,
+		PaymentType: model.PaymentTypeCash,
+		Description: "краска",
+		Amount:      decimal.NewFromInt(1500),
+		UserID:      42,
+		ProjectID:   model.DefaultProjectID,
+	}
+	require.NoError(t, srv.Insert(botCtx, expense))
+
+	updated := expense
+	updated.Amount = decimal.NewFromInt(1700)
+	updated.Description = "краска белая"
+	require.NoError(t, srv.Update(webCtx, updated))
+
+	// Сохранение без изменений в журнал не попадает.
+	require.NoError(t, srv.Update(webCtx, updated))
+
+	require.NoError(t, srv.UpdateCategory(botCtx, expense.ID, model.CategoryTools))
+	require.NoError(t, srv.Delete(webCtx, expense.ID))
+	require.NoError(t, srv.Restore(ctx, expense.ID))
+
+	entries, err := srv.History(ctx, expense.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+
+	actions := make([]model.ChangeAction, 0, len(entries))
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+
+	assert.Equal(t, []model.ChangeAction{
+		model.ActionCreate, model.ActionUpdate, model.ActionCategory, model.ActionDelete, model.ActionRestore,
+	}, actions)
+
+	create := entries[0]
+	assert.Equal(t, int64(42), create.UserID)
+	assert.Equal(t, model.SourceBot, create.Source)
+	assert.Contains(t, create.Changes, model.FieldChange{Field: "amount", Old: "", New: "1500"})
+
+	update := entries[1]
+	assert.Equal(t, int64(7), update.UserID)
+	assert.Equal(t, model.SourceWeb, update.Source)
+	assert.Equal(t, []model.FieldChange{
+		{Field: "amount", Old: "1500", New: "1700"},
+		{Field: "description", Old: "краска", New: "краска белая"},
+	}, update.Changes)
+
+	assert.Equal(t, []model.FieldChange{
+		{Field: "category", Old: "материалы", New: "инструменты"},
+	}, entries[2].Changes)
+
+	deletedAt := entries[3].Changes[0].New
+	assert.NotEmpty(t, deletedAt)
+	assert.Equal(t, []model.FieldChange{{Field: "deletedAt", Old: deletedAt, New: ""}}, entries[4].Changes)
+	assert.Equal(t, model.SourceSystem, entries[4].Source, "context without actor is a system change")
+
+	entries, err = srv.History(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kudadeli/model"
+)
+
+// Import вставляет траты одной транзакцией. Трата считается дубликатом, если в том же
+// проекте уже есть трата с той же суммой и описанием в тот же день (в часовом поясе loc) — такие
+// пропускаются, их индексы возвращаются. При dryRun транзакция откатывается.
+func (s *Service) Import(ctx context.Context, expenses model.Expenses, loc *time.Location,
+	dryRun bool) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	var duplicates []int
+
+	for i := range expenses {
+		e := expenses[i]
+
+		dayStart := startOfDay(e.CreatedAt.In(loc))
+
+		var exists bool
+
+		err := tx.QueryRowContext(ctx, selectDuplicateExpense,
+			dayStart.UTC().Format(time.RFC3339),
+			dayStart.AddDate(0, 0, 1).UTC().Format(time.RFC3339),
+			e.Amount.String(),
+			e.Description,
+			projectOrDefault(e.ProjectID),
+		).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("check duplicate: %w", err)
+		}
+
+		if exists {
+			duplicates = append(duplicates, i)
+
+			continue
+		}
+
+		err = s.insertTx(ctx, tx, e)
+		if err != nil {
+			return nil, fmt.Errorf("expense %d: %w", i, err)
+		}
+	}
+
+	if dryRun {
+		return duplicates, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return duplicates, nil
+}
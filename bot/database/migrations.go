@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var ErrSchemaTooNew = errors.New("database schema is newer than the binary")
+
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// migrations применяются строго по порядку, каждая в своей транзакции.
+// Уже выпущенные миграции менять нельзя — только добавлять новые в конец.
+var migrations = []migration{ //nolint:gochecknoglobals
+	{version: 1, name: "create expenses", query: createExpenses},
+	{version: 2, name: "create budgets", query: createBudgets},
+	{version: 3, name: "create attachments", query: createAttachments},
+	{version: 4, name: "create projects", query: createProjects},
+	{version: 5, name: "create categories", query: createCategories},
+	{version: 6, name: "create expense history", query: createHistory},
+	{version: 7, name: "create users", query: createUsers},
+	{version: 8, name: "create api tokens", query: createAPITokens},
+	{version: 9, name: "create exchange rates", query: createExchangeRates},
+	{version: 10, name: "create recurring expenses", query: createRecurringExpenses},
+	{version: 11, name: "create digests", query: createDigests},
+	{version: 12, name: "add user names and shares", query: createShares},
+	{version: 13, name: "add users to default project", query: addUsersToDefaultProject},
+	{version: 14, name: "track project changes", query: createProjectChanges},
+	{version: 15, name: "release archived category keywords", query: releaseArchivedKeywords},
+}
+
+func (s *Service) schemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+
+	err := s.db.QueryRowContext(ctx, selectSchemaVersion).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("select schema version: %w", err)
+	}
+
+	return int(version.Int64), nil
+}
+
+func (s *Service) migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, createSchemaMigrations)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	current, err := s.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("%w: database version %d, binary version %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		slog.InfoContext(ctx, "apply migration", "version", m.version, "name", m.name)
+
+		err := s.applyMigration(ctx, m)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) applyMigration(ctx context.Context, m migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, m.query)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, insertSchemaMigration, m.version, m.name, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("insert schema_migrations: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
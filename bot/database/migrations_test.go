@@ -0,0 +1,63 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_migrations.db"
+	defer os.Remove(tmpFile)
+
+	t.Run("Legacy database without schema_migrations", func(t *testing.T) {
+		db, err := sql.Open("sqlite", tmpFile)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, `
+CREATE TABLE expenses (
+    id TEXT PRIMARY KEY,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+    category_id INTEGER NOT NULL,
+    description TEXT,
+    amount TEXT NOT NULL,
+    payment_type_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	deleted_at DATETIME
+)`)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		srv, err := database.New(ctx, tmpFile, time.UTC)
+		require.NoError(t, err, "migrate legacy database")
+		require.NoError(t, srv.Close())
+	})
+
+	t.Run("Reopen is idempotent", func(t *testing.T) {
+		srv, err := database.New(ctx, tmpFile, time.UTC)
+		require.NoError(t, err, "reopen database")
+		require.NoError(t, srv.Close())
+	})
+
+	t.Run("Refuse newer schema", func(t *testing.T) {
+		db, err := sql.Open("sqlite", tmpFile)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (100000, 'future', '')`)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		_, err = database.New(ctx, tmpFile, time.UTC)
+		require.ErrorIs(t, err, database.ErrSchemaTooNew)
+	})
+}
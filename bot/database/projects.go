@@ -0,0 +1,240 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kudadeli/model"
+)
+
+func scanProject(row scanner) (model.Project, error) {
+	var (
+		project   model.Project
+		createdAt string
+	)
+
+	err := row.Scan(&project.ID, &project.Name, &project.Currency, &createdAt)
+	if err != nil {
+		return model.Project{}, fmt.Errorf("row scan: %w", err)
+	}
+
+	project.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return model.Project{}, fmt.Errorf("parse created at: %w", err)
+	}
+
+	return project, nil
+}
+
+// CreateProject создает проект, делает ownerID его участником и активным проектом владельца.
+func (s *Service) CreateProject(ctx context.Context, name, currency string, ownerID int64) (model.Project, error) {
+	project := model.Project{
+		Name:      strings.TrimSpace(name),
+		Currency:  strings.ToUpper(strings.TrimSpace(currency)),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	if project.Currency == "" {
+		project.Currency = model.DefaultCurrency
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.Project{}, fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, insertProject, project.Name, project.Currency, project.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return model.Project{}, fmt.Errorf("insert project: %w", err)
+	}
+
+	project.ID, err = res.LastInsertId()
+	if err != nil {
+		return model.Project{}, fmt.Errorf("last insert id: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, insertProjectMember, project.ID, ownerID)
+	if err != nil {
+		return model.Project{}, fmt.Errorf("insert project member: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, upsertUserProject, ownerID, project.ID)
+	if err != nil {
+		return model.Project{}, fmt.Errorf("upsert user project: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return model.Project{}, fmt.Errorf("commit: %w", err)
+	}
+
+	return project, nil
+}
+
+func (s *Service) Project(ctx context.Context, id model.ProjectID) (model.Project, error) {
+	project, err := scanProject(s.db.QueryRowContext(ctx, selectProject, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Project{}, model.ErrNotFound
+		}
+
+		return model.Project{}, err
+	}
+
+	return project, nil
+}
+
+// UserProjects возвращает проекты, в которых участвует пользователь. В проект по умолчанию
+// пользователь попадает при создании, см. EnsureOwners и SetUserRole.
+func (s *Service) UserProjects(ctx context.Context, userID int64) ([]model.Project, error) {
+	rows, err := s.db.QueryContext(ctx, selectUserProjects, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select user projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []model.Project
+
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, project)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return projects, nil
+}
+
+func (s *Service) AddProjectMember(ctx context.Context, projectID model.ProjectID, userID int64) error {
+	_, err := s.db.ExecContext(ctx, insertProjectMember, projectID, userID)
+	if err != nil {
+		return fmt.Errorf("insert project member: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) IsProjectMember(ctx context.Context, projectID model.ProjectID, userID int64) (bool, error) {
+	var isMember bool
+
+	err := s.db.QueryRowContext(ctx, selectIsProjectMember, projectID, userID).Scan(&isMember)
+	if err != nil {
+		return false, fmt.Errorf("select project member: %w", err)
+	}
+
+	return isMember, nil
+}
+
+func (s *Service) ProjectMembers(ctx context.Context, projectID model.ProjectID) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, selectProjectMembers, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("select project members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []int64
+
+	for rows.Next() {
+		var userID int64
+
+		err := rows.Scan(&userID)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		members = append(members, userID)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return members, nil
+}
+
+// SetActiveProject переключает пользователя на проект, в котором он участвует.
+func (s *Service) SetActiveProject(ctx context.Context, userID int64, projectID model.ProjectID) error {
+	isMember, err := s.IsProjectMember(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !isMember {
+		return model.ErrNotFound
+	}
+
+	_, err = s.db.ExecContext(ctx, upsertUserProject, userID, projectID)
+	if err != nil {
+		return fmt.Errorf("upsert user project: %w", err)
+	}
+
+	return nil
+}
+
+// ActiveProject возвращает проект, выбранный пользователем, или первый из его проектов.
+// Пользователь без проектов получает model.ErrNotFound.
+func (s *Service) ActiveProject(ctx context.Context, userID int64) (model.Project, error) {
+	projects, err := s.UserProjects(ctx, userID)
+	if err != nil {
+		return model.Project{}, err
+	}
+
+	if len(projects) == 0 {
+		return model.Project{}, model.ErrNotFound
+	}
+
+	var projectID model.ProjectID
+
+	err = s.db.QueryRowContext(ctx, selectUserProject, userID).Scan(&projectID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return model.Project{}, fmt.Errorf("select user project: %w", err)
+	}
+
+	for _, project := range projects {
+		if project.ID == projectID {
+			return project, nil
+		}
+	}
+
+	return projects[0], nil
+}
+
+// LinkChat привязывает групповой чат к проекту: все траты из чата попадают в него.
+func (s *Service) LinkChat(ctx context.Context, chatID int64, projectID model.ProjectID) error {
+	_, err := s.db.ExecContext(ctx, upsertChatProject, chatID, projectID)
+	if err != nil {
+		return fmt.Errorf("upsert chat project: %w", err)
+	}
+
+	return nil
+}
+
+// ChatProject возвращает проект, привязанный к чату, или model.ErrNotFound.
+func (s *Service) ChatProject(ctx context.Context, chatID int64) (model.Project, error) {
+	var projectID model.ProjectID
+
+	err := s.db.QueryRowContext(ctx, selectChatProject, chatID).Scan(&projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Project{}, model.ErrNotFound
+		}
+
+		return model.Project{}, fmt.Errorf("select chat project: %w", err)
+	}
+
+	return s.Project(ctx, projectID)
+}
@@ -0,0 +1,98 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestProjects(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_projects.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	const owner, guest = int64(1), int64(2)
+
+	t.Run("Unknown user has no projects", func(t *testing.T) {
+		projects, err := srv.UserProjects(ctx, owner)
+		require.NoError(t, err)
+		assert.Empty(t, projects)
+
+		_, err = srv.ActiveProject(ctx, owner)
+		require.ErrorIs(t, err, model.ErrNotFound)
+
+		// Чтение не добавляет пользователя в проекты.
+		isMember, err := srv.IsProjectMember(ctx, model.DefaultProjectID, owner)
+		require.NoError(t, err)
+		assert.False(t, isMember)
+	})
+
+	t.Run("New user joins default project", func(t *testing.T) {
+		require.NoError(t, srv.EnsureOwners(ctx, []int64{owner}))
+		require.NoError(t, srv.SetUserRole(ctx, guest, model.RoleViewer))
+
+		isMember, err := srv.IsProjectMember(ctx, model.DefaultProjectID, guest)
+		require.NoError(t, err)
+		assert.True(t, isMember)
+
+		project, err := srv.ActiveProject(ctx, owner)
+		require.NoError(t, err)
+
+		assert.Equal(t, model.DefaultProjectID, project.ID)
+		assert.Equal(t, model.DefaultCurrency, project.Currency)
+	})
+
+	var dacha model.Project
+
+	t.Run("CreateProject switches owner", func(t *testing.T) {
+		dacha, err = srv.CreateProject(ctx, " Дача ", "eur", owner)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Дача", dacha.Name)
+		assert.Equal(t, "EUR", dacha.Currency)
+
+		active, err := srv.ActiveProject(ctx, owner)
+		require.NoError(t, err)
+		assert.Equal(t, dacha.ID, active.ID)
+
+		projects, err := srv.UserProjects(ctx, owner)
+		require.NoError(t, err)
+		assert.Len(t, projects, 2)
+	})
+
+	t.Run("Switch requires membership", func(t *testing.T) {
+		err := srv.SetActiveProject(ctx, guest, dacha.ID)
+		require.ErrorIs(t, err, model.ErrNotFound)
+
+		require.NoError(t, srv.AddProjectMember(ctx, dacha.ID, guest))
+		require.NoError(t, srv.SetActiveProject(ctx, guest, dacha.ID))
+
+		members, err := srv.ProjectMembers(ctx, dacha.ID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int64{owner, guest}, members)
+	})
+
+	t.Run("Chat link", func(t *testing.T) {
+		_, err := srv.ChatProject(ctx, -100)
+		require.ErrorIs(t, err, model.ErrNotFound)
+
+		require.NoError(t, srv.LinkChat(ctx, -100, dacha.ID))
+
+		project, err := srv.ChatProject(ctx, -100)
+		require.NoError(t, err)
+		assert.Equal(t, dacha.ID, project.ID)
+	})
+}
@@ -1,8 +1,20 @@
 package database
 
 const (
+	createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+)
+`
+
+	selectSchemaVersion = `SELECT MAX(version) FROM schema_migrations`
+
+	insertSchemaMigration = `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`
+
 	createExpenses = `
-CREATE TABLE expenses (
+CREATE TABLE IF NOT EXISTS expenses (
     id TEXT PRIMARY KEY,
 	created_at TEXT NOT NULL,
 	updated_at TEXT NOT NULL,
@@ -17,35 +29,466 @@ CREATE TABLE expenses (
 
 	insertExpense = `
 INSERT INTO expenses (
-	id, created_at, updated_at, category_id, description, amount, payment_type_id, user_id
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	id, created_at, updated_at, category_id, description, amount, currency, payment_type_id, user_id, project_id
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 	updateExpense = `
 UPDATE expenses
-SET updated_at = ?, category_id = ?, description = ?, amount = ?, payment_type_id = ?
-WHERE id = ?
+SET created_at = ?, updated_at = ?, category_id = ?, description = ?, amount = ?, currency = ?, payment_type_id = ?
+WHERE id = ? AND deleted_at IS NULL
 `
 
 	updateExpenseCategory = `
 UPDATE expenses
 SET updated_at = ?, category_id = ?
-WHERE id = ?
+WHERE id = ? AND deleted_at IS NULL
+`
+
+	// Удаление мягкое: строка остается в корзине до очистки. updated_at тоже меняется,
+	// чтобы Last-Modified выборки сдвинулся.
+	deleteExpense = `UPDATE expenses SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	selectDeletedAt = `SELECT deleted_at FROM expenses WHERE id = ?`
+
+	restoreExpense = `UPDATE expenses SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`
+
+	selectExpenseColumns = `
+SELECT id, created_at, updated_at, category_id, description, amount, currency, payment_type_id, user_id, project_id,
+	deleted_at
+FROM expenses`
+
+	selectExpense = selectExpenseColumns + ` WHERE id = ? AND deleted_at IS NULL`
+
+	selectDeletedExpense = selectExpenseColumns + ` WHERE id = ? AND deleted_at IS NOT NULL`
+
+	purgeAttachments = `
+DELETE FROM attachments WHERE expense_id IN (
+	SELECT id FROM expenses WHERE deleted_at IS NOT NULL AND datetime(deleted_at) < datetime(?)
+)`
+
+	purgeExpenses = `DELETE FROM expenses WHERE deleted_at IS NOT NULL AND datetime(deleted_at) < datetime(?)`
+
+	selectProjectChangedAt = `SELECT changed_at FROM projects WHERE id = ?`
+
+	createBudgets = `
+CREATE TABLE budgets (
+	category_id INTEGER PRIMARY KEY,
+	amount TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+)
+`
+
+	upsertBudget = `
+INSERT INTO budgets (project_id, category_id, amount, updated_at) VALUES (?, ?, ?, ?)
+ON CONFLICT (project_id, category_id) DO UPDATE SET amount = excluded.amount, updated_at = excluded.updated_at
+`
+
+	deleteBudget = `DELETE FROM budgets WHERE project_id = ? AND category_id = ?`
+
+	selectBudgets = `SELECT category_id, amount FROM budgets WHERE project_id = ?`
+
+	// projectCurrency — базовая валюта проекта траты.
+	projectCurrency = `(SELECT p.currency FROM projects p WHERE p.id = expenses.project_id)`
+
+	selectMissingRates = `SELECT DISTINCT currency FROM expenses`
+
+	selectDuplicateExpense = `
+SELECT EXISTS (
+	SELECT 1 FROM expenses
+	WHERE deleted_at IS NULL
+		AND datetime(created_at) >= datetime(?) AND datetime(created_at) < datetime(?)
+		AND CAST(amount AS REAL) = CAST(? AS REAL)
+		AND COALESCE(description, '') = ?
+		AND project_id = ?
+)
+`
+
+	createAttachments = `
+CREATE TABLE attachments (
+	id TEXT PRIMARY KEY,
+	expense_id TEXT NOT NULL REFERENCES expenses (id),
+	created_at TEXT NOT NULL,
+	file_name TEXT NOT NULL,
+	mime_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	telegram_file_id TEXT NOT NULL DEFAULT '',
+	data BLOB NOT NULL
+);
+CREATE INDEX attachments_expense_id ON attachments (expense_id);
+`
+
+	insertAttachment = `
+INSERT INTO attachments (id, expense_id, created_at, file_name, mime_type, size, telegram_file_id, data)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+	selectAttachmentColumns = `
+SELECT id, expense_id, created_at, file_name, mime_type, size, telegram_file_id
+FROM attachments`
+
+	selectAttachments = selectAttachmentColumns + ` WHERE expense_id = ? ORDER BY created_at, id`
+
+	selectAttachment = selectAttachmentColumns + ` WHERE id = ? AND expense_id = ?`
+
+	selectAttachmentData = `SELECT data FROM attachments WHERE id = ?`
+
+	createProjects = `
+CREATE TABLE projects (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+INSERT INTO projects (id, name, currency, created_at)
+VALUES (1, 'Ремонт', 'RUB', strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+
+CREATE TABLE project_members (
+	project_id INTEGER NOT NULL REFERENCES projects (id),
+	user_id INTEGER NOT NULL,
+	PRIMARY KEY (project_id, user_id)
+);
+INSERT INTO project_members (project_id, user_id) SELECT DISTINCT 1, user_id FROM expenses;
+
+CREATE TABLE user_projects (
+	user_id INTEGER PRIMARY KEY,
+	project_id INTEGER NOT NULL REFERENCES projects (id)
+);
+
+CREATE TABLE chat_projects (
+	chat_id INTEGER PRIMARY KEY,
+	project_id INTEGER NOT NULL REFERENCES projects (id)
+);
+
+ALTER TABLE expenses ADD COLUMN project_id INTEGER NOT NULL DEFAULT 1 REFERENCES projects (id);
+CREATE INDEX expenses_project_id ON expenses (project_id);
+
+CREATE TABLE budgets_new (
+	project_id INTEGER NOT NULL REFERENCES projects (id),
+	category_id INTEGER NOT NULL,
+	amount TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (project_id, category_id)
+);
+INSERT INTO budgets_new (project_id, category_id, amount, updated_at)
+SELECT 1, category_id, amount, updated_at FROM budgets;
+DROP TABLE budgets;
+ALTER TABLE budgets_new RENAME TO budgets;
+`
+
+	insertProject = `INSERT INTO projects (name, currency, created_at) VALUES (?, ?, ?)`
+
+	selectProjectColumns = `SELECT p.id, p.name, p.currency, p.created_at FROM projects p`
+
+	selectProject = selectProjectColumns + ` WHERE p.id = ?`
+
+	selectProjectCurrency = `SELECT currency FROM projects WHERE id = ?`
+
+	selectUserProjects = selectProjectColumns + `
+JOIN project_members m ON m.project_id = p.id
+WHERE m.user_id = ?
+ORDER BY p.id`
+
+	insertProjectMember = `INSERT OR IGNORE INTO project_members (project_id, user_id) VALUES (?, ?)`
+
+	selectIsProjectMember = `SELECT EXISTS (SELECT 1 FROM project_members WHERE project_id = ? AND user_id = ?)`
+
+	selectProjectMembers = `SELECT user_id FROM project_members WHERE project_id = ? ORDER BY user_id`
+
+	upsertUserProject = `
+INSERT INTO user_projects (user_id, project_id) VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE SET project_id = excluded.project_id
 `
 
-	deleteExpense = `UPDATE expenses SET deleted_at = datetime('now') WHERE id = ?`
+	selectUserProject = `SELECT project_id FROM user_projects WHERE user_id = ?`
 
-	selectExpenses = `
-SELECT id, created_at, updated_at, category_id, description, amount, payment_type_id, user_id
-FROM expenses WHERE deleted_at IS NULL
-ORDER BY created_at DESC
-	`
+	upsertChatProject = `
+INSERT INTO chat_projects (chat_id, project_id) VALUES (?, ?)
+ON CONFLICT (chat_id) DO UPDATE SET project_id = excluded.project_id
+`
+
+	selectChatProject = `SELECT project_id FROM chat_projects WHERE chat_id = ?`
+
+	createCategories = `
+CREATE TABLE categories (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	emoji TEXT NOT NULL DEFAULT '',
+	sort_order INTEGER NOT NULL DEFAULT 0,
+	archived INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO categories (id, name, emoji, sort_order) VALUES
+	(1, 'материалы', '🧱', 1),
+	(2, 'работа/оплата мастерам', '👷', 2),
+	(3, 'инструменты', '🔨', 3),
+	(4, 'мебель и техника', '🛋', 4),
+	(5, 'прочее/непредвиденное', '❓', 5);
+
+CREATE TABLE category_keywords (
+	keyword TEXT PRIMARY KEY,
+	category_id INTEGER NOT NULL REFERENCES categories (id)
+);
+INSERT INTO category_keywords (keyword, category_id) VALUES
+	('материалы', 1),
+	('услуги', 2),
+	('инструменты', 3),
+	('мебель', 4),
+	('прочее', 5);
+`
+
+	selectCategories = `SELECT id, name, emoji, sort_order, archived FROM categories ORDER BY sort_order, id`
+
+	selectCategoryKeywords = `SELECT keyword, category_id FROM category_keywords ORDER BY rowid`
+
+	insertCategory = `INSERT INTO categories (name, emoji, sort_order, archived) VALUES (?, ?, ?, ?)`
+
+	updateCategory = `UPDATE categories SET name = ?, emoji = ?, sort_order = ?, archived = ? WHERE id = ?`
+
+	selectMaxCategorySortOrder = `SELECT COALESCE(MAX(sort_order), 0) FROM categories`
+
+	selectCategoryNames = `SELECT id, name FROM categories`
+
+	deleteCategoryKeywords = `DELETE FROM category_keywords WHERE category_id = ?`
+
+	selectKeywordCategory = `SELECT category_id FROM category_keywords WHERE keyword = ?`
+
+	insertCategoryKeyword = `INSERT INTO category_keywords (keyword, category_id) VALUES (?, ?)`
+
+	// touchProjects отмечает изменение во всех проектах: в ответах API по тратам
+	// есть названия категорий, и после их правки кеш клиентов устаревает.
+	touchProjects = `UPDATE projects SET changed_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`
+
+	createHistory = `
+CREATE TABLE expense_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	expense_id TEXT NOT NULL,
+	changed_at TEXT NOT NULL,
+	action TEXT NOT NULL,
+	user_id INTEGER NOT NULL,
+	source TEXT NOT NULL,
+	changes TEXT NOT NULL
+);
+CREATE INDEX expense_history_expense_id ON expense_history (expense_id);
+`
 
-	latestUpdatedAt = `
-SELECT updated_at 
-FROM expenses 
-WHERE deleted_at IS NULL 
-ORDER BY updated_at DESC 
-LIMIT 1
-    `
+	insertHistory = `
+INSERT INTO expense_history (expense_id, changed_at, action, user_id, source, changes)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+	selectHistory = `
+SELECT id, expense_id, changed_at, action, user_id, source, changes
+FROM expense_history
+WHERE expense_id = ?
+ORDER BY id
+`
+
+	createUsers = `
+CREATE TABLE users (
+	user_id INTEGER PRIMARY KEY,
+	role TEXT NOT NULL,
+	created_at TEXT NOT NULL
+)
+`
+
+	selectUsers = `SELECT user_id, role, name, created_at FROM users ORDER BY created_at, user_id`
+
+	selectUserRole = `SELECT role FROM users WHERE user_id = ?`
+
+	insertOwner = `INSERT INTO users (user_id, role, created_at) VALUES (?, 'owner', ?) ON CONFLICT (user_id) DO NOTHING`
+
+	upsertUserRole = `
+INSERT INTO users (user_id, role, created_at) VALUES (?, ?, ?)
+ON CONFLICT (user_id) DO UPDATE SET role = excluded.role
+`
+
+	deleteUser = `DELETE FROM users WHERE user_id = ?`
+
+	selectOtherOwners = `SELECT COUNT(*) FROM users WHERE role = 'owner' AND user_id != ?`
+
+	createAPITokens = `
+CREATE TABLE api_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scope TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	expires_at TEXT
+);
+CREATE INDEX api_tokens_user_id ON api_tokens (user_id);
+`
+
+	insertAPIToken = `
+INSERT INTO api_tokens (user_id, name, token_hash, scope, created_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+	selectAPITokenColumns = `SELECT id, user_id, name, scope, created_at, expires_at FROM api_tokens`
+
+	selectAPITokenByHash = selectAPITokenColumns + ` WHERE token_hash = ?`
+
+	selectUserAPITokens = selectAPITokenColumns + ` WHERE user_id = ? ORDER BY id`
+
+	deleteAPIToken = `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`
+
+	createExchangeRates = `
+ALTER TABLE expenses ADD COLUMN currency TEXT NOT NULL DEFAULT 'RUB';
+UPDATE expenses SET currency = (SELECT p.currency FROM projects p WHERE p.id = expenses.project_id);
+
+CREATE TABLE exchange_rates (
+	date TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	base TEXT NOT NULL,
+	rate TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (currency, base, date)
+)
+`
+
+	upsertExchangeRate = `
+INSERT INTO exchange_rates (date, currency, base, rate, updated_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (currency, base, date) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at
+`
+
+	selectRatesUpdatedAt = `SELECT MAX(datetime(updated_at)) FROM exchange_rates`
+
+	// selectLatestRates — последний курс каждой валюты к base.
+	selectLatestRates = `
+SELECT r.date, r.currency, r.base, r.rate
+FROM exchange_rates r
+WHERE r.base = ? AND r.date = (
+	SELECT MAX(l.date) FROM exchange_rates l WHERE l.currency = r.currency AND l.base = r.base
+)
+ORDER BY r.currency
+`
+
+	createRecurringExpenses = `
+CREATE TABLE recurring_expenses (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_id INTEGER NOT NULL REFERENCES projects (id),
+	user_id INTEGER NOT NULL,
+	schedule TEXT NOT NULL,
+	category_id INTEGER NOT NULL,
+	payment_type_id INTEGER NOT NULL,
+	amount TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	catch_up INTEGER NOT NULL DEFAULT 0,
+	paused INTEGER NOT NULL DEFAULT 0,
+	next_run TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+CREATE INDEX recurring_expenses_next_run ON recurring_expenses (next_run);
+`
+
+	insertRecurring = `
+INSERT INTO recurring_expenses (
+	project_id, user_id, schedule, category_id, payment_type_id, amount, currency, description,
+	catch_up, paused, next_run, created_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+	selectRecurringColumns = `
+SELECT id, project_id, user_id, schedule, category_id, payment_type_id, amount, currency, description,
+	catch_up, paused, next_run, created_at
+FROM recurring_expenses`
+
+	selectRecurring = selectRecurringColumns + ` WHERE id = ?`
+
+	selectProjectRecurring = selectRecurringColumns + ` WHERE project_id = ? ORDER BY id`
+
+	selectDueRecurring = selectRecurringColumns + `
+WHERE paused = 0 AND datetime(next_run) <= datetime(?)
+ORDER BY datetime(next_run), id`
+
+	updateRecurring = `UPDATE recurring_expenses SET schedule = ?, catch_up = ?, paused = ?, next_run = ? WHERE id = ?`
+
+	updateRecurringNextRun = `UPDATE recurring_expenses SET next_run = ? WHERE id = ?`
+
+	deleteRecurring = `DELETE FROM recurring_expenses WHERE id = ?`
+
+	createDigests = `
+CREATE TABLE digests (
+	user_id INTEGER PRIMARY KEY,
+	chat_id INTEGER NOT NULL,
+	hour INTEGER NOT NULL,
+	minute INTEGER NOT NULL,
+	timezone TEXT NOT NULL,
+	period TEXT NOT NULL,
+	next_run TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+)
+`
+
+	upsertDigest = `
+INSERT INTO digests (user_id, chat_id, hour, minute, timezone, period, next_run, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (user_id) DO UPDATE SET
+	chat_id = excluded.chat_id,
+	hour = excluded.hour,
+	minute = excluded.minute,
+	timezone = excluded.timezone,
+	period = excluded.period,
+	next_run = excluded.next_run,
+	updated_at = excluded.updated_at
+`
+
+	selectDigestColumns = `SELECT user_id, chat_id, hour, minute, timezone, period, next_run FROM digests`
+
+	selectDigest = selectDigestColumns + ` WHERE user_id = ?`
+
+	// Сводки получают только пользователи, у которых остался доступ.
+	selectDueDigests = selectDigestColumns + `
+WHERE user_id IN (SELECT user_id FROM users) AND datetime(next_run) <= datetime(?)
+ORDER BY datetime(next_run), user_id`
+
+	updateDigestNextRun = `UPDATE digests SET next_run = ? WHERE user_id = ?`
+
+	deleteDigest = `DELETE FROM digests WHERE user_id = ?`
+
+	createShares = `
+ALTER TABLE users ADD COLUMN name TEXT NOT NULL DEFAULT '';
+ALTER TABLE project_members ADD COLUMN share INTEGER;
+`
+
+	updateUserName = `UPDATE users SET name = ? WHERE user_id = ? AND name <> ?`
+
+	updateShare = `UPDATE project_members SET share = ? WHERE project_id = ? AND user_id = ?`
+
+	resetShares = `UPDATE project_members SET share = NULL WHERE project_id = ?`
+
+	selectShares = `SELECT user_id, share FROM project_members WHERE project_id = ? AND share IS NOT NULL ORDER BY user_id`
+
+	// Раньше пользователь без проектов попадал в проект по умолчанию при первом обращении,
+	// теперь — при создании. Тем, кто еще ни разу не обращался, участие выдается здесь.
+	addUsersToDefaultProject = `
+INSERT OR IGNORE INTO project_members (project_id, user_id)
+SELECT 1, user_id FROM users WHERE user_id NOT IN (SELECT user_id FROM project_members);
+`
+
+	// createProjectChanges заводит время последнего изменения трат проекта для Last-Modified.
+	// Триггеры обновляют его при любой записи в expenses, включая окончательное удаление
+	// из корзины и перенос траты в другой проект.
+	createProjectChanges = `
+ALTER TABLE projects ADD COLUMN changed_at TEXT NOT NULL DEFAULT '1970-01-01T00:00:00Z';
+UPDATE projects SET changed_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now');
+
+CREATE TRIGGER expenses_insert_changed AFTER INSERT ON expenses BEGIN
+	UPDATE projects SET changed_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') WHERE id = NEW.project_id;
+END;
+
+CREATE TRIGGER expenses_update_changed AFTER UPDATE ON expenses BEGIN
+	UPDATE projects SET changed_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') WHERE id IN (OLD.project_id, NEW.project_id);
+END;
+
+CREATE TRIGGER expenses_delete_changed AFTER DELETE ON expenses BEGIN
+	UPDATE projects SET changed_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now') WHERE id = OLD.project_id;
+END;
+`
+
+	// Архивные категории больше не держат ключевые слова: освобождаем уже занятые.
+	releaseArchivedKeywords = `
+DELETE FROM category_keywords WHERE category_id IN (SELECT id FROM categories WHERE archived);
+`
 )
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"kudadeli/model"
+)
+
+// rateExpr — курс валюты траты к валюте проекта на дату траты: последний заданный не позже
+// этой даты, прямой или обратный. Для валюты проекта — 1, без курса — NULL. Дата траты
+// берется в часовом поясе базы: курсы задаются по местным календарным дням, а date()
+// в SQLite считает в UTC, и вечерняя трата попадала на курс соседнего дня.
+func (s *Service) rateExpr() string {
+	day := "local_date(expenses.created_at, '" + strings.ReplaceAll(s.loc.String(), "'", "''") + "')"
+
+	return `CASE WHEN expenses.currency = ` + projectCurrency + ` THEN 1.0 ELSE COALESCE(
+	(SELECT CAST(r.rate AS REAL) FROM exchange_rates r
+		WHERE r.currency = expenses.currency AND r.base = ` + projectCurrency + `
+			AND r.date <= ` + day + `
+		ORDER BY r.date DESC LIMIT 1),
+	(SELECT 1.0 / CAST(r.rate AS REAL) FROM exchange_rates r
+		WHERE r.currency = ` + projectCurrency + ` AND r.base = expenses.currency
+			AND r.date <= ` + day + `
+		ORDER BY r.date DESC LIMIT 1)
+) END`
+}
+
+// projectAmountExpr — сумма траты в валюте проекта, без курса — NULL.
+func (s *Service) projectAmountExpr() string {
+	return `(CAST(amount AS REAL) * ` + s.rateExpr() + `)`
+}
+
+// sumAmountCents складывает суммы в валюте проекта. Суммы хранятся строками, поэтому
+// складываем их в копейках, чтобы не копить ошибку float. Траты без курса в сумму не попадают.
+func (s *Service) sumAmountCents() string {
+	return `COALESCE(SUM(CAST(ROUND(` + s.projectAmountExpr() + ` * 100) AS INTEGER)), 0)`
+}
+
+func upsertRateTx(ctx context.Context, tx *sql.Tx, rate model.ExchangeRate) error {
+	err := rate.Validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, upsertExchangeRate, rate.Date.Format(time.DateOnly), rate.Currency, rate.Base,
+		rate.Rate.String(), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("upsert exchange rate: %w", err)
+	}
+
+	return nil
+}
+
+// SetRates сохраняет курсы одной транзакцией, курс на ту же дату перезаписывается.
+// Дата курса берется календарная, без времени и часового пояса.
+func (s *Service) SetRates(ctx context.Context, rates []model.ExchangeRate) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for i := range rates {
+			err := upsertRateTx(ctx, tx, rates[i])
+			if err != nil {
+				return fmt.Errorf("rate %d: %w", i, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RatesUpdatedAt возвращает время последнего изменения курсов: новый курс меняет
+// пересчитанные суммы, даже если сами траты не менялись.
+func (s *Service) RatesUpdatedAt(ctx context.Context) (time.Time, error) {
+	var updatedAt sql.NullString
+
+	err := s.db.QueryRowContext(ctx, selectRatesUpdatedAt).Scan(&updatedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("select rates updated_at: %w", err)
+	}
+
+	if !updatedAt.Valid {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.DateTime, updatedAt.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse rates updated_at: %w", err)
+	}
+
+	return t, nil
+}
+
+// LatestRates возвращает последний известный курс каждой валюты к base.
+func (s *Service) LatestRates(ctx context.Context, base string) ([]model.ExchangeRate, error) {
+	rows, err := s.db.QueryContext(ctx, selectLatestRates, base)
+	if err != nil {
+		return nil, fmt.Errorf("select latest rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []model.ExchangeRate
+
+	for rows.Next() {
+		var (
+			rate          model.ExchangeRate
+			date, rateStr string
+		)
+
+		err := rows.Scan(&date, &rate.Currency, &rate.Base, &rateStr)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		rate.Date, err = time.Parse(time.DateOnly, date)
+		if err != nil {
+			return nil, fmt.Errorf("parse rate date: %w", err)
+		}
+
+		rate.Rate, err = decimal.NewFromString(rateStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse rate: %w", err)
+		}
+
+		rates = append(rates, rate)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return rates, nil
+}
+
+// missingRates возвращает валюты трат по фильтру, которые не на что пересчитать
+// в валюту проекта.
+func (s *Service) missingRates(ctx context.Context, filter ExpenseFilter) ([]string, error) {
+	b := filter.where()
+	b.add(s.rateExpr() + " IS NULL")
+
+	rows, err := s.db.QueryContext(ctx, selectMissingRates+b.String()+" ORDER BY 1", b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("select missing rates: %w", err)
+	}
+	defer rows.Close()
+
+	currencies := []string{}
+
+	for rows.Next() {
+		var currency string
+
+		err := rows.Scan(&currency)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		currencies = append(currencies, currency)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return currencies, nil
+}
@@ -0,0 +1,147 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestExchangeRates(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_rates.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	base := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, srv.SetRates(ctx, []model.ExchangeRate{
+		{Date: base, Currency: "usd", Base: "RUB", Rate: decimal.NewFromInt(80)},
+		{Date: base.AddDate(0, 0, 2), Currency: "USD", Base: "RUB", Rate: decimal.NewFromInt(90)},
+		// Обратный курс: 1 RUB = 0.01 EUR, то есть 1 EUR = 100 RUB.
+		{Date: base, Currency: "RUB", Base: "EUR", Rate: decimal.RequireFromString("0.01")},
+	}))
+
+	fixtures := []model.Expense{
+		{Amount: decimal.NewFromInt(100)},
+		{Amount: decimal.NewFromInt(10), Currency: "USD"},
+		{Amount: decimal.NewFromInt(10), Currency: "USD"},
+		{Amount: decimal.NewFromInt(5), Currency: "EUR"},
+		{Amount: decimal.NewFromInt(7), Currency: "GBP"},
+	}
+
+	for i := range fixtures {
+		fixtures[i].ID = uuid.New()
+		fixtures[i].Category = model.CategoryMaterials
+		fixtures[i].PaymentType = model.PaymentTypeCard
+		fixtures[i].CreatedAt = base.AddDate(0, 0, i)
+		fixtures[i].UpdatedAt = base.AddDate(0, 0, i)
+
+		require.NoError(t, srv.Insert(ctx, fixtures[i]), "insert failed")
+	}
+
+	t.Run("project currency by default", func(t *testing.T) {
+		expense, err := srv.Get(ctx, fixtures[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.DefaultCurrency, expense.Currency)
+	})
+
+	t.Run("converted at the rate on the expense date", func(t *testing.T) {
+		report, err := srv.Report(ctx, database.ExpenseFilter{}, 0)
+		require.NoError(t, err)
+
+		// 100 + 10×80 + 10×90 + 5×100, GBP без курса не считается.
+		assert.True(t, report.Total.Amount.Equal(decimal.NewFromInt(2300)), "total: %s", report.Total.Amount)
+		assert.Equal(t, 5, report.Total.Count)
+		assert.Equal(t, []string{"GBP"}, report.MissingRates)
+	})
+
+	t.Run("top sorted by converted amount", func(t *testing.T) {
+		report, err := srv.Report(ctx, database.ExpenseFilter{}, len(fixtures))
+		require.NoError(t, err)
+
+		// 10 USD×90, 10 USD×80, 5 EUR×100, 100 RUB, GBP без курса — в конце.
+		want := []model.ExpenseID{fixtures[2].ID, fixtures[1].ID, fixtures[3].ID, fixtures[0].ID, fixtures[4].ID}
+		got := make([]model.ExpenseID, 0, len(report.Top))
+
+		for _, e := range report.Top {
+			got = append(got, e.ID)
+		}
+
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("latest rates", func(t *testing.T) {
+		rates, err := srv.LatestRates(ctx, "RUB")
+		require.NoError(t, err)
+		require.Len(t, rates, 1)
+		assert.Equal(t, "USD", rates[0].Currency)
+		assert.True(t, rates[0].Rate.Equal(decimal.NewFromInt(90)))
+	})
+
+	t.Run("invalid rate", func(t *testing.T) {
+		err := srv.SetRates(ctx, []model.ExchangeRate{{Date: base, Currency: "USD", Base: "RUB"}})
+		require.ErrorIs(t, err, model.ErrInvalidRate)
+
+		err = srv.SetRates(ctx, []model.ExchangeRate{{Date: base, Currency: "доллар", Base: "RUB", Rate: decimal.NewFromInt(1)}})
+		require.ErrorIs(t, err, model.ErrInvalidCurrency)
+	})
+}
+
+func TestExchangeRatesLocalDate(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_rates_local.db"
+	defer os.Remove(tmpFile)
+
+	loc, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	srv, err := database.New(ctx, tmpFile, loc)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	require.NoError(t, srv.SetRates(ctx, []model.ExchangeRate{
+		{Date: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), Currency: "USD", Base: "RUB", Rate: decimal.NewFromInt(80)},
+		{Date: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC), Currency: "USD", Base: "RUB", Rate: decimal.NewFromInt(90)},
+	}))
+
+	// 22:00 UTC 1 мая — уже 01:00 2 мая по Москве, курс должен быть за 2 мая.
+	createdAt := time.Date(2025, 5, 1, 22, 0, 0, 0, time.UTC)
+
+	require.NoError(t, srv.Insert(ctx, model.Expense{
+		ID:          uuid.New(),
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		Category:    model.CategoryMaterials,
+		PaymentType: model.PaymentTypeCard,
+		Amount:      decimal.NewFromInt(10),
+		Currency:    "USD",
+	}))
+
+	total, err := srv.Total(ctx, database.ExpenseFilter{})
+	require.NoError(t, err)
+	assert.True(t, total.Amount.Equal(decimal.NewFromInt(900)), "total: %s", total.Amount)
+}
+
+func TestNewRejectsUnnamedLocation(t *testing.T) {
+	tmpFile := "test_rates_fixed_zone.db"
+	defer os.Remove(tmpFile)
+
+	_, err := database.New(context.Background(), tmpFile, time.FixedZone("UTC+3", 3*60*60))
+	require.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"kudadeli/model"
+)
+
+func scanRecurring(row scanner) (model.RecurringExpense, error) {
+	var (
+		r                         model.RecurringExpense
+		categoryID, paymentTypeID int
+		amount, nextRun           string
+		createdAt                 string
+	)
+
+	err := row.Scan(&r.ID, &r.ProjectID, &r.UserID, &r.Schedule, &categoryID, &paymentTypeID, &amount,
+		&r.Currency, &r.Description, &r.CatchUp, &r.Paused, &nextRun, &createdAt)
+	if err != nil {
+		return model.RecurringExpense{}, fmt.Errorf("row scan: %w", err)
+	}
+
+	r.Category = model.Category(categoryID)          //nolint:gosec
+	r.PaymentType = model.PaymentType(paymentTypeID) //nolint:gosec
+
+	r.Amount, err = decimal.NewFromString(amount)
+	if err != nil {
+		return model.RecurringExpense{}, fmt.Errorf("parse amount: %w", err)
+	}
+
+	r.NextRun, err = time.Parse(time.RFC3339, nextRun)
+	if err != nil {
+		return model.RecurringExpense{}, fmt.Errorf("parse next run: %w", err)
+	}
+
+	r.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return model.RecurringExpense{}, fmt.Errorf("parse created at: %w", err)
+	}
+
+	return r, nil
+}
+
+func (s *Service) queryRecurring(ctx context.Context, query string, args ...any) ([]model.RecurringExpense, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select recurring expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []model.RecurringExpense
+
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return templates, nil
+}
+
+// CreateRecurring сохраняет шаблон повторяющейся траты. Первое срабатывание NextRun
+// считает вызывающий: расписание база не разбирает.
+func (s *Service) CreateRecurring(ctx context.Context, r model.RecurringExpense) (model.RecurringExpense, error) {
+	r.ProjectID = projectOrDefault(r.ProjectID)
+	r.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if r.Currency == "" {
+			var err error
+
+			r.Currency, err = expenseCurrency(ctx, tx, model.Expense{ProjectID: r.ProjectID})
+			if err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, insertRecurring, r.ProjectID, r.UserID, r.Schedule, int(r.Category),
+			int(r.PaymentType), r.Amount.String(), r.Currency, r.Description, r.CatchUp, r.Paused,
+			r.NextRun.UTC().Format(time.RFC3339), r.CreatedAt.Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("insert recurring expense: %w", err)
+		}
+
+		r.ID, err = res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("last insert id: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return model.RecurringExpense{}, err
+	}
+
+	return r, nil
+}
+
+// Recurring возвращает шаблон по ID или model.ErrNotFound.
+func (s *Service) Recurring(ctx context.Context, id model.RecurringID) (model.RecurringExpense, error) {
+	r, err := scanRecurring(s.db.QueryRowContext(ctx, selectRecurring, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.RecurringExpense{}, model.ErrNotFound
+	}
+
+	return r, err
+}
+
+// ProjectRecurring возвращает шаблоны проекта, включая приостановленные.
+func (s *Service) ProjectRecurring(ctx context.Context, projectID model.ProjectID) ([]model.RecurringExpense, error) {
+	return s.queryRecurring(ctx, selectProjectRecurring, projectID)
+}
+
+// DueRecurring возвращает действующие шаблоны, срабатывание которых наступило к now.
+func (s *Service) DueRecurring(ctx context.Context, now time.Time) ([]model.RecurringExpense, error) {
+	return s.queryRecurring(ctx, selectDueRecurring, now.UTC().Format(time.RFC3339))
+}
+
+// UpdateRecurring сохраняет расписание, настройки и следующее срабатывание шаблона.
+func (s *Service) UpdateRecurring(ctx context.Context, r model.RecurringExpense) error {
+	res, err := s.db.ExecContext(ctx, updateRecurring, r.Schedule, r.CatchUp, r.Paused,
+		r.NextRun.UTC().Format(time.RFC3339), r.ID)
+	if err != nil {
+		return fmt.Errorf("update recurring expense: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// SetRecurringNextRun переносит следующее срабатывание шаблона.
+func (s *Service) SetRecurringNextRun(ctx context.Context, id model.RecurringID, next time.Time) error {
+	res, err := s.db.ExecContext(ctx, updateRecurringNextRun, next.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return fmt.Errorf("update recurring next run: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// DeleteRecurring удаляет шаблон. Уже записанные по нему траты остаются.
+func (s *Service) DeleteRecurring(ctx context.Context, id model.RecurringID) error {
+	res, err := s.db.ExecContext(ctx, deleteRecurring, id)
+	if err != nil {
+		return fmt.Errorf("delete recurring expense: %w", err)
+	}
+
+	return checkAffected(res)
+}
@@ -0,0 +1,97 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestRecurring(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_recurring.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	weekly, err := srv.CreateRecurring(ctx, model.RecurringExpense{
+		UserID:      1,
+		Schedule:    "0 9 * * 5",
+		Category:    model.CategoryLabor,
+		PaymentType: model.PaymentTypeCash,
+		Amount:      decimal.NewFromInt(15000),
+		Description: "прораб",
+		NextRun:     now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.DefaultCurrency, weekly.Currency, "project currency by default")
+
+	_, err = srv.CreateRecurring(ctx, model.RecurringExpense{
+		UserID:      1,
+		Schedule:    "@monthly",
+		Category:    model.CategoryUnexpected,
+		PaymentType: model.PaymentTypeCard,
+		Amount:      decimal.NewFromInt(5000),
+		Currency:    "EUR",
+		NextRun:     now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	t.Run("due", func(t *testing.T) {
+		due, err := srv.DueRecurring(ctx, now)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, weekly.ID, due[0].ID)
+		assert.True(t, decimal.NewFromInt(15000).Equal(due[0].Amount))
+
+		require.NoError(t, srv.SetRecurringNextRun(ctx, weekly.ID, now.AddDate(0, 0, 7)))
+
+		due, err = srv.DueRecurring(ctx, now)
+		require.NoError(t, err)
+		assert.Empty(t, due)
+	})
+
+	t.Run("paused is not due", func(t *testing.T) {
+		got, err := srv.Recurring(ctx, weekly.ID)
+		require.NoError(t, err)
+
+		got.Paused = true
+		require.NoError(t, srv.UpdateRecurring(ctx, got))
+
+		due, err := srv.DueRecurring(ctx, now.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, "EUR", due[0].Currency)
+	})
+
+	t.Run("same run same expense", func(t *testing.T) {
+		at := now.Add(-time.Hour)
+
+		assert.Equal(t, weekly.Expense(at, now).ID, weekly.Expense(at, now.Add(time.Minute)).ID)
+		assert.NotEqual(t, weekly.Expense(at, now).ID, weekly.Expense(at.AddDate(0, 0, 7), now).ID)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, srv.DeleteRecurring(ctx, weekly.ID))
+
+		_, err := srv.Recurring(ctx, weekly.ID)
+		require.ErrorIs(t, err, model.ErrNotFound)
+
+		templates, err := srv.ProjectRecurring(ctx, model.DefaultProjectID)
+		require.NoError(t, err)
+		assert.Len(t, templates, 1)
+	})
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"kudadeli/model"
+)
+
+type groupTotal[K any] struct {
+	key K
+	model.Total
+}
+
+// groupTotals суммирует траты по фильтру, группируя по выражению keyExpr.
+// Группы отсортированы по убыванию суммы.
+func groupTotals[K any](ctx context.Context, s *Service, keyExpr string, filter ExpenseFilter) ([]groupTotal[K], error) {
+	b := filter.where()
+
+	query := "SELECT " + keyExpr + ", " + s.sumAmountCents() + ", COUNT(*) FROM expenses" + b.String() +
+		" GROUP BY 1 ORDER BY 2 DESC, 1"
+
+	rows, err := s.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("select totals by %s: %w", keyExpr, err)
+	}
+	defer rows.Close()
+
+	var totals []groupTotal[K]
+
+	for rows.Next() {
+		var (
+			total groupTotal[K]
+			cents int64
+		)
+
+		err := rows.Scan(&total.key, &cents, &total.Count)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		total.Amount = centsToDecimal(cents)
+		totals = append(totals, total)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return totals, nil
+}
+
+// Total возвращает общую сумму и количество трат по фильтру.
+func (s *Service) Total(ctx context.Context, filter ExpenseFilter) (model.Total, error) {
+	var (
+		total model.Total
+		cents int64
+	)
+
+	b := filter.where()
+	query := "SELECT " + s.sumAmountCents() + ", COUNT(*) FROM expenses" + b.String()
+
+	err := s.db.QueryRowContext(ctx, query, b.args...).Scan(&cents, &total.Count)
+	if err != nil {
+		return model.Total{}, fmt.Errorf("select total: %w", err)
+	}
+
+	total.Amount = centsToDecimal(cents)
+
+	return total, nil
+}
+
+// Report собирает сводку по фильтру: итог, разбивку по категориям, типам оплаты
+// и пользователям и top самых крупных трат. Сортировка и пагинация фильтра не учитываются.
+func (s *Service) Report(ctx context.Context, filter ExpenseFilter, top int) (model.Report, error) {
+	report := model.Report{From: filter.From, To: filter.To}
+
+	var err error
+
+	report.Total, err = s.Total(ctx, filter)
+	if err != nil {
+		return model.Report{}, err
+	}
+
+	byCategory, err := groupTotals[int](ctx, s, "category_id", filter)
+	if err != nil {
+		return model.Report{}, err
+	}
+
+	for _, t := range byCategory {
+		report.ByCategory = append(report.ByCategory, model.CategoryTotal{
+			Category: model.Category(t.key), //nolint:gosec
+			Total:    t.Total,
+		})
+	}
+
+	byPaymentType, err := groupTotals[int](ctx, s, "payment_type_id", filter)
+	if err != nil {
+		return model.Report{}, err
+	}
+
+	for _, t := range byPaymentType {
+		report.ByPaymentType = append(report.ByPaymentType, model.PaymentTypeTotal{
+			PaymentType: model.PaymentType(t.key), //nolint:gosec
+			Total:       t.Total,
+		})
+	}
+
+	byUser, err := groupTotals[int64](ctx, s, "user_id", filter)
+	if err != nil {
+		return model.Report{}, err
+	}
+
+	for _, t := range byUser {
+		report.ByUser = append(report.ByUser, model.UserTotal{UserID: t.key, Total: t.Total})
+	}
+
+	report.MissingRates, err = s.missingRates(ctx, filter)
+	if err != nil {
+		return model.Report{}, err
+	}
+
+	if top > 0 {
+		topFilter := filter
+		// Траты в разных валютах сравниваем по сумме в валюте проекта.
+		topFilter.Sort = SortByProjectAmount
+		topFilter.Asc = false
+		topFilter.Limit = top
+		topFilter.Cursor = ""
+
+		report.Top, _, err = s.Find(ctx, topFilter)
+		if err != nil {
+			return model.Report{}, err
+		}
+	}
+
+	return report, nil
+}
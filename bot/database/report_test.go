@@ -0,0 +1,89 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestReport(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_report.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	base := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	fixtures := []model.Expense{
+		{Category: model.CategoryMaterials, PaymentType: model.PaymentTypeCash, Amount: decimal.RequireFromString("100.10")},
+		{Category: model.CategoryMaterials, PaymentType: model.PaymentTypeCard, Amount: decimal.RequireFromString("0.20")},
+		{Category: model.CategoryLabor, PaymentType: model.PaymentTypeCash, Amount: decimal.NewFromInt(5000)},
+		{Category: model.CategoryTools, PaymentType: model.PaymentTypeCard, Amount: decimal.NewFromInt(700)},
+	}
+
+	for i := range fixtures {
+		fixtures[i].ID = uuid.New()
+		fixtures[i].CreatedAt = base.AddDate(0, 0, i)
+		fixtures[i].UpdatedAt = base.AddDate(0, 0, i)
+
+		require.NoError(t, srv.Insert(ctx, fixtures[i]), "insert failed")
+	}
+
+	t.Run("All time", func(t *testing.T) {
+		report, err := srv.Report(ctx, database.ExpenseFilter{}, 2)
+		require.NoError(t, err)
+
+		assert.Equal(t, 4, report.Total.Count)
+		assert.True(t, report.Total.Amount.Equal(decimal.RequireFromString("5800.30")), "total: %s", report.Total.Amount)
+
+		require.Len(t, report.ByCategory, 3)
+		assert.Equal(t, model.CategoryLabor, report.ByCategory[0].Category)
+		assert.Equal(t, model.CategoryMaterials, report.ByCategory[2].Category)
+		assert.Equal(t, 2, report.ByCategory[2].Count)
+		assert.True(t, report.ByCategory[2].Amount.Equal(decimal.RequireFromString("100.30")))
+
+		require.Len(t, report.ByPaymentType, 2)
+		assert.Equal(t, model.PaymentTypeCash, report.ByPaymentType[0].PaymentType)
+		assert.True(t, report.ByPaymentType[0].Amount.Equal(decimal.RequireFromString("5100.10")))
+
+		require.Len(t, report.ByUser, 1)
+		assert.Equal(t, 4, report.ByUser[0].Count)
+
+		require.Len(t, report.Top, 2)
+		assert.Equal(t, fixtures[2].ID, report.Top[0].ID)
+		assert.Equal(t, fixtures[3].ID, report.Top[1].ID)
+	})
+
+	t.Run("Period", func(t *testing.T) {
+		report, err := srv.Report(ctx, database.ExpenseFilter{From: base, To: base.AddDate(0, 0, 2)}, 5)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, report.Total.Count)
+		require.Len(t, report.ByCategory, 1)
+		assert.Equal(t, model.CategoryMaterials, report.ByCategory[0].Category)
+	})
+
+	t.Run("Empty period", func(t *testing.T) {
+		report, err := srv.Report(ctx, database.ExpenseFilter{From: base.AddDate(1, 0, 0)}, 5)
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, report.Total.Count)
+		assert.True(t, report.Total.Amount.IsZero())
+		assert.Empty(t, report.ByCategory)
+		assert.Empty(t, report.Top)
+	})
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"kudadeli/model"
+)
+
+// SetShares задает доли участников проекта в общих тратах: {1: 70, 2: 30} — 70/30.
+// Участники без доли в расчетах не участвуют, пустые доли — делить поровну.
+// Если кто-то из пользователей не участник проекта, возвращает model.ErrNotFound.
+func (s *Service) SetShares(ctx context.Context, projectID model.ProjectID, shares map[int64]int64) error {
+	for userID, share := range shares {
+		if share <= 0 {
+			return fmt.Errorf("%w: %d for user %d", model.ErrInvalidShare, share, userID)
+		}
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, resetShares, projectID)
+		if err != nil {
+			return fmt.Errorf("reset shares: %w", err)
+		}
+
+		for userID, share := range shares {
+			res, err := tx.ExecContext(ctx, updateShare, share, projectID, userID)
+			if err != nil {
+				return fmt.Errorf("update share: %w", err)
+			}
+
+			err = checkAffected(res)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Shares возвращает заданные доли участников проекта.
+func (s *Service) Shares(ctx context.Context, projectID model.ProjectID) (map[int64]int64, error) {
+	rows, err := s.db.QueryContext(ctx, selectShares, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("select shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := make(map[int64]int64)
+
+	for rows.Next() {
+		var userID, share int64
+
+		err := rows.Scan(&userID, &share)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		shares[userID] = share
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return shares, nil
+}
+
+// Settlement считает взаиморасчеты участников проекта projectID по тратам из filter,
+// см. model.Settle. Проекты фильтра заменяются на projectID.
+func (s *Service) Settlement(ctx context.Context, projectID model.ProjectID,
+	filter ExpenseFilter) (model.Settlement, error) {
+	filter.ProjectIDs = []model.ProjectID{projectID}
+
+	byUser, err := groupTotals[int64](ctx, s, "user_id", filter)
+	if err != nil {
+		return model.Settlement{}, err
+	}
+
+	paid := make([]model.UserTotal, 0, len(byUser))
+
+	for _, t := range byUser {
+		paid = append(paid, model.UserTotal{UserID: t.key, Total: t.Total})
+	}
+
+	shares, err := s.Shares(ctx, projectID)
+	if err != nil {
+		return model.Settlement{}, err
+	}
+
+	settlement := model.Settlement{From: filter.From, To: filter.To}
+	settlement.Balances, settlement.Transfers = model.Settle(paid, shares)
+
+	for _, b := range settlement.Balances {
+		settlement.Total = settlement.Total.Add(b.Paid)
+	}
+
+	settlement.MissingRates, err = s.missingRates(ctx, filter)
+	if err != nil {
+		return model.Settlement{}, err
+	}
+
+	return settlement, nil
+}
@@ -0,0 +1,124 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestSettlement(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_settle.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, userID := range []int64{1, 2, 3} {
+		require.NoError(t, srv.AddProjectMember(ctx, model.DefaultProjectID, userID))
+	}
+
+	for i, paid := range []struct {
+		userID int64
+		amount int64
+	}{{1, 7000}, {2, 2000}, {1, 1000}} {
+		require.NoError(t, srv.Insert(ctx, model.Expense{
+			ID:          uuid.New(),
+			CreatedAt:   base.AddDate(0, 0, i),
+			UpdatedAt:   base.AddDate(0, 0, i),
+			Category:    model.CategoryMaterials,
+			PaymentType: model.PaymentTypeCard,
+			Amount:      decimal.NewFromInt(paid.amount),
+			UserID:      paid.userID,
+		}))
+	}
+
+	amount := func(s string) decimal.Decimal {
+		return decimal.RequireFromString(s)
+	}
+
+	t.Run("equal by default", func(t *testing.T) {
+		s, err := srv.Settlement(ctx, model.DefaultProjectID, database.ExpenseFilter{})
+		require.NoError(t, err)
+
+		assert.True(t, amount("10000").Equal(s.Total))
+		require.Len(t, s.Balances, 2)
+		assert.True(t, amount("5000").Equal(s.Balances[0].Owed))
+
+		require.Len(t, s.Transfers, 1)
+		assert.Equal(t, int64(2), s.Transfers[0].From)
+		assert.Equal(t, int64(1), s.Transfers[0].To)
+		assert.True(t, amount("3000").Equal(s.Transfers[0].Amount))
+	})
+
+	t.Run("70/30", func(t *testing.T) {
+		require.NoError(t, srv.SetShares(ctx, model.DefaultProjectID, map[int64]int64{1: 70, 2: 30}))
+
+		s, err := srv.Settlement(ctx, model.DefaultProjectID, database.ExpenseFilter{})
+		require.NoError(t, err)
+
+		require.Len(t, s.Transfers, 1)
+		assert.Equal(t, int64(2), s.Transfers[0].From)
+		assert.True(t, amount("1000").Equal(s.Transfers[0].Amount))
+	})
+
+	t.Run("share without expenses and rounding", func(t *testing.T) {
+		require.NoError(t, srv.SetShares(ctx, model.DefaultProjectID, map[int64]int64{1: 1, 2: 1, 3: 1}))
+
+		shares, err := srv.Shares(ctx, model.DefaultProjectID)
+		require.NoError(t, err)
+		assert.Len(t, shares, 3)
+
+		s, err := srv.Settlement(ctx, model.DefaultProjectID, database.ExpenseFilter{})
+		require.NoError(t, err)
+
+		owed := decimal.Zero
+		for _, b := range s.Balances {
+			owed = owed.Add(b.Owed)
+		}
+
+		assert.True(t, s.Total.Equal(owed), "owed %s", owed)
+
+		require.Len(t, s.Transfers, 2)
+		assert.Equal(t, int64(3), s.Transfers[0].From)
+		assert.True(t, amount("3333.33").Equal(s.Transfers[0].Amount))
+		assert.Equal(t, int64(2), s.Transfers[1].From)
+		assert.True(t, amount("1333.33").Equal(s.Transfers[1].Amount))
+	})
+
+	t.Run("period", func(t *testing.T) {
+		s, err := srv.Settlement(ctx, model.DefaultProjectID, database.ExpenseFilter{From: base.AddDate(0, 0, 1)})
+		require.NoError(t, err)
+		assert.True(t, amount("3000").Equal(s.Total))
+	})
+
+	t.Run("invalid share", func(t *testing.T) {
+		err := srv.SetShares(ctx, model.DefaultProjectID, map[int64]int64{1: 0})
+		require.ErrorIs(t, err, model.ErrInvalidShare)
+
+		err = srv.SetShares(ctx, model.DefaultProjectID, map[int64]int64{1: 50, 4: 50})
+		require.ErrorIs(t, err, model.ErrNotFound, "not a project member")
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		require.NoError(t, srv.SetShares(ctx, model.DefaultProjectID, nil))
+
+		shares, err := srv.Shares(ctx, model.DefaultProjectID)
+		require.NoError(t, err)
+		assert.Empty(t, shares)
+	})
+}
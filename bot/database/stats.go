@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kudadeli/model"
+)
+
+// slotExpr округляет created_at до 15 минут в UTC. SQLite не знает часовых поясов,
+// а все реальные смещения кратны 15 минутам, поэтому слоты можно без потерь
+// разложить по дням, неделям и месяцам в нужном поясе.
+const slotExpr = `strftime('%Y-%m-%dT%H:', created_at) || ` +
+	`printf('%02d', CAST(strftime('%M', created_at) AS INTEGER) / 15 * 15) || ':00Z'`
+
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+
+	return values
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)) //nolint:mnd
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// foldPeriods складывает слоты в периоды, начало которых вычисляет start. Слоты
+// отсортированы по времени, поэтому периоды получаются в хронологическом порядке.
+func foldPeriods(slots []groupTotal[time.Time], start func(time.Time) time.Time) []model.PeriodTotal {
+	var periods []model.PeriodTotal
+
+	for _, slot := range slots {
+		key := start(slot.key)
+
+		if n := len(periods); n > 0 && periods[n-1].Start.Equal(key) {
+			periods[n-1].Amount = periods[n-1].Amount.Add(slot.Amount)
+			periods[n-1].Count += slot.Count
+
+			continue
+		}
+
+		periods = append(periods, model.PeriodTotal{Start: key, Total: slot.Total})
+	}
+
+	return periods
+}
+
+func (s *Service) timeSlots(ctx context.Context, filter ExpenseFilter, loc *time.Location) ([]groupTotal[time.Time], error) {
+	b := filter.where()
+
+	query := "SELECT " + slotExpr + ", " + s.sumAmountCents() + ", COUNT(*) FROM expenses" + b.String() +
+		" GROUP BY 1 ORDER BY 1"
+
+	rows, err := s.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("select time slots: %w", err)
+	}
+	defer rows.Close()
+
+	var slots []groupTotal[time.Time]
+
+	for rows.Next() {
+		var (
+			slot  groupTotal[time.Time]
+			key   string
+			cents int64
+		)
+
+		err := rows.Scan(&key, &cents, &slot.Count)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		slot.key, err = time.Parse(time.RFC3339, key)
+		if err != nil {
+			return nil, fmt.Errorf("parse time slot: %w", err)
+		}
+
+		slot.key = slot.key.In(loc)
+		slot.Amount = centsToDecimal(cents)
+		slots = append(slots, slot)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return slots, nil
+}
+
+// Stats собирает агрегаты по фильтру: итоги по категориям, типам оплаты,
+// пользователям и по дням, неделям и месяцам в часовом поясе loc. Суммы — в валюте
+// проекта, Currency заполняет вызывающий.
+func (s *Service) Stats(ctx context.Context, filter ExpenseFilter, loc *time.Location) (model.Stats, error) {
+	report, err := s.Report(ctx, filter, 0)
+	if err != nil {
+		return model.Stats{}, err
+	}
+
+	stats := model.Stats{
+		From:          filter.From,
+		To:            filter.To,
+		Timezone:      loc.String(),
+		Total:         report.Total,
+		ByCategory:    nonNil(report.ByCategory),
+		ByPaymentType: nonNil(report.ByPaymentType),
+		ByUser:        nonNil(report.ByUser),
+		MissingRates:  report.MissingRates,
+	}
+
+	slots, err := s.timeSlots(ctx, filter, loc)
+	if err != nil {
+		return model.Stats{}, err
+	}
+
+	stats.ByDay = nonNil(foldPeriods(slots, startOfDay))
+	stats.ByWeek = nonNil(foldPeriods(slots, startOfWeek))
+	stats.ByMonth = nonNil(foldPeriods(slots, startOfMonth))
+
+	return stats, nil
+}
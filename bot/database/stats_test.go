@@ -0,0 +1,78 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_stats.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	fixtures := []struct {
+		createdAt time.Time
+		amount    int64
+		userID    int64
+	}{
+		{createdAt: time.Date(2025, 5, 30, 10, 0, 0, 0, time.UTC), amount: 100, userID: 1},
+		{createdAt: time.Date(2025, 5, 31, 20, 0, 0, 0, time.UTC), amount: 200, userID: 2},
+		// 01:30 1 июня по Москве
+		{createdAt: time.Date(2025, 5, 31, 22, 30, 0, 0, time.UTC), amount: 300, userID: 1},
+		{createdAt: time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC), amount: 400, userID: 1},
+	}
+
+	for _, f := range fixtures {
+		err := srv.Insert(ctx, model.Expense{
+			ID:          uuid.New(),
+			CreatedAt:   f.createdAt,
+			UpdatedAt:   f.createdAt,
+			Category:    model.CategoryMaterials,
+			PaymentType: model.PaymentTypeCash,
+			Amount:      decimal.NewFromInt(f.amount),
+			UserID:      f.userID,
+		})
+		require.NoError(t, err, "insert failed")
+	}
+
+	loc, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	stats, err := srv.Stats(ctx, database.ExpenseFilter{}, loc)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, stats.Total.Count)
+	assert.Equal(t, "Europe/Moscow", stats.Timezone)
+
+	require.Len(t, stats.ByUser, 2)
+	assert.Equal(t, int64(1), stats.ByUser[0].UserID)
+	assert.True(t, stats.ByUser[0].Amount.Equal(decimal.NewFromInt(800)))
+
+	require.Len(t, stats.ByMonth, 2)
+	assert.True(t, stats.ByMonth[0].Start.Equal(time.Date(2025, 5, 1, 0, 0, 0, 0, loc)))
+	assert.True(t, stats.ByMonth[0].Amount.Equal(decimal.NewFromInt(300)))
+	assert.True(t, stats.ByMonth[1].Amount.Equal(decimal.NewFromInt(700)))
+
+	require.Len(t, stats.ByDay, 4)
+
+	// 30 и 31 мая — пятница и суббота, 1 июня — воскресенье, 2 июня — понедельник.
+	require.Len(t, stats.ByWeek, 2)
+	assert.True(t, stats.ByWeek[0].Start.Equal(time.Date(2025, 5, 26, 0, 0, 0, 0, loc)))
+	assert.Equal(t, 3, stats.ByWeek[0].Count)
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kudadeli/model"
+)
+
+const (
+	apiTokenPrefix = "kd_"
+	apiTokenBytes  = 32
+)
+
+// hashAPIToken — SHA-256 от секрета. Секрет случайный и длинный, поэтому медленный
+// хеш для паролей здесь не нужен.
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func scanAPIToken(row scanner) (model.APIToken, error) {
+	var (
+		token            model.APIToken
+		scope, createdAt string
+		expiresAt        sql.NullString
+	)
+
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &scope, &createdAt, &expiresAt)
+	if err != nil {
+		return model.APIToken{}, fmt.Errorf("row scan: %w", err)
+	}
+
+	token.Scope = model.TokenScope(scope)
+
+	token.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return model.APIToken{}, fmt.Errorf("parse created at: %w", err)
+	}
+
+	if expiresAt.Valid {
+		t, err := time.Parse(time.RFC3339, expiresAt.String)
+		if err != nil {
+			return model.APIToken{}, fmt.Errorf("parse expires at: %w", err)
+		}
+
+		token.ExpiresAt = &t
+	}
+
+	return token, nil
+}
+
+// CreateAPIToken выпускает токен пользователю и возвращает его вместе с секретом.
+// Секрет больше нигде не сохраняется, показать его повторно нельзя.
+func (s *Service) CreateAPIToken(ctx context.Context, userID int64, name string, scope model.TokenScope,
+	expiresAt *time.Time) (model.APIToken, string, error) {
+	raw := make([]byte, apiTokenBytes)
+
+	_, err := rand.Read(raw)
+	if err != nil {
+		return model.APIToken{}, "", fmt.Errorf("generate token: %w", err)
+	}
+
+	secret := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	token := model.APIToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Scope:     scope,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	var expires sql.NullString
+
+	if expiresAt != nil {
+		t := expiresAt.UTC().Truncate(time.Second)
+		token.ExpiresAt = &t
+		expires = sql.NullString{String: t.Format(time.RFC3339), Valid: true}
+	}
+
+	res, err := s.db.ExecContext(ctx, insertAPIToken, token.UserID, token.Name, hashAPIToken(secret),
+		string(token.Scope), token.CreatedAt.Format(time.RFC3339), expires)
+	if err != nil {
+		return model.APIToken{}, "", fmt.Errorf("insert api token: %w", err)
+	}
+
+	token.ID, err = res.LastInsertId()
+	if err != nil {
+		return model.APIToken{}, "", fmt.Errorf("last insert id: %w", err)
+	}
+
+	return token, secret, nil
+}
+
+// APIToken находит действующий токен по секрету. Неизвестный и истекший токены —
+// model.ErrNotFound.
+func (s *Service) APIToken(ctx context.Context, secret string) (model.APIToken, error) {
+	token, err := scanAPIToken(s.db.QueryRowContext(ctx, selectAPITokenByHash, hashAPIToken(secret)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.APIToken{}, model.ErrNotFound
+		}
+
+		return model.APIToken{}, err
+	}
+
+	if token.Expired(time.Now()) {
+		return model.APIToken{}, model.ErrNotFound
+	}
+
+	return token, nil
+}
+
+// UserAPITokens возвращает токены пользователя, включая истекшие.
+func (s *Service) UserAPITokens(ctx context.Context, userID int64) ([]model.APIToken, error) {
+	rows, err := s.db.QueryContext(ctx, selectUserAPITokens, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []model.APIToken
+
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken удаляет токен пользователя. Чужой или неизвестный токен — model.ErrNotFound.
+func (s *Service) RevokeAPIToken(ctx context.Context, userID, id int64) error {
+	res, err := s.db.ExecContext(ctx, deleteAPIToken, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete api token: %w", err)
+	}
+
+	return checkAffected(res)
+}
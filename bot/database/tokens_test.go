@@ -0,0 +1,67 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestAPITokens(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_tokens.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	token, secret, err := srv.CreateAPIToken(ctx, 1, "дом", model.ScopeRead, &expiresAt)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "kd_"))
+	assert.Equal(t, model.ScopeRead, token.Scope)
+
+	t.Run("lookup by secret", func(t *testing.T) {
+		got, err := srv.APIToken(ctx, secret)
+		require.NoError(t, err)
+		assert.Equal(t, token.ID, got.ID)
+		assert.Equal(t, int64(1), got.UserID)
+		require.NotNil(t, got.ExpiresAt)
+
+		_, err = srv.APIToken(ctx, secret+"x")
+		require.ErrorIs(t, err, model.ErrNotFound)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+
+		_, expired, err := srv.CreateAPIToken(ctx, 1, "старый", model.ScopeWrite, &past)
+		require.NoError(t, err)
+
+		_, err = srv.APIToken(ctx, expired)
+		require.ErrorIs(t, err, model.ErrNotFound)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		tokens, err := srv.UserAPITokens(ctx, 1)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 2)
+
+		require.ErrorIs(t, srv.RevokeAPIToken(ctx, 2, token.ID), model.ErrNotFound, "only the owner revokes")
+		require.NoError(t, srv.RevokeAPIToken(ctx, 1, token.ID))
+
+		_, err = srv.APIToken(ctx, secret)
+		require.ErrorIs(t, err, model.ErrNotFound)
+	})
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kudadeli/model"
+)
+
+// parseDeletedAt разбирает deleted_at: раньше он писался через datetime('now'), теперь — в RFC3339.
+func parseDeletedAt(input string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, input)
+	if err == nil {
+		return t, nil
+	}
+
+	t, err = time.Parse(time.DateTime, input)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse deleted at: %w", err)
+	}
+
+	return t, nil
+}
+
+// GetDeleted возвращает трату из корзины.
+func (s *Service) GetDeleted(ctx context.Context, id model.ExpenseID) (model.Expense, error) {
+	expense, err := scanExpense(s.db.QueryRowContext(ctx, selectDeletedExpense, id.String()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Expense{}, model.ErrNotFound
+		}
+
+		return model.Expense{}, err
+	}
+
+	return expense, nil
+}
+
+// Restore возвращает трату из корзины. ErrNotFound — если трата не удалена или уже очищена.
+func (s *Service) Restore(ctx context.Context, id model.ExpenseID) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var deletedAt sql.NullString
+
+		err := tx.QueryRowContext(ctx, selectDeletedAt, id.String()).Scan(&deletedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("select deleted at: %w", err)
+		}
+
+		res, err := tx.ExecContext(ctx, restoreExpense, time.Now().UTC().Format(time.RFC3339), id.String())
+		if err != nil {
+			return fmt.Errorf("restore expense: %w", err)
+		}
+
+		err = checkAffected(res)
+		if err != nil {
+			return err
+		}
+
+		return recordHistory(ctx, tx, id, model.ActionRestore, []model.FieldChange{
+			{Field: "deletedAt", Old: deletedAt.String, New: ""},
+		})
+	})
+}
+
+// Purge окончательно удаляет траты, попавшие в корзину раньше before, вместе с вложениями.
+// Возвращает число удаленных трат.
+func (s *Service) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		cutoff := before.UTC().Format(time.RFC3339)
+
+		_, err := tx.ExecContext(ctx, purgeAttachments, cutoff)
+		if err != nil {
+			return fmt.Errorf("purge attachments: %w", err)
+		}
+
+		res, err := tx.ExecContext(ctx, purgeExpenses, cutoff)
+		if err != nil {
+			return fmt.Errorf("purge expenses: %w", err)
+		}
+
+		purged, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		}
+
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,128 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_trash.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	// updated_at в прошлом, чтобы было видно, что удаление и восстановление его сдвигают.
+	past := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	newExpense := func(description string) model.Expense {
+		expense := model.Expense{
+			ID:          uuid.New(),
+			CreatedAt:   past,
+			UpdatedAt:   past,
+			Category:    model.CategoryMaterials,
+			PaymentType: model.PaymentTypeCard,
+			Description: description,
+			Amount:      decimal.NewFromInt(100),
+			UserID:      1,
+			ProjectID:   model.DefaultProjectID,
+		}
+		require.NoError(t, srv.Insert(ctx, expense))
+
+		return expense
+	}
+
+	kept := newExpense("краска")
+	removed := newExpense("шпатель")
+
+	// changedAt возвращает время изменения проекта, выждав, чтобы следующее изменение было позже.
+	changedAt := func() time.Time {
+		changed, err := srv.ProjectChangedAt(ctx, model.DefaultProjectID)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		return changed
+	}
+
+	inserted := changedAt()
+	assert.True(t, inserted.After(past), "insert must bump project changed_at: %s", inserted)
+
+	t.Run("Delete moves to trash", func(t *testing.T) {
+		require.NoError(t, srv.Delete(ctx, removed.ID))
+
+		_, err := srv.Get(ctx, removed.ID)
+		require.ErrorIs(t, err, model.ErrNotFound)
+
+		deleted, err := srv.GetDeleted(ctx, removed.ID)
+		require.NoError(t, err)
+		require.NotNil(t, deleted.DeletedAt)
+		assert.Equal(t, "шпатель", deleted.Description)
+
+		_, err = srv.GetDeleted(ctx, kept.ID)
+		require.ErrorIs(t, err, model.ErrNotFound)
+
+		items, _, err := srv.Find(ctx, database.ExpenseFilter{Deleted: true, Sort: database.SortByDeletedAt})
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, removed.ID, items[0].ID)
+
+		assert.True(t, changedAt().After(inserted), "delete must bump project changed_at")
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		require.NoError(t, srv.Restore(ctx, removed.ID))
+		require.ErrorIs(t, srv.Restore(ctx, removed.ID), model.ErrNotFound)
+		require.ErrorIs(t, srv.Restore(ctx, kept.ID), model.ErrNotFound)
+
+		restored, err := srv.Get(ctx, removed.ID)
+		require.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		assert.True(t, restored.UpdatedAt.After(past), "restore must bump updated_at")
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		require.NoError(t, srv.AddAttachment(ctx, model.Attachment{
+			ID:        uuid.New(),
+			ExpenseID: removed.ID,
+			CreatedAt: past,
+			FileName:  "receipt.jpg",
+			MIMEType:  "image/jpeg",
+		}, []byte{0xff}))
+		require.NoError(t, srv.Delete(ctx, removed.ID))
+
+		purged, err := srv.Purge(ctx, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Zero(t, purged, "fresh deletions must stay in trash")
+
+		deleted := changedAt()
+
+		purged, err = srv.Purge(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		assert.True(t, changedAt().After(deleted), "purge must bump project changed_at")
+
+		_, err = srv.GetDeleted(ctx, removed.ID)
+		require.ErrorIs(t, err, model.ErrNotFound)
+
+		attachments, err := srv.Attachments(ctx, removed.ID)
+		require.NoError(t, err)
+		assert.Empty(t, attachments)
+
+		_, err = srv.Get(ctx, kept.ID)
+		require.NoError(t, err, "active expenses are never purged")
+	})
+}
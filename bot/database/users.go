@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kudadeli/model"
+)
+
+// Users возвращает пользователей с доступом в порядке добавления.
+func (s *Service) Users(ctx context.Context) ([]model.User, error) {
+	rows, err := s.db.QueryContext(ctx, selectUsers)
+	if err != nil {
+		return nil, fmt.Errorf("select users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []model.User
+
+	for rows.Next() {
+		var (
+			user            model.User
+			role, createdAt string
+		)
+
+		err := rows.Scan(&user.ID, &role, &user.Name, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("row scan: %w", err)
+		}
+
+		user.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse created at: %w", err)
+		}
+
+		user.Role = model.Role(role)
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return users, nil
+}
+
+// UserRole возвращает роль пользователя или model.ErrNotFound, если доступа у него нет.
+func (s *Service) UserRole(ctx context.Context, userID int64) (model.Role, error) {
+	var role string
+
+	err := s.db.QueryRowContext(ctx, selectUserRole, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", model.ErrNotFound
+		}
+
+		return "", fmt.Errorf("select user role: %w", err)
+	}
+
+	return model.Role(role), nil
+}
+
+// SetUserName запоминает имя пользователя из профиля Telegram. Пользователей без доступа
+// не добавляет.
+func (s *Service) SetUserName(ctx context.Context, userID int64, name string) error {
+	_, err := s.db.ExecContext(ctx, updateUserName, name, userID, name)
+	if err != nil {
+		return fmt.Errorf("update user name: %w", err)
+	}
+
+	return nil
+}
+
+// EnsureOwners делает владельцами пользователей, которых еще нет в базе. Так пользователи
+// из KUDADELI_USERS сохраняют доступ, а роли, выданные через /users, не перезаписываются.
+func (s *Service) EnsureOwners(ctx context.Context, userIDs []int64) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, userID := range userIDs {
+			res, err := tx.ExecContext(ctx, insertOwner, userID, now)
+			if err != nil {
+				return fmt.Errorf("insert owner %d: %w", userID, err)
+			}
+
+			inserted, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("rows affected: %w", err)
+			}
+
+			if inserted > 0 {
+				_, err = tx.ExecContext(ctx, insertProjectMember, model.DefaultProjectID, userID)
+				if err != nil {
+					return fmt.Errorf("insert project member %d: %w", userID, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// checkNotLastOwner не дает лишить доступа последнего владельца: иначе управлять
+// пользователями станет некому.
+func checkNotLastOwner(ctx context.Context, tx *sql.Tx, userID int64) error {
+	var role string
+
+	err := tx.QueryRowContext(ctx, selectUserRole, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && model.Role(role) != model.RoleOwner) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("select user role: %w", err)
+	}
+
+	var others int
+
+	err = tx.QueryRowContext(ctx, selectOtherOwners, userID).Scan(&others)
+	if err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+
+	if others == 0 {
+		return model.ErrLastOwner
+	}
+
+	return nil
+}
+
+// SetUserRole выдает пользователю роль или меняет ее. Новый пользователь становится
+// участником проекта по умолчанию, остальные проекты добавляются через /project add.
+func (s *Service) SetUserRole(ctx context.Context, userID int64, role model.Role) error {
+	if !role.IsValid() {
+		return fmt.Errorf("%w: %q", model.ErrInvalidRole, role)
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if role != model.RoleOwner {
+			err := checkNotLastOwner(ctx, tx, userID)
+			if err != nil {
+				return err
+			}
+		}
+
+		var current string
+
+		err := tx.QueryRowContext(ctx, selectUserRole, userID).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			_, err = tx.ExecContext(ctx, insertProjectMember, model.DefaultProjectID, userID)
+			if err != nil {
+				return fmt.Errorf("insert project member: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("select user role: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, upsertUserRole, userID, string(role), time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("upsert user role: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RemoveUser забирает у пользователя доступ. Участие в проектах остается: вернув роль,
+// пользователь увидит те же проекты.
+func (s *Service) RemoveUser(ctx context.Context, userID int64) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		err := checkNotLastOwner(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, deleteUser, userID)
+		if err != nil {
+			return fmt.Errorf("delete user: %w", err)
+		}
+
+		return checkAffected(res)
+	})
+}
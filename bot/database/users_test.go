@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_users.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	_, err = srv.UserRole(ctx, 1)
+	require.ErrorIs(t, err, model.ErrNotFound)
+
+	t.Run("EnsureOwners keeps assigned roles", func(t *testing.T) {
+		require.NoError(t, srv.EnsureOwners(ctx, []int64{1, 2}))
+		require.NoError(t, srv.SetUserRole(ctx, 2, model.RoleViewer))
+		require.NoError(t, srv.EnsureOwners(ctx, []int64{1, 2}))
+
+		role, err := srv.UserRole(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, model.RoleViewer, role)
+	})
+
+	t.Run("SetUserName", func(t *testing.T) {
+		require.NoError(t, srv.SetUserName(ctx, 1, "Аня"))
+		require.NoError(t, srv.SetUserName(ctx, 100, "Без доступа"))
+
+		users, err := srv.Users(ctx)
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, "Аня", users[0].Name)
+		assert.Empty(t, users[1].Name)
+	})
+
+	t.Run("SetUserRole", func(t *testing.T) {
+		require.NoError(t, srv.SetUserRole(ctx, 3, model.RoleContractor))
+		require.ErrorIs(t, srv.SetUserRole(ctx, 3, model.Role("admin")), model.ErrInvalidRole)
+
+		users, err := srv.Users(ctx)
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+
+		roles := map[int64]model.Role{}
+		for _, user := range users {
+			roles[user.ID] = user.Role
+		}
+
+		assert.Equal(t, map[int64]model.Role{1: model.RoleOwner, 2: model.RoleViewer, 3: model.RoleContractor}, roles)
+	})
+
+	t.Run("last owner stays", func(t *testing.T) {
+		require.ErrorIs(t, srv.SetUserRole(ctx, 1, model.RoleEditor), model.ErrLastOwner)
+		require.ErrorIs(t, srv.RemoveUser(ctx, 1), model.ErrLastOwner)
+
+		require.NoError(t, srv.SetUserRole(ctx, 2, model.RoleOwner))
+		require.NoError(t, srv.SetUserRole(ctx, 1, model.RoleEditor))
+		require.NoError(t, srv.RemoveUser(ctx, 1))
+
+		_, err := srv.UserRole(ctx, 1)
+		require.ErrorIs(t, err, model.ErrNotFound)
+		require.ErrorIs(t, srv.RemoveUser(ctx, 1), model.ErrNotFound)
+	})
+}
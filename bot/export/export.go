@@ -0,0 +1,112 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"kudadeli/model"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+
+	dateLayout = "2006-01-02 15:04:05"
+)
+
+// utf8BOM нужен, чтобы Excel открывал CSV в UTF-8, а не в cp1251.
+const utf8BOM = "\ufeff"
+
+func ParseFormat(input string) (Format, bool) {
+	switch Format(input) {
+	case "", FormatCSV:
+		return FormatCSV, true
+	case FormatXLSX:
+		return FormatXLSX, true
+	default:
+		return "", false
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// FileName возвращает имя файла выгрузки вида kudadeli-20250501-1504.csv.
+func (f Format) FileName(now time.Time) string {
+	return "kudadeli-" + now.Format("20060102-1504") + "." + string(f)
+}
+
+// Header — заголовки колонок выгрузки, в том же порядке, что и Row.
+func Header() []string {
+	return []string{"Дата", "Категория", "Тип оплаты", "Сумма", "Валюта", "Описание", "Пользователь", "ID"}
+}
+
+const amountColumn = 3
+
+// Row возвращает значения колонок траты. Сумма выводится как есть: без ошибок float,
+// без округления до копеек и без пересчета — в своей валюте.
+func Row(e model.Expense, categories *model.CategoryRegistry, loc *time.Location) []string {
+	return []string{
+		e.CreatedAt.In(loc).Format(dateLayout),
+		categories.Name(e.Category),
+		e.PaymentType.String(),
+		e.Amount.String(),
+		e.Currency,
+		e.Description,
+		strconv.FormatInt(e.UserID, 10),
+		e.ID.String(),
+	}
+}
+
+// Write выгружает траты, категории подписываются названиями из categories.
+func Write(w io.Writer, format Format, expenses model.Expenses, categories *model.CategoryRegistry,
+	loc *time.Location) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, expenses, categories, loc)
+	case FormatXLSX:
+		return WriteXLSX(w, expenses, categories, loc)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func WriteCSV(w io.Writer, expenses model.Expenses, categories *model.CategoryRegistry, loc *time.Location) error {
+	_, err := io.WriteString(w, utf8BOM)
+	if err != nil {
+		return fmt.Errorf("write bom: %w", err)
+	}
+
+	cw := csv.NewWriter(w)
+
+	err = cw.Write(Header())
+	if err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for i := range expenses {
+		err := cw.Write(Row(expenses[i], categories, loc))
+		if err != nil {
+			return fmt.Errorf("write row: %w", err)
+		}
+	}
+
+	cw.Flush()
+
+	err = cw.Error()
+	if err != nil {
+		return fmt.Errorf("csv flush: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/export"
+	"kudadeli/model"
+)
+
+func testExpenses() model.Expenses {
+	return model.Expenses{
+		{
+			ID:          uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+			CreatedAt:   time.Date(2025, 5, 31, 22, 30, 0, 0, time.UTC),
+			Category:    model.CategoryMaterials,
+			PaymentType: model.PaymentTypeCard,
+			Description: `краска "белая", 2 банки`,
+			// Ни ошибки float, ни округления до копеек: 0.1 + 0.205 = 0.305.
+			Amount:   decimal.RequireFromString("0.1").Add(decimal.RequireFromString("0.205")),
+			Currency: "RUB",
+			UserID:   42,
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, export.WriteCSV(&buf, testExpenses(), model.NewCategoryRegistry(model.DefaultCategories()), loc))
+
+	want := "\ufeffДата,Категория,Тип оплаты,Сумма,Валюта,Описание,Пользователь,ID\n" +
+		`2025-06-01 01:30:00,материалы,карта,0.305,RUB,"краска ""белая"", 2 банки",42,00000000-0000-0000-0000-000000000001` + "\n"
+	require.Equal(t, want, buf.String())
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+
+	categories := model.NewCategoryRegistry(model.DefaultCategories())
+
+	require.NoError(t, export.WriteXLSX(&buf, testExpenses(), categories, time.UTC))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	var sheet string
+
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		sheet = string(data)
+	}
+
+	require.NotEmpty(t, sheet, "sheet not found")
+	require.True(t, strings.Contains(sheet, `<c t="n"><v>0.305</v></c>`), "amount must be a number cell")
+	require.True(t, strings.Contains(sheet, `краска &#34;белая&#34;, 2 банки`), "description must be escaped")
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"kudadeli/model"
+)
+
+// Минимальный SpreadsheetML: одна страница, строки как inline strings, сумма — число.
+// Этого хватает Excel, LibreOffice и Google Sheets, а тащить зависимость ради выгрузки не хочется.
+const (
+	xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` + //nolint:lll
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` + //nolint:lll
+		`</Types>`
+
+	xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` + //nolint:lll
+		`</Relationships>`
+
+	xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Траты" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` + //nolint:lll
+		`</Relationships>`
+
+	xlsxSheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd   = `</sheetData></worksheet>`
+)
+
+func writeZipFile(zw *zip.Writer, name, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+
+	_, err = io.WriteString(f, content)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func xlsxRow(index int, values []string) string {
+	var sb strings.Builder
+
+	sb.WriteString(`<row r="` + strconv.Itoa(index) + `">`)
+
+	for i, value := range values {
+		// Сумма пишется числом как есть, чтобы по ней работали формулы.
+		if i == amountColumn && index > 1 {
+			sb.WriteString(`<c t="n"><v>` + value + `</v></c>`)
+
+			continue
+		}
+
+		sb.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		_ = xml.EscapeText(&sb, []byte(value)) // strings.Builder не возвращает ошибок
+		sb.WriteString(`</t></is></c>`)
+	}
+
+	sb.WriteString(`</row>`)
+
+	return sb.String()
+}
+
+func WriteXLSX(w io.Writer, expenses model.Expenses, categories *model.CategoryRegistry, loc *time.Location) error {
+	zw := zip.NewWriter(w)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+
+	for _, part := range parts {
+		err := writeZipFile(zw, part.name, part.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("create sheet: %w", err)
+	}
+
+	bw := bufio.NewWriter(f)
+
+	_, err = bw.WriteString(xlsxSheetStart + xlsxRow(1, Header()))
+	if err != nil {
+		return fmt.Errorf("write sheet: %w", err)
+	}
+
+	for i := range expenses {
+		_, err := bw.WriteString(xlsxRow(i+2, Row(expenses[i], categories, loc))) //nolint:mnd
+		if err != nil {
+			return fmt.Errorf("write sheet: %w", err)
+		}
+	}
+
+	_, err = bw.WriteString(xlsxSheetEnd)
+	if err != nil {
+		return fmt.Errorf("write sheet: %w", err)
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("flush sheet: %w", err)
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return fmt.Errorf("close zip: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,419 @@
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+var (
+	ErrEmptyFile       = errors.New("empty file")
+	ErrMissingColumn   = errors.New("missing column")
+	ErrInvalidDate     = errors.New("invalid date")
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrUnknownPayment  = errors.New("unknown payment type")
+	ErrUnknownCategory = errors.New("unknown category")
+	ErrInvalidRate     = errors.New("invalid rate")
+)
+
+type column byte
+
+const (
+	columnDate column = iota
+	columnCategory
+	columnPaymentType
+	columnAmount
+	columnDescription
+	columnUser
+	columnCurrency
+)
+
+// columnNames — допустимые заголовки колонок. Русские совпадают с заголовками выгрузки /export.
+var columnNames = map[string]column{ //nolint:gochecknoglobals
+	"дата":         columnDate,
+	"date":         columnDate,
+	"категория":    columnCategory,
+	"category":     columnCategory,
+	"тип оплаты":   columnPaymentType,
+	"оплата":       columnPaymentType,
+	"payment":      columnPaymentType,
+	"paymenttype":  columnPaymentType,
+	"сумма":        columnAmount,
+	"amount":       columnAmount,
+	"описание":     columnDescription,
+	"description":  columnDescription,
+	"пользователь": columnUser,
+	"user":         columnUser,
+	"userid":       columnUser,
+	"валюта":       columnCurrency,
+	"currency":     columnCurrency,
+}
+
+var dateLayouts = []string{ //nolint:gochecknoglobals
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	"2.1.2006",
+}
+
+// RowError — ошибка в строке файла. Line считается с 1, включая заголовок.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("строка %d: %s", e.Line, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// Row — разобранная строка файла.
+type Row struct {
+	Line    int
+	Expense model.Expense
+}
+
+// Options задают параметры разбора. UserID используется, если в файле нет колонки пользователя.
+// Все траты файла попадают в проект ProjectID.
+type Options struct {
+	Location  *time.Location
+	UserID    int64
+	ProjectID model.ProjectID
+	// Categories — категории, по которым разбирается колонка «Категория». Run берет их из базы.
+	Categories *model.CategoryRegistry
+}
+
+type header map[column]int
+
+func parseHeader(record []string) (header, error) {
+	h := make(header)
+
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, "_", " ")
+
+		if c, ok := columnNames[name]; ok {
+			h[c] = i
+		} else if c, ok := columnNames[strings.ReplaceAll(name, " ", "")]; ok {
+			h[c] = i
+		}
+	}
+
+	for _, required := range []struct {
+		column column
+		name   string
+	}{
+		{columnDate, "Дата"},
+		{columnPaymentType, "Тип оплаты"},
+		{columnAmount, "Сумма"},
+	} {
+		if _, ok := h[required.column]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, required.name)
+		}
+	}
+
+	return h, nil
+}
+
+func (h header) value(record []string, c column) string {
+	i, ok := h[c]
+	if !ok || i >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[i])
+}
+
+func parseDate(input string, loc *time.Location) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, input, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidDate, input)
+}
+
+// parseAmount понимает «1 500,50» и «1500.50».
+func parseAmount(input string) (decimal.Decimal, error) {
+	normalized := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".", "₽", "").Replace(input)
+
+	amount, err := decimal.NewFromString(normalized)
+	if err != nil || !amount.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: %q", ErrInvalidAmount, input)
+	}
+
+	return amount, nil
+}
+
+func parsePaymentType(input string) (model.PaymentType, error) {
+	if pt, ok := model.PaymentTypeFromString(input); ok {
+		return pt, nil
+	}
+
+	if pt, ok := parser.PaymentType(input); ok {
+		return pt, nil
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrUnknownPayment, input)
+}
+
+func parseCategory(categories *model.CategoryRegistry, input string) (model.Category, error) {
+	if input == "" {
+		return model.CategoryUnexpected, nil
+	}
+
+	if c, ok := categories.FromName(input); ok {
+		return c, nil
+	}
+
+	if c, ok := parser.Category(categories, input); ok {
+		return c, nil
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrUnknownCategory, input)
+}
+
+func parseRow(h header, record []string, opts Options) (model.Expense, error) {
+	createdAt, err := parseDate(h.value(record, columnDate), opts.Location)
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	amount, err := parseAmount(h.value(record, columnAmount))
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	paymentType, err := parsePaymentType(h.value(record, columnPaymentType))
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	category, err := parseCategory(opts.Categories, h.value(record, columnCategory))
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	// Без колонки валюты трата записывается в валюте проекта.
+	currency := h.value(record, columnCurrency)
+	if currency != "" {
+		var ok bool
+
+		currency, ok = model.NormalizeCurrency(currency)
+		if !ok {
+			return model.Expense{}, fmt.Errorf("%w: %q", model.ErrInvalidCurrency, h.value(record, columnCurrency))
+		}
+	}
+
+	userID := opts.UserID
+
+	if user := h.value(record, columnUser); user != "" {
+		userID, err = strconv.ParseInt(user, 10, 64)
+		if err != nil {
+			return model.Expense{}, fmt.Errorf("invalid user id %q: %w", user, err)
+		}
+	}
+
+	return model.Expense{
+		ID:          uuid.New(),
+		CreatedAt:   createdAt,
+		UpdatedAt:   time.Now(),
+		Category:    category,
+		PaymentType: paymentType,
+		Description: strings.ToLower(h.value(record, columnDescription)),
+		Amount:      amount,
+		Currency:    currency,
+		UserID:      userID,
+		ProjectID:   opts.ProjectID,
+	}, nil
+}
+
+// detectComma выбирает разделитель по первой строке: Excel в русской локали сохраняет CSV через «;».
+func detectComma(br *bufio.Reader) rune {
+	line, _ := br.Peek(br.Size())
+	if i := strings.IndexByte(string(line), '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	if strings.Count(string(line), ";") > strings.Count(string(line), ",") {
+		return ';'
+	}
+
+	return ','
+}
+
+// ReadCSV разбирает CSV с заголовком. Возвращает корректные строки и ошибки по
+// остальным; ошибка возвращается, только если файл не удалось прочитать целиком.
+func ReadCSV(r io.Reader, opts Options) ([]Row, []RowError, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	br := bufio.NewReader(r)
+
+	cr := csv.NewReader(br)
+	cr.Comma = detectComma(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	record, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, ErrEmptyFile
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("read header: %w", err)
+	}
+
+	h, err := parseHeader(record)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		rows []Row
+		errs []RowError
+	)
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("read csv: %w", err)
+		}
+
+		// csv.Reader пропускает пустые строки, поэтому номер берем у него.
+		lineNo, _ := cr.FieldPos(0)
+
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		expense, err := parseRow(h, record, opts)
+		if err != nil {
+			errs = append(errs, RowError{Line: lineNo, Err: err})
+
+			continue
+		}
+
+		rows = append(rows, Row{Line: lineNo, Expense: expense})
+	}
+
+	return rows, errs, nil
+}
+
+// Database — то, что нужно импорту от хранилища.
+type Database interface {
+	CategoryRegistry() *model.CategoryRegistry
+	Import(ctx context.Context, expenses model.Expenses, loc *time.Location, dryRun bool) ([]int, error)
+}
+
+// Result — итог импорта.
+type Result struct {
+	DryRun     bool
+	Rows       int
+	Imported   int
+	Duplicates []int // номера строк файла
+	Errors     []RowError
+}
+
+// Run разбирает CSV и импортирует его одной транзакцией. Если хотя бы одна строка
+// не разобралась, ничего не записывается: файл нужно исправить и загрузить заново.
+func Run(ctx context.Context, db Database, r io.Reader, opts Options, dryRun bool) (Result, error) {
+	opts.Categories = db.CategoryRegistry()
+
+	rows, errs, err := ReadCSV(r, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		DryRun: dryRun || len(errs) > 0,
+		Rows:   len(rows) + len(errs),
+		Errors: errs,
+	}
+
+	expenses := make(model.Expenses, len(rows))
+	for i := range rows {
+		expenses[i] = rows[i].Expense
+	}
+
+	duplicates, err := db.Import(ctx, expenses, opts.Location, result.DryRun)
+	if err != nil {
+		return Result{}, fmt.Errorf("import: %w", err)
+	}
+
+	for _, i := range duplicates {
+		result.Duplicates = append(result.Duplicates, rows[i].Line)
+	}
+
+	result.Imported = len(rows) - len(duplicates)
+
+	return result, nil
+}
+
+const maxReportedErrors = 20
+
+// String возвращает отчет об импорте для пользователя.
+func (r Result) String() string {
+	var sb strings.Builder
+
+	switch {
+	case len(r.Errors) > 0:
+		sb.WriteString("❌ В файле есть ошибки, ничего не записано.\n")
+	case r.DryRun:
+		sb.WriteString("🔍 Проверка без записи.\n")
+	default:
+		sb.WriteString("✅ Импорт завершен.\n")
+	}
+
+	fmt.Fprintf(&sb, "Строк: %d, к записи: %d, дубликатов: %d, ошибок: %d\n",
+		r.Rows, r.Imported, len(r.Duplicates), len(r.Errors))
+
+	if len(r.Duplicates) > 0 {
+		lines := make([]string, len(r.Duplicates))
+		for i, line := range r.Duplicates {
+			lines[i] = strconv.Itoa(line)
+		}
+
+		sb.WriteString("Дубликаты (пропущены), строки: ")
+		sb.WriteString(strings.Join(lines, ", "))
+		sb.WriteByte('\n')
+	}
+
+	for i, e := range r.Errors {
+		if i == maxReportedErrors {
+			fmt.Fprintf(&sb, "… и еще %d\n", len(r.Errors)-maxReportedErrors)
+
+			break
+		}
+
+		sb.WriteString(e.Error())
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,146 @@
+package importer_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/importer"
+	"kudadeli/model"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "\ufeffДата;Категория;Тип оплаты;Сумма;Описание\n" +
+		"01.04.2025;материалы;наличные;1 500,50;Краска белая\n" +
+		"02.04.2025 18:30;работа/оплата мастерам;карта;7000;демонтаж\n" +
+		"\n" +
+		"03.04.2025;;нал;200;гвозди\n" +
+		"вчера;материалы;карта;100;клей\n" +
+		"04.04.2025;обои;карта;100;клей\n" +
+		"05.04.2025;материалы;бартер;100;клей\n" +
+		"06.04.2025;материалы;карта;-5;клей\n"
+
+	rows, errs, err := importer.ReadCSV(strings.NewReader(input), importer.Options{
+		Location:   time.UTC,
+		UserID:     7,
+		Categories: model.NewCategoryRegistry(model.DefaultCategories()),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, rows, 3)
+
+	first := rows[0].Expense
+	assert.Equal(t, 2, rows[0].Line)
+	assert.True(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC).Equal(first.CreatedAt))
+	assert.Equal(t, model.CategoryMaterials, first.Category)
+	assert.Equal(t, model.PaymentTypeCash, first.PaymentType)
+	assert.True(t, decimal.RequireFromString("1500.50").Equal(first.Amount), "amount: %s", first.Amount)
+	assert.Equal(t, "краска белая", first.Description)
+	assert.Equal(t, int64(7), first.UserID)
+
+	assert.Equal(t, model.CategoryLabor, rows[1].Expense.Category)
+	assert.Equal(t, model.CategoryUnexpected, rows[2].Expense.Category)
+	assert.Equal(t, 5, rows[2].Line)
+
+	require.Len(t, errs, 4)
+	assert.ErrorIs(t, errs[0], importer.ErrInvalidDate)
+	assert.Equal(t, 6, errs[0].Line)
+	assert.ErrorIs(t, errs[1], importer.ErrUnknownCategory)
+	assert.ErrorIs(t, errs[2], importer.ErrUnknownPayment)
+	assert.ErrorIs(t, errs[3], importer.ErrInvalidAmount)
+}
+
+func TestReadRates(t *testing.T) {
+	input := "Дата;Валюта;Курс\n" +
+		"01.04.2025;usd;92,5\n" +
+		"2025-04-02;EUR;100\n"
+
+	rates, err := importer.ReadRates(strings.NewReader(input), "RUB")
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+
+	assert.Equal(t, "USD", rates[0].Currency)
+	assert.Equal(t, "RUB", rates[0].Base)
+	assert.True(t, decimal.RequireFromString("92.5").Equal(rates[0].Rate))
+	assert.True(t, time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC).Equal(rates[1].Date))
+
+	_, err = importer.ReadRates(strings.NewReader("date,currency,rate\n01.04.2025,доллар,92\n"), "RUB")
+	require.ErrorIs(t, err, model.ErrInvalidCurrency)
+
+	_, err = importer.ReadRates(strings.NewReader("date,currency,rate\n01.04.2025,USD,0\n"), "RUB")
+	require.ErrorIs(t, err, importer.ErrInvalidRate)
+}
+
+func TestReadCSVMissingColumn(t *testing.T) {
+	_, _, err := importer.ReadCSV(strings.NewReader("Дата,Описание\n01.04.2025,клей\n"), importer.Options{})
+	require.ErrorIs(t, err, importer.ErrMissingColumn)
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_import.db"
+	defer os.Remove(tmpFile)
+
+	srv, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer srv.Close()
+
+	input := "Дата,Категория,Тип оплаты,Сумма,Описание\n" +
+		"01.04.2025 10:00,материалы,карта,100,клей\n" +
+		"01.04.2025 18:00,материалы,карта,100.00,клей\n" +
+		"02.04.2025,материалы,карта,100,клей\n"
+
+	count := func() int {
+		items, err := srv.List(ctx, -1)
+		require.NoError(t, err)
+
+		return len(items)
+	}
+
+	t.Run("Dry run", func(t *testing.T) {
+		result, err := importer.Run(ctx, srv, strings.NewReader(input), importer.Options{Location: time.UTC}, true)
+		require.NoError(t, err)
+
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 2, result.Imported)
+		assert.Equal(t, []int{3}, result.Duplicates)
+		assert.Equal(t, 0, count(), "dry run must not write")
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		result, err := importer.Run(ctx, srv, strings.NewReader(input), importer.Options{Location: time.UTC}, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.Imported)
+		assert.Equal(t, 2, count())
+	})
+
+	t.Run("Import again skips duplicates", func(t *testing.T) {
+		result, err := importer.Run(ctx, srv, strings.NewReader(input), importer.Options{Location: time.UTC}, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, result.Imported)
+		assert.Equal(t, []int{2, 3, 4}, result.Duplicates)
+		assert.Equal(t, 2, count())
+	})
+
+	t.Run("Row errors abort import", func(t *testing.T) {
+		broken := "Дата,Тип оплаты,Сумма\n10.04.2025,карта,50\n11.04.2025,бартер,50\n"
+
+		result, err := importer.Run(ctx, srv, strings.NewReader(broken), importer.Options{Location: time.UTC}, false)
+		require.NoError(t, err)
+
+		assert.True(t, result.DryRun)
+		assert.Len(t, result.Errors, 1)
+		assert.Equal(t, 2, count())
+	})
+}
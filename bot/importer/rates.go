@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"kudadeli/model"
+)
+
+type rateColumn byte
+
+const (
+	rateColumnDate rateColumn = iota
+	rateColumnCurrency
+	rateColumnBase
+	rateColumnRate
+)
+
+var rateColumnNames = map[string]rateColumn{ //nolint:gochecknoglobals
+	"дата":     rateColumnDate,
+	"date":     rateColumnDate,
+	"валюта":   rateColumnCurrency,
+	"currency": rateColumnCurrency,
+	"база":     rateColumnBase,
+	"base":     rateColumnBase,
+	"курс":     rateColumnRate,
+	"rate":     rateColumnRate,
+}
+
+// ReadRates разбирает CSV с курсами валют: «дата,валюта,курс» и необязательная колонка
+// «база». Курс — сколько единиц базовой валюты стоит одна единица валюты; без колонки
+// базы берется base. В отличие от трат, файл курсов разбирается целиком или никак.
+func ReadRates(r io.Reader, base string) ([]model.ExchangeRate, error) {
+	br := bufio.NewReader(r)
+
+	cr := csv.NewReader(br)
+	cr.Comma = detectComma(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	record, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrEmptyFile
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	h := make(map[rateColumn]int)
+
+	for i, name := range record {
+		if c, ok := rateColumnNames[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))]; ok {
+			h[c] = i
+		}
+	}
+
+	for _, required := range []struct {
+		column rateColumn
+		name   string
+	}{
+		{rateColumnDate, "Дата"},
+		{rateColumnCurrency, "Валюта"},
+		{rateColumnRate, "Курс"},
+	} {
+		if _, ok := h[required.column]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, required.name)
+		}
+	}
+
+	value := func(record []string, c rateColumn) string {
+		i, ok := h[c]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	var rates []model.ExchangeRate
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+
+		lineNo, _ := cr.FieldPos(0)
+
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		date, err := parseDate(value(record, rateColumnDate), time.UTC)
+		if err != nil {
+			return nil, RowError{Line: lineNo, Err: err}
+		}
+
+		rate, err := parseAmount(value(record, rateColumnRate))
+		if err != nil {
+			return nil, RowError{Line: lineNo, Err: fmt.Errorf("%w: %q", ErrInvalidRate, value(record, rateColumnRate))}
+		}
+
+		exchangeRate := model.ExchangeRate{
+			Date:     date,
+			Currency: value(record, rateColumnCurrency),
+			Base:     value(record, rateColumnBase),
+			Rate:     rate,
+		}
+
+		if exchangeRate.Base == "" {
+			exchangeRate.Base = base
+		}
+
+		err = exchangeRate.Validate()
+		if err != nil {
+			return nil, RowError{Line: lineNo, Err: err}
+		}
+
+		rates = append(rates, exchangeRate)
+	}
+
+	return rates, nil
+}
@@ -2,35 +2,78 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	// Образ собирается FROM scratch, поэтому базу часовых поясов вшиваем в бинарник.
+	_ "time/tzdata"
 
 	"kudadeli/bot"
 	"kudadeli/config"
 	"kudadeli/database"
+	"kudadeli/importer"
+	"kudadeli/model"
 	"kudadeli/web"
 
 	"golang.org/x/sync/errgroup"
 )
 
+const purgeInterval = time.Hour
+
+// purgeTrash раз в purgeInterval окончательно удаляет траты, пролежавшие в корзине дольше retention.
+func purgeTrash(ctx context.Context, db *database.Service, retention time.Duration) error {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := db.Purge(ctx, time.Now().Add(-retention))
+		if err != nil {
+			slog.ErrorContext(ctx, "purge trash", "error", err)
+		} else if purged > 0 {
+			slog.InfoContext(ctx, "purge trash", "purged", purged, "retention", retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
 // set from ldflags.
 var (
 	Version = "" //nolint:gochecknoglobals
 )
 
+var errNoOwners = errors.New("set KUDADELI_USERS to at least one owner")
+
 func run(ctx context.Context, cfg *config.Config) error {
-	db, err := database.New(ctx, cfg.Database)
+	// Без владельцев некому выдать роли через /users: доступа не было бы ни у кого.
+	if len(cfg.AllowedUsers) == 0 {
+		return errNoOwners
+	}
+
+	db, err := database.New(ctx, cfg.Database, cfg.Location)
 	if err != nil {
 		return fmt.Errorf("database.new: %w", err)
 	}
 	defer db.Close()
 
-	slog.InfoContext(ctx, "http", "address", cfg.Addr, "allowedOrigins", cfg.AllowedOrigins)
+	// Пользователи из KUDADELI_USERS — владельцы, остальным роли выдаются через /users.
+	err = db.EnsureOwners(ctx, cfg.AllowedUsers)
+	if err != nil {
+		return fmt.Errorf("ensure owners: %w", err)
+	}
+
+	slog.InfoContext(ctx, "http", "address", cfg.Addr, "allowedOrigins", cfg.AllowedOrigins, "timezone", cfg.Location)
 
-	serverHTTP, err := web.New(ctx, db, cfg.Addr, cfg.AllowedOrigins, cfg.EnableBot, cfg.AllowedUsers, cfg.Token)
+	serverHTTP, err := web.New(ctx, db, cfg.Addr, cfg.AllowedOrigins, cfg.Token, cfg.Location)
 	if err != nil {
 		return fmt.Errorf("failed to create HTTP server: %w", err)
 	}
@@ -47,10 +90,14 @@ func run(ctx context.Context, cfg *config.Config) error {
 		return serverHTTP.Shutdown(ctx)
 	})
 
-	slog.InfoContext(ctx, "telebot", "enabled", cfg.EnableBot, "token", cfg.Token != "", "allowedUsers", cfg.AllowedUsers)
+	g.Go(func() error {
+		return purgeTrash(ctx, db, cfg.TrashRetention)
+	})
+
+	slog.InfoContext(ctx, "telebot", "enabled", cfg.EnableBot, "token", cfg.Token != "", "owners", cfg.AllowedUsers)
 
 	if cfg.EnableBot {
-		telebot, err := bot.New(ctx, cfg.Token, db, cfg.AllowedUsers)
+		telebot, err := bot.New(ctx, cfg.Token, db, cfg.Location, cfg.UndoWindow, cfg.GroupPrefix)
 		if err != nil {
 			return fmt.Errorf("telebot new: %w", err)
 		}
@@ -61,6 +108,14 @@ func run(ctx context.Context, cfg *config.Config) error {
 			return nil
 		})
 
+		g.Go(func() error {
+			return telebot.RunRecurring(ctx)
+		})
+
+		g.Go(func() error {
+			return telebot.RunDigests(ctx)
+		})
+
 		g.Go(func() error {
 			<-ctx.Done()
 			telebot.Stop(ctx)
@@ -72,6 +127,93 @@ func run(ctx context.Context, cfg *config.Config) error {
 	return g.Wait() //nolint:wrapcheck
 }
 
+var errImportUsage = errors.New("usage: kudadeli import [-dry-run] [-user ID] [-project ID] file.csv")
+
+// runImport — подкоманда импорта трат из CSV: kudadeli import [-dry-run] [-user ID] [-project ID] file.csv.
+func runImport(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "check the file without writing to the database")
+	userID := fs.Int64("user", 0, "Telegram user ID for rows without a user column")
+	projectID := fs.Int64("project", model.DefaultProjectID, "project ID to import expenses into")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parse flags: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		return errImportUsage
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	db, err := database.New(ctx, cfg.Database, cfg.Location)
+	if err != nil {
+		return fmt.Errorf("database.new: %w", err)
+	}
+	defer db.Close()
+
+	result, err := importer.Run(ctx, db, f, importer.Options{
+		Location:  cfg.Location,
+		UserID:    *userID,
+		ProjectID: *projectID,
+	}, *dryRun)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	_, err = fmt.Fprint(os.Stdout, result.String())
+
+	return err //nolint:wrapcheck
+}
+
+var errRatesUsage = errors.New("usage: kudadeli rates [-base RUB] file.csv")
+
+// runRates — подкоманда загрузки курсов валют из CSV «дата,валюта,курс»: kudadeli rates [-base RUB] file.csv.
+func runRates(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("rates", flag.ContinueOnError)
+	base := fs.String("base", model.DefaultCurrency, "base currency for rows without a base column")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("parse flags: %w", err)
+	}
+
+	if fs.NArg() != 1 {
+		return errRatesUsage
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	rates, err := importer.ReadRates(f, *base)
+	if err != nil {
+		return fmt.Errorf("read rates: %w", err)
+	}
+
+	db, err := database.New(ctx, cfg.Database, cfg.Location)
+	if err != nil {
+		return fmt.Errorf("database.new: %w", err)
+	}
+	defer db.Close()
+
+	err = db.SetRates(ctx, rates)
+	if err != nil {
+		return fmt.Errorf("set rates: %w", err)
+	}
+
+	_, err = fmt.Fprintf(os.Stdout, "Загружено курсов: %d\n", len(rates))
+
+	return err //nolint:wrapcheck
+}
+
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -85,7 +227,16 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
-	err := run(ctx, cfg)
+	var err error
+
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "import":
+		err = runImport(ctx, cfg, os.Args[2:])
+	case len(os.Args) > 1 && os.Args[1] == "rates":
+		err = runRates(ctx, cfg, os.Args[2:])
+	default:
+		err = run(ctx, cfg)
+	}
 	if err != nil {
 		logger.ErrorContext(ctx, "run error", "error", err)
 	} else {
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AttachmentID = uuid.UUID
+
+// Attachment — файл (обычно фото чека), привязанный к трате. Содержимое хранится отдельно.
+type Attachment struct {
+	ID             AttachmentID `json:"id"`
+	ExpenseID      ExpenseID    `json:"expenseId"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	FileName       string       `json:"fileName"`
+	MIMEType       string       `json:"mimeType"`
+	Size           int64        `json:"size"`
+	TelegramFileID string       `json:"-"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "github.com/shopspring/decimal"
+
+// Budget — запланированный бюджет категории и фактически потраченная сумма.
+type Budget struct {
+	Category Category        `json:"category"`
+	Amount   decimal.Decimal `json:"amount"`
+	Spent    decimal.Decimal `json:"spent"`
+}
+
+type Budgets []Budget
+
+func (b Budget) IsSet() bool {
+	return b.Amount.IsPositive()
+}
+
+// Percent возвращает долю потраченного от бюджета в процентах.
+// Если бюджет не задан, возвращает ноль.
+func (b Budget) Percent() decimal.Decimal {
+	if !b.IsSet() {
+		return decimal.Zero
+	}
+
+	return b.Spent.Mul(decimal.NewFromInt(100)).Div(b.Amount)
+}
@@ -1,9 +1,22 @@
 package model
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"unicode"
 )
 
+var (
+	ErrCategoryExists  = errors.New("category already exists")
+	ErrKeywordTaken    = errors.New("keyword is used by another category")
+	ErrInvalidCategory = errors.New("invalid category")
+)
+
+// Category — ID категории. Категории хранятся в базе, встроенные 1–5 есть всегда
+// и сохраняют свои ID, поэтому старые записи остаются валидными.
 type Category byte
 
 const (
@@ -14,47 +27,170 @@ const (
 	CategoryUnexpected                     // 5
 )
 
-func (c Category) String() string {
-	switch c {
-	case CategoryMaterials:
-		return "материалы"
-	case CategoryLabor:
-		return "работа/оплата мастерам"
-	case CategoryTools:
-		return "инструменты"
-	case CategoryFurniture:
-		return "мебель и техника"
-	case CategoryUnexpected:
-		return "прочее/непредвиденное"
-	default:
-		return "неизвестно"
+// CategoryInfo — описание категории: название, эмодзи, порядок и ключевые слова парсера.
+// Архивная категория остается у старых трат, но не предлагается для новых.
+type CategoryInfo struct {
+	ID        Category `json:"id"`
+	Name      string   `json:"name"`
+	Emoji     string   `json:"emoji"`
+	SortOrder int      `json:"sortOrder"`
+	Archived  bool     `json:"archived"`
+	Keywords  []string `json:"keywords"`
+}
+
+// Validate проверяет название и ключевые слова. Ключевое слово — одно слово не из цифр,
+// иначе парсер его не найдет или спутает с суммой.
+func (c CategoryInfo) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidCategory)
+	}
+
+	for _, keyword := range c.Keywords {
+		if strings.ContainsFunc(keyword, unicode.IsSpace) || strings.Trim(keyword, "0123456789.,") == "" {
+			return fmt.Errorf("%w: invalid keyword %q", ErrInvalidCategory, keyword)
+		}
+	}
+
+	return nil
+}
+
+// NormalizeKeywords приводит ключевые слова к нижнему регистру и убирает повторы.
+func NormalizeKeywords(keywords []string) []string {
+	result := make([]string, 0, len(keywords))
+
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword != "" && !slices.Contains(result, keyword) {
+			result = append(result, keyword)
+		}
+	}
+
+	return result
+}
+
+// DefaultCategories — встроенные категории, с которыми создается база.
+func DefaultCategories() []CategoryInfo {
+	return []CategoryInfo{
+		{ID: CategoryMaterials, Name: "материалы", Emoji: "🧱", SortOrder: 1, Keywords: []string{"материалы"}},
+		{ID: CategoryLabor, Name: "работа/оплата мастерам", Emoji: "👷", SortOrder: 2, Keywords: []string{"услуги"}},
+		{ID: CategoryTools, Name: "инструменты", Emoji: "🔨", SortOrder: 3, Keywords: []string{"инструменты"}},
+		{ID: CategoryFurniture, Name: "мебель и техника", Emoji: "🛋", SortOrder: 4, Keywords: []string{"мебель"}},
+		{ID: CategoryUnexpected, Name: "прочее/непредвиденное", Emoji: "❓", SortOrder: 5, Keywords: []string{"прочее"}},
+	}
+}
+
+type categorySnapshot struct {
+	list     []CategoryInfo
+	byID     map[Category]CategoryInfo
+	keywords map[string]Category
+}
+
+func newCategorySnapshot(list []CategoryInfo) *categorySnapshot {
+	r := &categorySnapshot{
+		list:     slices.Clone(list),
+		byID:     make(map[Category]CategoryInfo, len(list)),
+		keywords: make(map[string]Category),
+	}
+
+	slices.SortStableFunc(r.list, func(a, b CategoryInfo) int {
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder - b.SortOrder
+		}
+
+		return int(a.ID) - int(b.ID)
+	})
+
+	for _, c := range r.list {
+		r.byID[c.ID] = c
+
+		if c.Archived {
+			continue
+		}
+
+		for _, keyword := range c.Keywords {
+			r.keywords[keyword] = c.ID
+		}
 	}
+
+	return r
+}
+
+// CategoryRegistry — снимок категорий из базы, чтобы названия, проверки и парсер
+// обходились без похода в базу. Реестром владеет database и заменяет снимок после
+// каждого изменения категорий, остальные пакеты получают его явно.
+type CategoryRegistry struct {
+	snapshot atomic.Pointer[categorySnapshot]
 }
 
-func (c Category) IsValid() bool {
-	switch c {
-	case CategoryMaterials,
-		CategoryLabor,
-		CategoryTools,
-		CategoryFurniture,
-		CategoryUnexpected:
-		return true
+func NewCategoryRegistry(list []CategoryInfo) *CategoryRegistry {
+	r := &CategoryRegistry{}
+	r.Set(list)
 
-	default:
-		return false
+	return r
+}
+
+// Set заменяет известные категории.
+func (r *CategoryRegistry) Set(list []CategoryInfo) {
+	r.snapshot.Store(newCategorySnapshot(list))
+}
+
+// Infos возвращает все категории, включая архивные, в порядке сортировки.
+func (r *CategoryRegistry) Infos() []CategoryInfo {
+	return slices.Clone(r.snapshot.Load().list)
+}
+
+// Active возвращает ID действующих (не архивных) категорий в порядке сортировки.
+func (r *CategoryRegistry) Active() []Category {
+	list := r.snapshot.Load().list
+	result := make([]Category, 0, len(list))
+
+	for _, c := range list {
+		if !c.Archived {
+			result = append(result, c.ID)
+		}
 	}
+
+	return result
 }
 
-func Categories() []Category {
-	return []Category{
-		CategoryMaterials,
-		CategoryLabor,
-		CategoryTools,
-		CategoryFurniture,
-		CategoryUnexpected,
+// ByKeyword ищет действующую категорию по ключевому слову в нижнем регистре.
+func (r *CategoryRegistry) ByKeyword(keyword string) (Category, bool) {
+	c, ok := r.snapshot.Load().keywords[keyword]
+
+	return c, ok
+}
+
+func (r *CategoryRegistry) Info(c Category) (CategoryInfo, bool) {
+	info, ok := r.snapshot.Load().byID[c]
+
+	return info, ok
+}
+
+// Name возвращает название категории.
+func (r *CategoryRegistry) Name(c Category) string {
+	if info, ok := r.Info(c); ok {
+		return info.Name
 	}
+
+	return "неизвестно"
 }
 
-func (c Category) MarshalJSON() ([]byte, error) {
-	return json.Marshal(c.String())
+// IsValid сообщает, что категория существует. Архивные категории тоже валидны.
+func (r *CategoryRegistry) IsValid(c Category) bool {
+	_, ok := r.Info(c)
+
+	return ok
+}
+
+// FromName — обратное преобразование для Name, без учета регистра.
+func (r *CategoryRegistry) FromName(input string) (Category, bool) {
+	input = strings.TrimSpace(input)
+
+	for _, c := range r.snapshot.Load().list {
+		if strings.EqualFold(c.Name, input) {
+			return c.ID, true
+		}
+	}
+
+	return 0, false
 }
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidCurrency = errors.New("invalid currency")
+	ErrInvalidRate     = errors.New("invalid exchange rate")
+)
+
+const currencyCodeLen = 3
+
+//nolint:gochecknoglobals
+var currencySymbols = map[string]string{
+	"RUB": "₽",
+	"USD": "$",
+	"EUR": "€",
+}
+
+// NormalizeCurrency приводит код валюты к виду ISO 4217: три латинские буквы в верхнем регистре.
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != currencyCodeLen || strings.ContainsFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) {
+		return "", false
+	}
+
+	return code, true
+}
+
+// CurrencySymbol возвращает знак валюты, а для валют без знака — ее код.
+func CurrencySymbol(code string) string {
+	if symbol, ok := currencySymbols[code]; ok {
+		return symbol
+	}
+
+	return code
+}
+
+// ExchangeRate — курс на дату: 1 Currency стоит Rate единиц Base. Курс действует,
+// пока не задан более свежий.
+type ExchangeRate struct {
+	Date     time.Time       `json:"date"`
+	Currency string          `json:"currency"`
+	Base     string          `json:"base"`
+	Rate     decimal.Decimal `json:"rate"`
+}
+
+// Validate проверяет коды валют и курс и приводит коды к верхнему регистру.
+func (r *ExchangeRate) Validate() error {
+	currency, ok := NormalizeCurrency(r.Currency)
+	if !ok {
+		return ErrInvalidCurrency
+	}
+
+	base, ok := NormalizeCurrency(r.Base)
+	if !ok || base == currency {
+		return ErrInvalidCurrency
+	}
+
+	if !r.Rate.IsPositive() {
+		return ErrInvalidRate
+	}
+
+	r.Currency, r.Base = currency, base
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidDigest = errors.New("invalid digest settings")
+
+// DigestPeriod — как часто присылать сводку.
+type DigestPeriod string
+
+const (
+	// DigestDaily — каждый день: вчерашние траты и итоги недели на сегодня.
+	DigestDaily DigestPeriod = "daily"
+	// DigestWeekly — по понедельникам: итоги прошедшей недели.
+	DigestWeekly DigestPeriod = "weekly"
+)
+
+func (p DigestPeriod) IsValid() bool {
+	return p == DigestDaily || p == DigestWeekly
+}
+
+func (p DigestPeriod) String() string {
+	if p == DigestWeekly {
+		return "по понедельникам"
+	}
+
+	return "каждый день"
+}
+
+// Digest — настройки сводки пользователя. Сводка собирается с правами пользователя
+// по его активному проекту и уходит в ChatID: в личку (ChatID == UserID) или в группу,
+// тогда по проекту, привязанному к группе.
+type Digest struct {
+	UserID   int64        `json:"userId"`
+	ChatID   int64        `json:"chatId"`
+	Hour     int          `json:"hour"`
+	Minute   int          `json:"minute"`
+	Timezone string       `json:"timezone"`
+	Period   DigestPeriod `json:"period"`
+	NextRun  time.Time    `json:"nextRun"`
+}
+
+// Validate проверяет время, период и часовой пояс сводки.
+func (d Digest) Validate() error {
+	if d.Hour < 0 || d.Hour > 23 || d.Minute < 0 || d.Minute > 59 { //nolint:mnd
+		return ErrInvalidDigest
+	}
+
+	if !d.Period.IsValid() {
+		return ErrInvalidDigest
+	}
+
+	if _, err := time.LoadLocation(d.Timezone); err != nil {
+		return ErrInvalidDigest
+	}
+
+	return nil
+}
+
+// Location возвращает часовой пояс сводки. Timezone проверяется в Validate, поэтому
+// при ошибке возвращается UTC.
+func (d Digest) Location() *time.Location {
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// Schedule возвращает расписание сводки в формате cron.
+func (d Digest) Schedule() string {
+	weekday := "*"
+	if d.Period == DigestWeekly {
+		weekday = "1"
+	}
+
+	return strconv.Itoa(d.Minute) + " " + strconv.Itoa(d.Hour) + " * * " + weekday
+}
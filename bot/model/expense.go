@@ -1,12 +1,15 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+var ErrNotFound = errors.New("not found")
+
 type ExpenseID = uuid.UUID
 
 type Expense struct {
@@ -17,7 +20,11 @@ type Expense struct {
 	PaymentType PaymentType     `json:"paymentType"`
 	Description string          `json:"description"`
 	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
 	UserID      int64           `json:"userId"`
+	ProjectID   ProjectID       `json:"projectId"`
+	// DeletedAt задан только у трат из корзины.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
 
 type Expenses []Expense
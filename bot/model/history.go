@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// ChangeAction — что произошло с тратой.
+type ChangeAction string
+
+const (
+	ActionCreate   ChangeAction = "create"
+	ActionUpdate   ChangeAction = "update"
+	ActionCategory ChangeAction = "category"
+	ActionDelete   ChangeAction = "delete"
+	ActionRestore  ChangeAction = "restore"
+)
+
+// ChangeSource — откуда пришло изменение.
+type ChangeSource string
+
+const (
+	SourceBot ChangeSource = "bot"
+	SourceWeb ChangeSource = "web"
+	// SourceSystem — изменения без пользователя: импорт из консоли, фоновые задачи.
+	SourceSystem ChangeSource = "system"
+)
+
+// FieldChange — старое и новое значение поля в том виде, в каком его видит пользователь.
+// Пустое Old у созданной траты, пустое New у удаленного deletedAt.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// HistoryEntry — запись журнала изменений траты. Журнал только дополняется.
+type HistoryEntry struct {
+	ID        int64         `json:"id"`
+	ExpenseID ExpenseID     `json:"expenseId"`
+	ChangedAt time.Time     `json:"changedAt"`
+	Action    ChangeAction  `json:"action"`
+	UserID    int64         `json:"userId"`
+	Source    ChangeSource  `json:"source"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+func expenseFields(categories *CategoryRegistry, e Expense) []FieldChange {
+	return []FieldChange{
+		{Field: "createdAt", New: e.CreatedAt.UTC().Format(time.RFC3339)},
+		{Field: "paymentType", New: e.PaymentType.String()},
+		{Field: "amount", New: e.Amount.String()},
+		{Field: "currency", New: e.Currency},
+		{Field: "category", New: categories.Name(e.Category)},
+		{Field: "description", New: e.Description},
+	}
+}
+
+// ExpenseChanges сравнивает две версии траты и возвращает измененные поля.
+// before == nil означает создание: в изменения попадают все поля. Категория
+// записывается названием из categories.
+func ExpenseChanges(categories *CategoryRegistry, before *Expense, after Expense) []FieldChange {
+	changes := expenseFields(categories, after)
+	if before == nil {
+		return changes
+	}
+
+	result := make([]FieldChange, 0, len(changes))
+
+	for i, old := range expenseFields(categories, *before) {
+		if old.New != changes[i].New {
+			result = append(result, FieldChange{Field: old.Field, Old: old.New, New: changes[i].New})
+		}
+	}
+
+	return result
+}
@@ -1,6 +1,9 @@
 package model
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 type PaymentType byte
 
@@ -20,6 +23,36 @@ func (p PaymentType) String() string {
 	}
 }
 
+func (p PaymentType) IsValid() bool {
+	switch p {
+	case PaymentTypeCash, PaymentTypeCard:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func PaymentTypes() []PaymentType {
+	return []PaymentType{
+		PaymentTypeCash,
+		PaymentTypeCard,
+	}
+}
+
+// PaymentTypeFromString — обратное преобразование для String(), без учета регистра.
+func PaymentTypeFromString(input string) (PaymentType, bool) {
+	input = strings.TrimSpace(input)
+
+	for _, p := range PaymentTypes() {
+		if strings.EqualFold(p.String(), input) {
+			return p, true
+		}
+	}
+
+	return 0, false
+}
+
 func (p PaymentType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(p.String())
 }
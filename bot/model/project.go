@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type ProjectID = int64
+
+// DefaultProjectID — проект, в который попали все траты, записанные до появления проектов.
+const DefaultProjectID ProjectID = 1
+
+const DefaultCurrency = "RUB"
+
+// Project — отдельный ремонт или объект со своими участниками, бюджетами и валютой.
+type Project struct {
+	ID        ProjectID `json:"id"`
+	Name      string    `json:"name"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"createdAt"`
+}
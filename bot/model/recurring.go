@@ -0,0 +1,51 @@
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type RecurringID = int64
+
+// recurringNamespace — пространство имен для ID трат из шаблонов, см. RecurringExpense.Expense.
+var recurringNamespace = uuid.MustParse("5b0c0d7e-52c1-4a57-9b8e-0f6f0c2f8a61") //nolint:gochecknoglobals
+
+// RecurringExpense — шаблон повторяющейся траты: оплата прорабу по пятницам, аренда
+// склада раз в месяц. По расписанию Schedule (cron) бот предлагает автору шаблона
+// записать трату.
+type RecurringExpense struct {
+	ID          RecurringID     `json:"id"`
+	ProjectID   ProjectID       `json:"projectId"`
+	UserID      int64           `json:"userId"`
+	Schedule    string          `json:"schedule"`
+	Category    Category        `json:"category"`
+	PaymentType PaymentType     `json:"paymentType"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	Description string          `json:"description"`
+	// CatchUp — после простоя предложить все пропущенные срабатывания, а не только последнее.
+	CatchUp   bool      `json:"catchUp"`
+	Paused    bool      `json:"paused"`
+	NextRun   time.Time `json:"nextRun"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Expense собирает трату срабатывания at. ID траты выводится из шаблона и времени
+// срабатывания, поэтому повторное подтверждение не запишет трату дважды.
+func (r RecurringExpense) Expense(at, now time.Time) Expense {
+	return Expense{
+		ID:          uuid.NewSHA1(recurringNamespace, []byte(strconv.FormatInt(r.ID, 10)+"/"+strconv.FormatInt(at.Unix(), 10))),
+		CreatedAt:   at,
+		UpdatedAt:   now,
+		Category:    r.Category,
+		PaymentType: r.PaymentType,
+		Description: r.Description,
+		Amount:      r.Amount,
+		Currency:    r.Currency,
+		UserID:      r.UserID,
+		ProjectID:   r.ProjectID,
+	}
+}
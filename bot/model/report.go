@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Total — сумма и количество трат в группе.
+type Total struct {
+	Amount decimal.Decimal `json:"amount"`
+	Count  int             `json:"count"`
+}
+
+type CategoryTotal struct {
+	Category Category `json:"category"`
+	Total
+}
+
+type PaymentTypeTotal struct {
+	PaymentType PaymentType `json:"paymentType"`
+	Total
+}
+
+// Report — сводка трат за период [From, To). Нулевые границы означают «без ограничения».
+// Суммы пересчитаны в валюту проекта по курсу на дату траты. Траты в валютах из MissingRates
+// посчитаны в Count, но не в Amount: курса для них нет.
+type Report struct {
+	From          time.Time          `json:"from"`
+	To            time.Time          `json:"to"`
+	Total         Total              `json:"total"`
+	ByCategory    []CategoryTotal    `json:"byCategory"`
+	ByPaymentType []PaymentTypeTotal `json:"byPaymentType"`
+	ByUser        []UserTotal        `json:"byUser"`
+	Top           Expenses           `json:"top"`
+	MissingRates  []string           `json:"missingRates"`
+}
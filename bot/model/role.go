@@ -0,0 +1,117 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrForbidden   = errors.New("forbidden")
+	ErrInvalidRole = errors.New("invalid role")
+	ErrLastOwner   = errors.New("cannot remove the last owner")
+)
+
+// Role — роль пользователя. Роли общие для всех проектов, в какие проекты
+// пользователь входит, определяет участие в проекте.
+type Role string
+
+const (
+	// RoleOwner может все, включая управление пользователями, категориями и проектами.
+	RoleOwner Role = "owner"
+	// RoleEditor записывает и исправляет любые траты, задает бюджеты.
+	RoleEditor Role = "editor"
+	// RoleViewer только смотрит.
+	RoleViewer Role = "viewer"
+	// RoleContractor записывает траты и видит, исправляет и удаляет только свои.
+	RoleContractor Role = "contractor"
+)
+
+// Permission — действие, которое проверяется перед выполнением команды или запроса.
+type Permission byte
+
+const (
+	// PermissionViewAll — видеть траты всех участников, а не только свои.
+	PermissionViewAll Permission = iota + 1
+	// PermissionRecord — записывать траты и исправлять свои.
+	PermissionRecord
+	// PermissionEditAll — исправлять, удалять и восстанавливать чужие траты.
+	PermissionEditAll
+	// PermissionBudgets — задавать бюджеты.
+	PermissionBudgets
+	// PermissionAdmin — пользователи, категории, проекты.
+	PermissionAdmin
+)
+
+var rolePermissions = map[Role][]Permission{ //nolint:gochecknoglobals
+	RoleOwner:      {PermissionViewAll, PermissionRecord, PermissionEditAll, PermissionBudgets, PermissionAdmin},
+	RoleEditor:     {PermissionViewAll, PermissionRecord, PermissionEditAll, PermissionBudgets},
+	RoleViewer:     {PermissionViewAll},
+	RoleContractor: {PermissionRecord},
+}
+
+var roleNames = map[Role]string{ //nolint:gochecknoglobals
+	RoleOwner:      "владелец",
+	RoleEditor:     "редактор",
+	RoleViewer:     "наблюдатель",
+	RoleContractor: "подрядчик",
+}
+
+// Roles возвращает роли от самой сильной к самой слабой.
+func Roles() []Role {
+	return []Role{RoleOwner, RoleEditor, RoleViewer, RoleContractor}
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+
+	return ok
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+
+	return "без доступа"
+}
+
+// RoleFromString принимает роль по имени на английском или по-русски.
+func RoleFromString(input string) (Role, bool) {
+	role := Role(input)
+	if role.IsValid() {
+		return role, true
+	}
+
+	for r, name := range roleNames {
+		if name == input {
+			return r, true
+		}
+	}
+
+	return "", false
+}
+
+// Can сообщает, есть ли у роли разрешение. У пустой роли разрешений нет.
+func (r Role) Can(permission Permission) bool {
+	return slices.Contains(rolePermissions[r], permission)
+}
+
+// CanView сообщает, может ли пользователь userID с этой ролью видеть трату.
+func (r Role) CanView(userID int64, expense Expense) bool {
+	return r.Can(PermissionViewAll) || (r.Can(PermissionRecord) && expense.UserID == userID)
+}
+
+// CanEdit сообщает, может ли пользователь userID с этой ролью исправить или удалить трату.
+func (r Role) CanEdit(userID int64, expense Expense) bool {
+	return r.Can(PermissionEditAll) || (r.Can(PermissionRecord) && expense.UserID == userID)
+}
+
+// User — пользователь с доступом к боту и API.
+type User struct {
+	ID   int64 `json:"id"`
+	Role Role  `json:"role"`
+	// Name — имя из профиля Telegram, обновляется, когда пользователь пишет боту.
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
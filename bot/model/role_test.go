@@ -0,0 +1,68 @@
+package model_test
+
+import (
+	"kudadeli/model"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role    model.Role
+		allowed []model.Permission
+	}{
+		{model.RoleOwner, []model.Permission{
+			model.PermissionViewAll, model.PermissionRecord, model.PermissionEditAll,
+			model.PermissionBudgets, model.PermissionAdmin,
+		}},
+		{model.RoleEditor, []model.Permission{
+			model.PermissionViewAll, model.PermissionRecord, model.PermissionEditAll, model.PermissionBudgets,
+		}},
+		{model.RoleViewer, []model.Permission{model.PermissionViewAll}},
+		{model.RoleContractor, []model.Permission{model.PermissionRecord}},
+		{"", nil},
+		{"admin", nil},
+	}
+
+	all := []model.Permission{
+		model.PermissionViewAll, model.PermissionRecord, model.PermissionEditAll,
+		model.PermissionBudgets, model.PermissionAdmin,
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			for _, permission := range all {
+				assert.Equal(t, slices.Contains(tt.allowed, permission),
+					tt.role.Can(permission), "permission %d", permission)
+			}
+		})
+	}
+}
+
+func TestRoleCanViewAndEdit(t *testing.T) {
+	own := model.Expense{UserID: 1}
+	other := model.Expense{UserID: 2}
+
+	tests := []struct {
+		role               model.Role
+		viewOwn, viewOther bool
+		editOwn, editOther bool
+	}{
+		{model.RoleOwner, true, true, true, true},
+		{model.RoleEditor, true, true, true, true},
+		{model.RoleViewer, true, true, false, false},
+		{model.RoleContractor, true, false, true, false},
+		{"", false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			assert.Equal(t, tt.viewOwn, tt.role.CanView(1, own), "view own")
+			assert.Equal(t, tt.viewOther, tt.role.CanView(1, other), "view other")
+			assert.Equal(t, tt.editOwn, tt.role.CanEdit(1, own), "edit own")
+			assert.Equal(t, tt.editOther, tt.role.CanEdit(1, other), "edit other")
+		})
+	}
+}
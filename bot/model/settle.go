@@ -0,0 +1,158 @@
+package model
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrInvalidShare = errors.New("invalid share")
+
+// Balance — сколько участник заплатил за общие траты и сколько из них приходится
+// на него по доле.
+type Balance struct {
+	UserID int64           `json:"userId"`
+	Share  int64           `json:"share"`
+	Paid   decimal.Decimal `json:"paid"`
+	Owed   decimal.Decimal `json:"owed"`
+}
+
+// Net — сколько участнику должны остальные (больше нуля) или он остальным (меньше нуля).
+func (b Balance) Net() decimal.Decimal {
+	return b.Paid.Sub(b.Owed)
+}
+
+// Transfer — перевод, которым From рассчитывается с To.
+type Transfer struct {
+	From   int64           `json:"from"`
+	To     int64           `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// Settlement — взаиморасчеты участников проекта за период [From, To). Суммы в валюте
+// проекта, траты без курса (MissingRates) не учтены.
+type Settlement struct {
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	Total        decimal.Decimal `json:"total"`
+	Balances     []Balance       `json:"balances"`
+	Transfers    []Transfer      `json:"transfers"`
+	MissingRates []string        `json:"missingRates"`
+}
+
+// Settle делит траты paid между участниками по долям shares («70/30» — доли 70 и 30)
+// и считает, кто кому сколько переведет. Без долей траты делятся поровну между теми,
+// кто платил. Заплатившие без доли в тратах не участвуют: им все возвращается.
+func Settle(paid []UserTotal, shares map[int64]int64) ([]Balance, []Transfer) {
+	balances := make(map[int64]*Balance)
+
+	balance := func(userID int64) *Balance {
+		if b, ok := balances[userID]; ok {
+			return b
+		}
+
+		b := &Balance{UserID: userID}
+		balances[userID] = b
+
+		return b
+	}
+
+	total := decimal.Zero
+
+	for _, t := range paid {
+		b := balance(t.UserID)
+		b.Paid = b.Paid.Add(t.Amount)
+		total = total.Add(t.Amount)
+
+		if len(shares) == 0 {
+			b.Share = 1
+		}
+	}
+
+	for userID, share := range shares {
+		balance(userID).Share = share
+	}
+
+	result := make([]Balance, 0, len(balances))
+	sumShares := int64(0)
+
+	for _, b := range balances {
+		sumShares += b.Share
+	}
+
+	for _, b := range balances {
+		if sumShares > 0 {
+			b.Owed = total.Mul(decimal.NewFromInt(b.Share)).Div(decimal.NewFromInt(sumShares)).Round(2) //nolint:mnd
+		}
+
+		result = append(result, *b)
+	}
+
+	// Самые большие доли первыми: на первого ложится копейка от округления.
+	slices.SortFunc(result, func(a, b Balance) int {
+		return cmp.Or(cmp.Compare(b.Share, a.Share), b.Paid.Cmp(a.Paid), cmp.Compare(a.UserID, b.UserID))
+	})
+
+	if sumShares > 0 {
+		owed := decimal.Zero
+
+		for _, b := range result {
+			owed = owed.Add(b.Owed)
+		}
+
+		result[0].Owed = result[0].Owed.Add(total.Sub(owed))
+	}
+
+	return result, transfers(result)
+}
+
+// transfers сводит долги к переводам: самый крупный должник платит самому крупному
+// получателю, пока все не окажутся в расчете.
+func transfers(balances []Balance) []Transfer {
+	type party struct {
+		userID int64
+		amount decimal.Decimal
+	}
+
+	var creditors, debtors []party
+
+	for _, b := range balances {
+		switch net := b.Net(); {
+		case net.IsPositive():
+			creditors = append(creditors, party{userID: b.UserID, amount: net})
+		case net.IsNegative():
+			debtors = append(debtors, party{userID: b.UserID, amount: net.Neg()})
+		}
+	}
+
+	byAmount := func(a, b party) int {
+		return cmp.Or(b.amount.Cmp(a.amount), cmp.Compare(a.userID, b.userID))
+	}
+
+	slices.SortFunc(creditors, byAmount)
+	slices.SortFunc(debtors, byAmount)
+
+	var result []Transfer
+
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		amount := decimal.Min(debtors[i].amount, creditors[j].amount)
+
+		result = append(result, Transfer{From: debtors[i].userID, To: creditors[j].userID, Amount: amount})
+
+		debtors[i].amount = debtors[i].amount.Sub(amount)
+		creditors[j].amount = creditors[j].amount.Sub(amount)
+
+		if debtors[i].amount.IsZero() {
+			i++
+		}
+
+		if creditors[j].amount.IsZero() {
+			j++
+		}
+	}
+
+	return result
+}
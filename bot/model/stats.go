@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+type UserTotal struct {
+	UserID int64 `json:"userId"`
+	Total
+}
+
+// PeriodTotal — итог за день, неделю или месяц, начинающийся в Start.
+type PeriodTotal struct {
+	Start time.Time `json:"start"`
+	Total
+}
+
+// Stats — агрегаты трат для графиков мини-приложения.
+type Stats struct {
+	From          time.Time          `json:"from"`
+	To            time.Time          `json:"to"`
+	Timezone      string             `json:"timezone"`
+	Currency      string             `json:"currency"`
+	Total         Total              `json:"total"`
+	ByCategory    []CategoryTotal    `json:"byCategory"`
+	ByPaymentType []PaymentTypeTotal `json:"byPaymentType"`
+	ByUser        []UserTotal        `json:"byUser"`
+	ByDay         []PeriodTotal      `json:"byDay"`
+	ByWeek        []PeriodTotal      `json:"byWeek"`
+	ByMonth       []PeriodTotal      `json:"byMonth"`
+	MissingRates  []string           `json:"missingRates"`
+}
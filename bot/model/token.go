@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// TokenScope — что можно делать с API-токеном.
+type TokenScope string
+
+const (
+	// ScopeRead — только чтение.
+	ScopeRead TokenScope = "read"
+	// ScopeWrite — чтение и изменения в пределах роли владельца токена.
+	ScopeWrite TokenScope = "write"
+)
+
+func (s TokenScope) IsValid() bool {
+	return s == ScopeRead || s == ScopeWrite
+}
+
+// APIToken — персональный токен для скриптов. Сам секрет показывается один раз
+// при выпуске, в базе хранится только его хеш.
+type APIToken struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"userId"`
+	Name      string     `json:"name"`
+	Scope     TokenScope `json:"scope"`
+	CreatedAt time.Time  `json:"createdAt"`
+	// ExpiresAt == nil — токен бессрочный.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Expired сообщает, истек ли токен к моменту now.
+func (t APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
@@ -0,0 +1,256 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	thousandsGroupLen = 3
+	maxAmountWords    = 8
+)
+
+var (
+	// currencySuffixes — обозначения валют, которые можно дописать к сумме. Длинные формы
+	// раньше коротких: «доллар» не должен срезаться как «р».
+	currencySuffixes = []currencySuffix{ //nolint:gochecknoglobals
+		{"долларов", "USD"}, {"доллара", "USD"}, {"доллар", "USD"}, {"usd", "USD"}, {"$", "USD"},
+		{"евро", "EUR"}, {"eur", "EUR"}, {"€", "EUR"},
+		{"рублей", "RUB"}, {"рубля", "RUB"}, {"рубль", "RUB"}, {"руб.", "RUB"}, {"руб", "RUB"}, {"rub", "RUB"},
+		{"р.", "RUB"}, {"р", "RUB"}, {"₽", "RUB"},
+	}
+	// currencyPrefixes — знаки валют, которые можно написать перед суммой: $100, €50.
+	currencyPrefixes = []currencySuffix{{"$", "USD"}, {"€", "EUR"}, {"₽", "RUB"}} //nolint:gochecknoglobals
+	// thousandSuffixes умножают число на тысячу: 1,5к, 3.2k, 15 тыс.
+	thousandSuffixes = []string{"тыс.", "тыс", "к", "k"} //nolint:gochecknoglobals
+
+	thousand = decimal.NewFromInt(1000) //nolint:gochecknoglobals
+)
+
+type currencySuffix struct {
+	token string
+	code  string
+}
+
+// cutCurrency срезает обозначение валюты в конце или знак валюты в начале и возвращает
+// код валюты («» — валюта не указана).
+func cutCurrency(input string) (string, string) {
+	for _, c := range currencySuffixes {
+		if rest, ok := strings.CutSuffix(input, c.token); ok {
+			return strings.TrimSpace(rest), c.code
+		}
+	}
+
+	for _, c := range currencyPrefixes {
+		if rest, ok := strings.CutPrefix(input, c.token); ok {
+			return strings.TrimSpace(rest), c.code
+		}
+	}
+
+	return input, ""
+}
+
+// currencyWord сообщает код валюты, если слово целиком — ее обозначение: «usd», «евро», «$».
+func currencyWord(word string) (string, bool) {
+	for _, c := range currencySuffixes {
+		if word == c.token {
+			return c.code, true
+		}
+	}
+
+	return "", false
+}
+
+// normalizeOperators сводит все знаки умножения к «*»: 2x750, 2х750 (кириллица), 2×750.
+func normalizeOperators(input string) string {
+	return strings.NewReplacer("×", "*", "х", "*", "x", "*").Replace(input)
+}
+
+func cutSuffix(input string, suffixes []string) (string, bool) {
+	for _, suffix := range suffixes {
+		if rest, ok := strings.CutSuffix(input, suffix); ok {
+			return strings.TrimSpace(rest), true
+		}
+	}
+
+	return input, false
+}
+
+func isDigits(input string) bool {
+	return input != "" && !strings.ContainsFunc(input, func(r rune) bool { return !unicode.IsDigit(r) })
+}
+
+// joinGroups склеивает число, разбитое на разряды пробелами (в том числе неразрывными)
+// или апострофом: «1 500», «12 000,50», «1'500».
+// Все группы, кроме первой, должны начинаться ровно с трех цифр.
+func joinGroups(input string) (string, bool) {
+	groups := strings.Fields(strings.ReplaceAll(input, "'", " "))
+	if len(groups) == 0 {
+		return "", false
+	}
+
+	if len(groups) == 1 {
+		return groups[0], true
+	}
+
+	if !isDigits(groups[0]) || len(groups[0]) > thousandsGroupLen {
+		return "", false
+	}
+
+	for i, group := range groups[1:] {
+		digits, fraction, hasFraction := strings.Cut(strings.ReplaceAll(group, ",", "."), ".")
+		if len(digits) != thousandsGroupLen || !isDigits(digits) ||
+			(hasFraction && (i != len(groups)-2 || !isDigits(fraction))) {
+			return "", false
+		}
+	}
+
+	return strings.Join(groups, ""), true
+}
+
+// normalizeSeparators превращает число с разделителями разрядов и десятичной запятой
+// в вид, понятный decimal. Одиночный разделитель перед ровно тремя цифрами считается
+// разделителем разрядов (1.500, 1,500): копеек больше двух не бывает.
+func normalizeSeparators(input string, multiplied bool) string {
+	dots, commas := strings.Count(input, "."), strings.Count(input, ",")
+
+	switch {
+	case dots > 0 && commas > 0:
+		// Десятичный разделитель — последний: 1.500,50 или 1,500.50.
+		if strings.LastIndex(input, ",") > strings.LastIndex(input, ".") {
+			return strings.ReplaceAll(strings.ReplaceAll(input, ".", ""), ",", ".")
+		}
+
+		return strings.ReplaceAll(input, ",", "")
+	case dots+commas > 1 && (dots == 0 || commas == 0):
+		return strings.NewReplacer(".", "", ",", "").Replace(input)
+	case dots+commas == 1:
+		input = strings.ReplaceAll(input, ",", ".")
+		whole, fraction, _ := strings.Cut(input, ".")
+
+		if !multiplied && len(fraction) == thousandsGroupLen && whole != "" && whole != "0" {
+			return whole + fraction
+		}
+
+		return input
+	default:
+		return input
+	}
+}
+
+// number разбирает одно число с необязательными обозначением валюты и множителем тысяч.
+// Возвращает число и код валюты, если она указана.
+func number(input string) (decimal.Decimal, string, bool) {
+	input, currency := cutCurrency(strings.TrimSpace(input))
+	input, multiplied := cutSuffix(input, thousandSuffixes)
+
+	input, ok := joinGroups(input)
+	if !ok {
+		return decimal.Zero, "", false
+	}
+
+	input = normalizeSeparators(input, multiplied)
+
+	whole, fraction, _ := strings.Cut(input, ".")
+	if !isDigits(whole) || (fraction != "" && !isDigits(fraction)) || strings.HasSuffix(input, ".") {
+		return decimal.Zero, "", false
+	}
+
+	amount, err := decimal.NewFromString(input)
+	if err != nil {
+		return decimal.Zero, "", false
+	}
+
+	if multiplied {
+		amount = amount.Mul(thousand)
+	}
+
+	return amount, currency, true
+}
+
+// Amount разбирает сумму без учета валюты, см. AmountCurrency.
+func Amount(input string) (decimal.Decimal, bool) {
+	amount, _, ok := AmountCurrency(input)
+
+	return amount, ok
+}
+
+// AmountCurrency разбирает сумму: разделители разрядов (1 500, 1.500), десятичную запятую (1,5),
+// суффиксы тысяч (1,5к, 3.2k, 15 тыс), обозначения валют (1500р, $20, 15 евро) и простые
+// выражения из сложения и умножения (2x750, 1500+300, 2*750+100). Возвращает код валюты
+// или «», если валюта не указана. Разные валюты в одном выражении складывать нельзя.
+func AmountCurrency(input string) (decimal.Decimal, string, bool) {
+	input = normalizeOperators(strings.ToLower(strings.TrimSpace(input)))
+	if input == "" {
+		return decimal.Zero, "", false
+	}
+
+	var (
+		total    = decimal.Zero
+		currency string
+	)
+
+	for term := range strings.SplitSeq(input, "+") {
+		product := decimal.NewFromInt(1)
+
+		for factor := range strings.SplitSeq(term, "*") {
+			amount, code, ok := number(factor)
+			if !ok || (code != "" && currency != "" && code != currency) {
+				return decimal.Zero, "", false
+			}
+
+			if code != "" {
+				currency = code
+			}
+
+			product = product.Mul(amount)
+		}
+
+		total = total.Add(product)
+	}
+
+	if !total.IsPositive() {
+		return decimal.Zero, "", false
+	}
+
+	return total, currency, true
+}
+
+// isAmountWord сообщает, может ли слово быть частью суммы: число, оператор, «тыс» или
+// обозначение валюты. Одиночные «к» и «k» частью суммы не считаются — это предлог.
+func isAmountWord(word string) bool {
+	if word == "к" || word == "k" {
+		return false
+	}
+
+	rest, _ := cutCurrency(word)
+	rest, _ = cutSuffix(rest, thousandSuffixes)
+
+	return !strings.ContainsFunc(normalizeOperators(rest), func(r rune) bool {
+		return !unicode.IsDigit(r) && !strings.ContainsRune(".,+*'₽$€", r)
+	})
+}
+
+// amountAt пробует прочитать сумму, начиная со слова start. Сумма может занимать
+// несколько слов («1 500», «2 x 750», «15 тыс руб»), выбирается самая длинная.
+// Возвращает сумму, код валюты и число занятых слов.
+func amountAt(words []string, start int) (decimal.Decimal, string, int) {
+	if !strings.ContainsFunc(words[start], unicode.IsDigit) {
+		return decimal.Zero, "", 0
+	}
+
+	end := start
+	for end < len(words) && end-start < maxAmountWords && isAmountWord(words[end]) {
+		end++
+	}
+
+	for ; end > start; end-- {
+		if amount, currency, ok := AmountCurrency(strings.Join(words[start:end], " ")); ok {
+			return amount, currency, end - start
+		}
+	}
+
+	return decimal.Zero, "", 0
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"strings"
+	"time"
+
+	"kudadeli/model"
+)
+
+// Line — результат разбора одной строки сообщения: либо трата, либо ошибка.
+type Line struct {
+	// Number — номер строки в сообщении, с 1. Пустые строки тоже считаются.
+	Number  int
+	Text    string
+	Expense model.Expense
+	Err     error
+}
+
+// MessagesAt разбирает каждую непустую строку сообщения как отдельную трату, см. MessageAt.
+// Ошибка в одной строке не мешает остальным.
+func MessagesAt(categories *model.CategoryRegistry, input string, now time.Time) []Line {
+	var lines []Line
+
+	for i, text := range strings.Split(input, "\n") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		expense, err := MessageAt(categories, text, now)
+		lines = append(lines, Line{Number: i + 1, Text: text, Expense: expense, Err: err})
+	}
+
+	return lines
+}
+
+// IsStrict сообщает, записана ли каждая непустая строка сообщения строго как
+// «[тип_оплаты] [сумма] [описание]»: тип оплаты первым словом, сразу за ним сумма
+// и хотя бы одно слово после. В группе так бот отличает траты от обычной переписки.
+func IsStrict(input string) bool {
+	strict := false
+
+	for text := range strings.SplitSeq(input, "\n") {
+		words := strings.Fields(strings.ToLower(text))
+		if len(words) == 0 {
+			continue
+		}
+
+		if _, ok := paymentWords[words[0]]; !ok || len(words) < minWords+1 {
+			return false
+		}
+
+		_, _, n := amountAt(words, 1)
+		if n == 0 || 1+n >= len(words) {
+			return false
+		}
+
+		strict = true
+	}
+
+	return strict
+}
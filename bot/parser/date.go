@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxDaysAgo  = 366
+	daysInWeek  = 7
+	twoDigitsYY = 2000
+)
+
+var (
+	// dayMonthRe — «12.10», «12.10.26», «12.10.2026». Месяц всегда из двух цифр,
+	// иначе «1.5» от «1.5к» не отличить.
+	dayMonthRe = regexp.MustCompile(`^(\d{1,2})\.(\d{2})(?:\.(\d{2}|\d{4}))?$`) //nolint:gochecknoglobals
+
+	relativeDays = map[string]int{ //nolint:gochecknoglobals
+		"сегодня":   0,
+		"вчера":     1,
+		"позавчера": 2,
+	}
+
+	weekdayWords = map[string]time.Weekday{ //nolint:gochecknoglobals
+		"понедельник": time.Monday,
+		"вторник":     time.Tuesday,
+		"среду":       time.Wednesday,
+		"четверг":     time.Thursday,
+		"пятницу":     time.Friday,
+		"субботу":     time.Saturday,
+		"воскресенье": time.Sunday,
+	}
+
+	dayUnits = map[string]bool{ //nolint:gochecknoglobals
+		"день": true, "дня": true, "дней": true, "дн": true, "дн.": true,
+	}
+)
+
+func daysAgo(now time.Time, days int) time.Time {
+	return startOfDay(now).AddDate(0, 0, -days)
+}
+
+// dayMonth разбирает «12.10» и «12.10.2026». Дата без года, которая в этом году
+// еще не наступила, относится к прошлому году.
+func dayMonth(word string, now time.Time) (time.Time, bool) {
+	m := dayMonthRe.FindStringSubmatch(word)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	day, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	year := now.Year()
+
+	if m[3] != "" {
+		year, _ = strconv.Atoi(m[3])
+		if len(m[3]) == 2 { //nolint:mnd
+			year += twoDigitsYY
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+	if date.Day() != day || int(date.Month()) != month {
+		return time.Time{}, false
+	}
+
+	if m[3] == "" && date.After(now) {
+		date = date.AddDate(-1, 0, 0)
+	}
+
+	return date, true
+}
+
+// isDayMonthWithoutYear сообщает, записано ли слово как «12.10» — без года.
+func isDayMonthWithoutYear(word string) bool {
+	m := dayMonthRe.FindStringSubmatch(word)
+
+	return m != nil && m[3] == ""
+}
+
+// dateAt пробует прочитать дату, начиная со слова i. Возвращает начало дня и число слов.
+func dateAt(words []string, i int, now time.Time) (time.Time, int) {
+	word := words[i]
+	next := func(n int) string {
+		if i+n < len(words) {
+			return words[i+n]
+		}
+
+		return ""
+	}
+
+	if days, ok := relativeDays[word]; ok {
+		return daysAgo(now, days), 1
+	}
+
+	if date, ok := dayMonth(word, now); ok {
+		return date, 1
+	}
+
+	// «в пятницу» — ближайшая прошедшая пятница, в пятницу это неделя назад.
+	if word == "в" || word == "во" {
+		if weekday, ok := weekdayWords[next(1)]; ok {
+			days := (int(now.Weekday()) - int(weekday) + daysInWeek) % daysInWeek
+			if days == 0 {
+				days = daysInWeek
+			}
+
+			return daysAgo(now, days), 2 //nolint:mnd
+		}
+	}
+
+	if word == "неделю" && next(1) == "назад" {
+		return daysAgo(now, daysInWeek), 2 //nolint:mnd
+	}
+
+	// «2 дня назад», «5 дней назад».
+	if days, err := strconv.Atoi(word); err == nil && days > 0 && days <= maxDaysAgo &&
+		dayUnits[next(1)] && next(2) == "назад" {
+		return daysAgo(now, days), 3 //nolint:mnd
+	}
+
+	return time.Time{}, 0
+}
+
+// findDate ищет первую дату в словах. Возвращает начало дня, индекс первого слова
+// даты и число слов (0 — даты нет).
+func findDate(words []string, now time.Time) (time.Time, int, int) {
+	for i := range words {
+		if date, n := dateAt(words, i, now); n > 0 {
+			return date, i, n
+		}
+	}
+
+	return time.Time{}, 0, 0
+}
+
+// withClock переносит время суток now на дату date: задним числом трата
+// записывается на то же время, чтобы порядок внутри дня оставался естественным.
+func withClock(date, now time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(),
+		now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), now.Location())
+}
+
+// Date ищет в тексте дату траты: «вчера», «позавчера», «12.10», «12.10.2026»,
+// «в пятницу», «2 дня назад». Дата считается в часовом поясе now, время суток берется из now.
+func Date(input string, now time.Time) (time.Time, bool) {
+	date, _, n := findDate(strings.Fields(strings.ToLower(input)), now)
+	if n == 0 {
+		return time.Time{}, false
+	}
+
+	return withClock(date, now), true
+}
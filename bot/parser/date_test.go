@@ -0,0 +1,113 @@
+package parser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/model"
+	"kudadeli/parser"
+)
+
+func TestDate(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2026, 10, 16, 0, 30, 0, 0, loc) // пятница, сразу после полуночи
+
+	at := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 0, 30, 0, 0, loc)
+	}
+
+	tests := []struct {
+		input string
+		want  time.Time
+		ok    bool
+	}{
+		{input: "вчера", want: at(10, 15), ok: true},
+		{input: "Позавчера", want: at(10, 14), ok: true},
+		{input: "сегодня", want: now, ok: true},
+		{input: "12.10", want: at(10, 12), ok: true},
+		{input: "12.10.2026", want: at(10, 12), ok: true},
+		{input: "01.03.25", want: time.Date(2025, 3, 1, 0, 30, 0, 0, loc), ok: true},
+		{input: "25.12", want: time.Date(2025, 12, 25, 0, 30, 0, 0, loc), ok: true},
+		{input: "в пятницу", want: at(10, 9), ok: true},
+		{input: "во вторник", want: at(10, 13), ok: true},
+		{input: "в воскресенье", want: at(10, 11), ok: true},
+		{input: "2 дня назад", want: at(10, 14), ok: true},
+		{input: "5 дней назад", want: at(10, 11), ok: true},
+		{input: "неделю назад", want: at(10, 9), ok: true},
+		{input: "31.02", ok: false},
+		{input: "1.5", ok: false},
+		{input: "249.90", ok: false},
+		{input: "в ванную", ok: false},
+		{input: "2 дня", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parser.Date(tt.input, now)
+			require.Equal(t, tt.ok, ok)
+
+			if tt.ok {
+				require.True(t, tt.want.Equal(got), "got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageAtBackdating(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2026, 10, 18, 14, 5, 0, 0, loc) // воскресенье
+
+	tests := []struct {
+		input       string
+		wantDate    time.Time
+		amount      int64
+		description string
+	}{
+		{
+			input:       "вчера нал 1500 краска",
+			wantDate:    time.Date(2026, 10, 17, 14, 5, 0, 0, loc),
+			amount:      1500,
+			description: "краска",
+		},
+		{
+			input:       "карта 12.10 3200 двери",
+			wantDate:    time.Date(2026, 10, 12, 14, 5, 0, 0, loc),
+			amount:      3200,
+			description: "двери",
+		},
+		{
+			input:       "нал 800 грунтовка в пятницу",
+			wantDate:    time.Date(2026, 10, 16, 14, 5, 0, 0, loc),
+			amount:      800,
+			description: "грунтовка",
+		},
+		{
+			input:       "карта 2 дня назад 1,5к доставка",
+			wantDate:    time.Date(2026, 10, 16, 14, 5, 0, 0, loc),
+			amount:      1500,
+			description: "доставка",
+		},
+		{
+			input:       "нал 300 мусор",
+			wantDate:    now,
+			amount:      300,
+			description: "мусор",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expense, err := parser.MessageAt(defaultCategories(), tt.input, now)
+			require.NoError(t, err)
+
+			require.True(t, tt.wantDate.Equal(expense.CreatedAt), "got %s", expense.CreatedAt)
+			require.True(t, now.Equal(expense.UpdatedAt))
+			require.True(t, decimal.NewFromInt(tt.amount).Equal(expense.Amount), "got %s", expense.Amount)
+			require.Equal(t, tt.description, expense.Description)
+			require.Equal(t, model.CategoryUnexpected, expense.Category)
+		})
+	}
+}
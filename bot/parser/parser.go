@@ -3,6 +3,8 @@ package parser
 import (
 	"errors"
 	"log/slog"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -19,17 +21,25 @@ var (
 	ErrPaymentTypeNotFound = errors.New("payment type not found")
 	ErrAmountNotFound      = errors.New("amount not found")
 
-	categoryWords = map[string]model.Category{ //nolint:gochecknoglobals
-		"материалы":   model.CategoryMaterials,
-		"услуги":      model.CategoryLabor,
-		"инструменты": model.CategoryTools,
-		"мебель":      model.CategoryFurniture,
+	paymentWords = map[string]model.PaymentType{ //nolint:gochecknoglobals
+		"нал":      model.PaymentTypeCash,
+		"наличные": model.PaymentTypeCash,
+		"карта":    model.PaymentTypeCard,
 	}
 )
 
 const minWords = 2
 
-func Message(input string) (model.Expense, error) {
+// Message разбирает трату, записанную сейчас. См. MessageAt.
+func Message(categories *model.CategoryRegistry, input string) (model.Expense, error) {
+	return MessageAt(categories, input, time.Now())
+}
+
+// MessageAt разбирает трату «[тип_оплаты] [сумма] [категория] [описание]». Дата в тексте
+// («вчера», «12.10», «в пятницу») переносит трату на этот день, см. Date; без даты трата
+// записывается на now. Валюта суммы («$20», «15 евро») попадает в Currency; без нее
+// Currency пустая — это валюта проекта. Категория ищется по ключевым словам из categories.
+func MessageAt(categories *model.CategoryRegistry, input string, now time.Time) (model.Expense, error) {
 	input = strings.TrimSpace(strings.ToLower(input))
 	if input == "" {
 		return model.Expense{}, ErrEmptyMessage
@@ -40,46 +50,43 @@ func Message(input string) (model.Expense, error) {
 		return model.Expense{}, ErrNotEnoughData
 	}
 
-	var (
-		paymentType      model.PaymentType
-		category         = model.CategoryUnexpected
-		amount           decimal.Decimal
-		foundPaymentType bool
-		foundAmount      bool
-		descriptionWords = make([]string, 0, len(words)-minWords)
-	)
+	createdAt, words := splitDate(words, now)
 
-	for i := range words {
-		word := words[i]
+	paymentIndex := slices.IndexFunc(words, func(word string) bool {
+		_, ok := paymentWords[word]
 
-		// Платеж
-		if !foundPaymentType {
-			switch word {
-			case "нал", "наличные":
-				paymentType = model.PaymentTypeCash
-				foundPaymentType = true
+		return ok
+	})
+	if paymentIndex < 0 {
+		return model.Expense{}, ErrPaymentTypeNotFound
+	}
 
-				continue
-			case "карта":
-				paymentType = model.PaymentTypeCard
-				foundPaymentType = true
+	amount, currency, amountStart, amountLen := findAmount(words, paymentIndex)
+	if amountLen == 0 {
+		return model.Expense{}, ErrAmountNotFound
+	}
 
-				continue
-			}
+	// Валюта может стоять и отдельным словом перед суммой: «usd 20», «$ 20».
+	if currency == "" && amountStart > 0 && amountStart-1 != paymentIndex {
+		if code, ok := currencyWord(words[amountStart-1]); ok {
+			currency = code
+			amountStart--
+			amountLen++
 		}
+	}
 
-		// Сумма (после типа оплаты)
-		if foundPaymentType && !foundAmount {
-			if amt, err := decimal.NewFromString(word); err == nil {
-				amount = amt
-				foundAmount = true
+	var (
+		category         = model.CategoryUnexpected
+		descriptionWords = make([]string, 0, len(words)-minWords)
+	)
 
-				continue
-			}
+	for i, word := range words {
+		if i == paymentIndex || (i >= amountStart && i < amountStart+amountLen) {
+			continue
 		}
 
-		// Категория
-		if cat, ok := categoryWords[word]; ok {
+		// Категория — по ключевым словам из настроек категорий
+		if cat, ok := categories.ByKeyword(word); ok {
 			category = cat
 
 			continue
@@ -89,27 +96,60 @@ func Message(input string) (model.Expense, error) {
 		descriptionWords = append(descriptionWords, word)
 	}
 
-	if !foundPaymentType {
-		return model.Expense{}, ErrPaymentTypeNotFound
-	}
-
-	if !foundAmount {
-		return model.Expense{}, ErrAmountNotFound
-	}
-
-	createdAt := time.Now()
-
 	return model.Expense{
 		ID:          uuid.New(),
 		CreatedAt:   createdAt,
-		UpdatedAt:   createdAt,
+		UpdatedAt:   now,
 		Category:    category,
-		PaymentType: paymentType,
+		PaymentType: paymentWords[words[paymentIndex]],
 		Description: strings.Join(descriptionWords, " "),
 		Amount:      amount,
+		Currency:    currency,
 	}, nil
 }
 
+// splitDate убирает из слов дату траты и возвращает время траты. «10.05» без года
+// может быть и суммой, поэтому датой оно считается, только если сумма найдется
+// среди остальных слов.
+func splitDate(words []string, now time.Time) (time.Time, []string) {
+	date, start, n := findDate(words, now)
+	if n == 0 {
+		return now, words
+	}
+
+	rest := slices.Delete(slices.Clone(words), start, start+n)
+
+	if n == 1 && isDayMonthWithoutYear(words[start]) {
+		paymentIndex := slices.IndexFunc(rest, func(word string) bool {
+			_, ok := paymentWords[word]
+
+			return ok
+		})
+
+		if paymentIndex >= 0 {
+			if _, _, _, amountLen := findAmount(rest, paymentIndex); amountLen == 0 {
+				return now, words
+			}
+		}
+	}
+
+	return withClock(date, now), rest
+}
+
+// findAmount ищет сумму сначала после слова оплаты, потом перед ним.
+// Возвращает сумму, код валюты, индекс первого слова суммы и число слов (0 — не нашли).
+func findAmount(words []string, paymentIndex int) (decimal.Decimal, string, int, int) {
+	for _, bounds := range [][2]int{{paymentIndex + 1, len(words)}, {0, paymentIndex}} {
+		for i := bounds[0]; i < bounds[1]; i++ {
+			if amount, currency, n := amountAt(words[:bounds[1]], i); n > 0 {
+				return amount, currency, i, n
+			}
+		}
+	}
+
+	return decimal.Zero, "", 0, 0
+}
+
 func Integer(input string, defaultValue int) int {
 	input = strings.TrimSpace(input)
 
@@ -125,6 +165,40 @@ func Integer(input string, defaultValue int) int {
 	return num
 }
 
+// PaymentType ищет тип оплаты по ключевому слову.
+func PaymentType(input string) (model.PaymentType, bool) {
+	pt, ok := paymentWords[strings.TrimSpace(strings.ToLower(input))]
+
+	return pt, ok
+}
+
+// Currency ищет валюту по обозначению («$», «евро», «руб») или коду ISO 4217 («USD»).
+func Currency(input string) (string, bool) {
+	input = strings.TrimSpace(strings.ToLower(input))
+
+	if code, ok := currencyWord(input); ok {
+		return code, true
+	}
+
+	return model.NormalizeCurrency(input)
+}
+
+// Category ищет категорию из categories по ключевому слову или числовому ID.
+func Category(categories *model.CategoryRegistry, input string) (model.Category, bool) {
+	input = strings.TrimSpace(strings.ToLower(input))
+
+	if cat, ok := categories.ByKeyword(input); ok {
+		return cat, true
+	}
+
+	id := Integer(input, 0)
+	if id > 0 && id <= math.MaxUint8 && categories.IsValid(model.Category(id)) {
+		return model.Category(id), true
+	}
+
+	return 0, false
+}
+
 func ID(input string) model.ExpenseID {
 	input = strings.TrimSpace(input)
 
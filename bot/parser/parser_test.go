@@ -12,6 +12,11 @@ import (
 	"kudadeli/parser"
 )
 
+// defaultCategories — встроенные категории, с которыми создается база.
+func defaultCategories() *model.CategoryRegistry {
+	return model.NewCategoryRegistry(model.DefaultCategories())
+}
+
 func TestMessage(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -59,6 +64,171 @@ func TestMessage(t *testing.T) {
 				Description: "демонтаж",
 			},
 		},
+		{
+			name:  "прочее",
+			input: "нал 300 прочее вывоз мусора",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(300),
+				Description: "вывоз мусора",
+			},
+		},
+		{
+			name:  "разряды через пробел",
+			input: "нал 1 500 краска ванная",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(1500),
+				Description: "краска ванная",
+			},
+		},
+		{
+			name:  "тысячи с запятой и суффиксом к",
+			input: "карта 1,5к плитка",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(1500),
+				Description: "плитка",
+			},
+		},
+		{
+			name:  "латинская k",
+			input: "нал 3.2k услуги электрик",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryLabor,
+				Amount:      decimal.NewFromInt(3200),
+				Description: "электрик",
+			},
+		},
+		{
+			name:  "сумма похожа на дату",
+			input: "карта 10.05 скотч",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.RequireFromString("10.05"),
+				Description: "скотч",
+			},
+		},
+		{
+			name:  "рубли слитно",
+			input: "карта 1500р грунтовка",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(1500),
+				Currency:    "RUB",
+				Description: "грунтовка",
+			},
+		},
+		{
+			name:  "умножение",
+			input: "нал 2x750 мешки для мусора",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(1500),
+				Description: "мешки для мусора",
+			},
+		},
+		{
+			name:  "умножение через пробелы и кириллическую х",
+			input: "карта 3 х 450 материалы профиль",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryMaterials,
+				Amount:      decimal.NewFromInt(1350),
+				Description: "профиль",
+			},
+		},
+		{
+			name:  "сложение",
+			input: "нал 1200+350 доставка и подъем",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(1550),
+				Description: "доставка и подъем",
+			},
+		},
+		{
+			name:  "тыс и руб отдельными словами",
+			input: "карта 15 тыс руб кухня предоплата",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(15000),
+				Currency:    "RUB",
+				Description: "кухня предоплата",
+			},
+		},
+		{
+			name:  "копейки через запятую",
+			input: "карта 249,90 саморезы",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.RequireFromString("249.90"),
+				Description: "саморезы",
+			},
+		},
+		{
+			name:  "сумма перед типом оплаты",
+			input: "700 нал такси до строительного",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(700),
+				Description: "такси до строительного",
+			},
+		},
+		{
+			name:  "число в описании после суммы",
+			input: "нал 1500 к двери 2 петли",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(1500),
+				Description: "к двери 2 петли",
+			},
+		},
+		{
+			name:  "доллары знаком",
+			input: "карта $20 шпатель",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(20),
+				Currency:    "USD",
+				Description: "шпатель",
+			},
+		},
+		{
+			name:  "евро словом после суммы",
+			input: "нал 15 евро кофе мастерам",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCash,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(15),
+				Currency:    "EUR",
+				Description: "кофе мастерам",
+			},
+		},
+		{
+			name:  "валюта словом перед суммой",
+			input: "карта usd 1 500 плитка",
+			want: model.Expense{
+				PaymentType: model.PaymentTypeCard,
+				Category:    model.CategoryUnexpected,
+				Amount:      decimal.NewFromInt(1500),
+				Currency:    "USD",
+				Description: "плитка",
+			},
+		},
 		{
 			name:        "пустая строка",
 			input:       "",
@@ -83,7 +253,7 @@ func TestMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			expense, err := parser.Message(tt.input)
+			expense, err := parser.Message(defaultCategories(), tt.input)
 			if tt.expectError != nil {
 				require.Error(t, err)
 				require.Equal(t, tt.expectError, err)
@@ -95,6 +265,7 @@ func TestMessage(t *testing.T) {
 			require.Equal(t, tt.want.PaymentType, expense.PaymentType)
 			require.Equal(t, tt.want.Category, expense.Category)
 			require.True(t, tt.want.Amount.Equal(expense.Amount))
+			require.Equal(t, tt.want.Currency, expense.Currency)
 			require.Equal(t, tt.want.Description, expense.Description)
 
 			// Проверим что ID и время установлены
@@ -104,3 +275,182 @@ func TestMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{input: "1500", want: "1500", ok: true},
+		{input: "1500.50", want: "1500.5", ok: true},
+		{input: "1 500", want: "1500", ok: true},
+		{input: "1\u00a0500", want: "1500", ok: true},
+		{input: "1'500", want: "1500", ok: true},
+		{input: "12 000,50", want: "12000.5", ok: true},
+		{input: "1.500", want: "1500", ok: true},
+		{input: "1,500", want: "1500", ok: true},
+		{input: "1.500.000", want: "1500000", ok: true},
+		{input: "1.500,50", want: "1500.5", ok: true},
+		{input: "1,500.50", want: "1500.5", ok: true},
+		{input: "0,500", want: "0.5", ok: true},
+		{input: "1,5", want: "1.5", ok: true},
+		{input: "1,5к", want: "1500", ok: true},
+		{input: "1,500к", want: "1500", ok: true},
+		{input: "3.2k", want: "3200", ok: true},
+		{input: "15 тыс.", want: "15000", ok: true},
+		{input: "1500р", want: "1500", ok: true},
+		{input: "1500 руб.", want: "1500", ok: true},
+		{input: "1500₽", want: "1500", ok: true},
+		{input: "2x750", want: "1500", ok: true},
+		{input: "2×750", want: "1500", ok: true},
+		{input: "2*750+100", want: "1600", ok: true},
+		{input: "1к+500", want: "1500", ok: true},
+		{input: "", ok: false},
+		{input: "0", ok: false},
+		{input: "-100", ok: false},
+		{input: "1e3", ok: false},
+		{input: "1500+", ok: false},
+		{input: "1 50", ok: false},
+		{input: "тысяча", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parser.Amount(tt.input)
+			require.Equal(t, tt.ok, ok)
+
+			if tt.ok {
+				require.True(t, decimal.RequireFromString(tt.want).Equal(got), "got %s", got)
+			}
+		})
+	}
+}
+
+func TestAmountCurrency(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     string
+		currency string
+		ok       bool
+	}{
+		{input: "1500", want: "1500", currency: "", ok: true},
+		{input: "1500р", want: "1500", currency: "RUB", ok: true},
+		{input: "$20", want: "20", currency: "USD", ok: true},
+		{input: "20$", want: "20", currency: "USD", ok: true},
+		{input: "20 usd", want: "20", currency: "USD", ok: true},
+		{input: "100 долларов", want: "100", currency: "USD", ok: true},
+		{input: "€15,50", want: "15.5", currency: "EUR", ok: true},
+		{input: "1,5к евро", want: "1500", currency: "EUR", ok: true},
+		{input: "2x€10", want: "20", currency: "EUR", ok: true},
+		{input: "$10+€5", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, currency, ok := parser.AmountCurrency(tt.input)
+			require.Equal(t, tt.ok, ok)
+
+			if tt.ok {
+				require.True(t, decimal.RequireFromString(tt.want).Equal(got), "got %s", got)
+				require.Equal(t, tt.currency, currency)
+			}
+		})
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{input: "$", want: "USD", ok: true},
+		{input: "Евро", want: "EUR", ok: true},
+		{input: "руб", want: "RUB", ok: true},
+		{input: "gbp", want: "GBP", ok: true},
+		{input: "доллары", ok: false},
+		{input: "us", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parser.Currency(tt.input)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCategory(t *testing.T) {
+	tests := []struct {
+		input string
+		want  model.Category
+		ok    bool
+	}{
+		{input: "материалы", want: model.CategoryMaterials, ok: true},
+		{input: "Услуги", want: model.CategoryLabor, ok: true},
+		{input: "4", want: model.CategoryFurniture, ok: true},
+		{input: "261", ok: false},
+		{input: "0", ok: false},
+		{input: "обои", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parser.Category(defaultCategories(), tt.input)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMessagesAt(t *testing.T) {
+	now := time.Date(2026, 10, 18, 14, 5, 0, 0, time.UTC)
+
+	input := "нал 1500 краска\n\nкарта обои\n  карта 2x750 материалы валик  \nвчера нал 300 такси"
+	lines := parser.MessagesAt(defaultCategories(), input, now)
+	require.Len(t, lines, 4)
+
+	require.Equal(t, 1, lines[0].Number)
+	require.NoError(t, lines[0].Err)
+	require.True(t, decimal.NewFromInt(1500).Equal(lines[0].Expense.Amount))
+
+	require.Equal(t, 3, lines[1].Number)
+	require.Equal(t, "карта обои", lines[1].Text)
+	require.ErrorIs(t, lines[1].Err, parser.ErrAmountNotFound)
+
+	require.Equal(t, 4, lines[2].Number)
+	require.NoError(t, lines[2].Err)
+	require.Equal(t, model.CategoryMaterials, lines[2].Expense.Category)
+	require.Equal(t, "валик", lines[2].Expense.Description)
+
+	require.NoError(t, lines[3].Err)
+	require.Equal(t, 17, lines[3].Expense.CreatedAt.Day())
+
+	require.NotEqual(t, lines[0].Expense.ID, lines[2].Expense.ID)
+}
+
+func TestIsStrict(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "нал 1500 краска", want: true},
+		{input: "Карта 1,5к плитка ванная", want: true},
+		{input: "нал $20 шпатель", want: true},
+		{input: "нал 1500 краска\n\nкарта 3200 двери", want: true},
+		{input: "нал 1500", want: false},
+		{input: "краска нал 1500", want: false},
+		{input: "карта дома, вечером 1500 переведу", want: false},
+		{input: "нал 1500 краска\nкупил еще валик", want: false},
+		{input: "привет всем", want: false},
+		{input: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.want, parser.IsStrict(tt.input))
+		})
+	}
+}
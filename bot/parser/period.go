@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPeriod = errors.New("invalid period")
+
+var dateLayouts = []string{"02.01.2006", "2.1.2006", time.DateOnly} //nolint:gochecknoglobals
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func parseDate(input string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, input, loc); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Period разбирает период отчета: today, week, month, all (или по-русски) либо
+// явный диапазон дат «01.05.2025-31.05.2025» / «01.05.2025 31.05.2025».
+// Возвращает полуинтервал [from, to) в часовом поясе now. Для all обе границы нулевые,
+// без аргументов — текущий месяц.
+func Period(args []string, now time.Time) (time.Time, time.Time, error) {
+	input := strings.ToLower(strings.TrimSpace(strings.Join(args, " ")))
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	switch input {
+	case "today", "сегодня", "день":
+		return today, tomorrow, nil
+	case "week", "неделя":
+		// Неделя начинается с понедельника.
+		offset := (int(today.Weekday()) + 6) % 7 //nolint:mnd
+
+		return today.AddDate(0, 0, -offset), tomorrow, nil
+	case "", "month", "месяц":
+		return today.AddDate(0, 0, 1-today.Day()), tomorrow, nil
+	case "all", "все", "всё":
+		return time.Time{}, time.Time{}, nil
+	}
+
+	parts := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ' ' || r == '—' || r == '–'
+	})
+
+	if len(parts) == 1 {
+		if from, to, ok := strings.Cut(parts[0], "-"); ok && !strings.Contains(to, "-") {
+			parts = []string{from, to}
+		}
+	}
+
+	if len(parts) != 2 { //nolint:mnd
+		return time.Time{}, time.Time{}, ErrInvalidPeriod
+	}
+
+	from, ok := parseDate(parts[0], now.Location())
+	if !ok {
+		return time.Time{}, time.Time{}, ErrInvalidPeriod
+	}
+
+	to, ok := parseDate(parts[1], now.Location())
+	if !ok || to.Before(from) {
+		return time.Time{}, time.Time{}, ErrInvalidPeriod
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
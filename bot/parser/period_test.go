@@ -0,0 +1,51 @@
+package parser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/parser"
+)
+
+func TestPeriod(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2025, 5, 15, 18, 30, 0, 0, loc) // четверг
+
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantFrom    time.Time
+		wantTo      time.Time
+		expectError error
+	}{
+		{name: "по умолчанию месяц", args: nil, wantFrom: date(2025, 5, 1), wantTo: date(2025, 5, 16)},
+		{name: "сегодня", args: []string{"сегодня"}, wantFrom: date(2025, 5, 15), wantTo: date(2025, 5, 16)},
+		{name: "week", args: []string{"week"}, wantFrom: date(2025, 5, 12), wantTo: date(2025, 5, 16)},
+		{name: "all", args: []string{"all"}},
+		{name: "диапазон через дефис", args: []string{"01.04.2025-30.04.2025"}, wantFrom: date(2025, 4, 1), wantTo: date(2025, 5, 1)},
+		{name: "диапазон через пробел", args: []string{"2025-04-01", "2025-04-10"}, wantFrom: date(2025, 4, 1), wantTo: date(2025, 4, 11)},
+		{name: "конец раньше начала", args: []string{"10.04.2025-01.04.2025"}, expectError: parser.ErrInvalidPeriod},
+		{name: "мусор", args: []string{"вчерашний"}, expectError: parser.ErrInvalidPeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := parser.Period(tt.args, now)
+			if tt.expectError != nil {
+				require.ErrorIs(t, err, tt.expectError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, tt.wantFrom.Equal(from), "from: want %s, got %s", tt.wantFrom, from)
+			require.True(t, tt.wantTo.Equal(to), "to: want %s, got %s", tt.wantTo, to)
+		})
+	}
+}
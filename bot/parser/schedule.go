@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+const (
+	scheduleFields = 5
+	// scheduleHorizon — сколько дней вперед ищем срабатывание: «29 февраля в понедельник»
+	// бывает раз в несколько лет, дальше смотреть нет смысла.
+	scheduleHorizon = 8 * 366
+)
+
+// scheduleAliases — сокращения для частых расписаний. В отличие от обычного cron,
+// срабатывают в 9 утра, а не в полночь: так напоминание приходит в рабочее время.
+var scheduleAliases = map[string]string{ //nolint:gochecknoglobals
+	"@daily":      "0 9 * * *",
+	"ежедневно":   "0 9 * * *",
+	"@weekly":     "0 9 * * 1",
+	"еженедельно": "0 9 * * 1",
+	"@monthly":    "0 9 1 * *",
+	"ежемесячно":  "0 9 1 * *",
+}
+
+// Schedule — расписание в формате cron из пяти полей: минута, час, день месяца, месяц,
+// день недели (0 и 7 — воскресенье). Поле — «*», число, диапазон «1-5», шаг «*/2» или
+// «1-10/3» и списки через запятую.
+type Schedule struct {
+	minute, hour, day, month, weekday uint64
+	// Как в cron: если ограничены и день месяца, и день недели, подходит любой из них.
+	anyDay, anyWeekday bool
+}
+
+type scheduleField struct {
+	name     string
+	min, max int
+}
+
+//nolint:gochecknoglobals,mnd
+var scheduleFieldRanges = [scheduleFields]scheduleField{
+	{"минута", 0, 59},
+	{"час", 0, 23},
+	{"день месяца", 1, 31},
+	{"месяц", 1, 12},
+	{"день недели", 0, 7},
+}
+
+// parseScheduleField разбирает одно поле в битовую маску допустимых значений.
+func parseScheduleField(input string, field scheduleField) (uint64, error) {
+	var mask uint64
+
+	for part := range strings.SplitSeq(input, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+
+		if hasStep {
+			var err error
+
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("%w: %s: шаг %q", ErrInvalidSchedule, field.name, stepPart)
+			}
+		}
+
+		from, to := field.min, field.max
+
+		if rangePart != "*" {
+			low, high, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+
+			from, err = strconv.Atoi(low)
+			if err != nil {
+				return 0, fmt.Errorf("%w: %s: %q", ErrInvalidSchedule, field.name, part)
+			}
+
+			to = from
+
+			switch {
+			case isRange:
+				to, err = strconv.Atoi(high)
+				if err != nil {
+					return 0, fmt.Errorf("%w: %s: %q", ErrInvalidSchedule, field.name, part)
+				}
+			case hasStep:
+				to = field.max
+			}
+		}
+
+		if from < field.min || to > field.max || from > to {
+			return 0, fmt.Errorf("%w: %s: %q вне %d-%d", ErrInvalidSchedule, field.name, part, field.min, field.max)
+		}
+
+		for v := from; v <= to; v += step {
+			mask |= 1 << v
+		}
+	}
+
+	return mask, nil
+}
+
+// ParseSchedule разбирает расписание cron или одно из сокращений: @daily, @weekly,
+// @monthly (ежедневно, еженедельно, ежемесячно).
+func ParseSchedule(input string) (Schedule, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if alias, ok := scheduleAliases[input]; ok {
+		input = alias
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) != scheduleFields {
+		return Schedule{}, fmt.Errorf("%w: нужно %d полей, а не %d", ErrInvalidSchedule, scheduleFields, len(fields))
+	}
+
+	var (
+		masks [scheduleFields]uint64
+		err   error
+	)
+
+	for i, field := range fields {
+		masks[i], err = parseScheduleField(field, scheduleFieldRanges[i])
+		if err != nil {
+			return Schedule{}, err
+		}
+	}
+
+	const sunday = 7
+
+	// Воскресенье можно писать и как 7.
+	if masks[4]&(1<<sunday) != 0 {
+		masks[4] = masks[4]&^(1<<sunday) | 1
+	}
+
+	s := Schedule{
+		minute:     masks[0],
+		hour:       masks[1],
+		day:        masks[2],
+		month:      masks[3],
+		weekday:    masks[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+
+	// «0 9 31 2 *» синтаксически верно, но никогда не сработает.
+	if _, ok := s.Next(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)); !ok {
+		return Schedule{}, fmt.Errorf("%w: расписание никогда не срабатывает", ErrInvalidSchedule)
+	}
+
+	return s, nil
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	day := s.day&(1<<t.Day()) != 0
+	weekday := s.weekday&(1<<int(t.Weekday())) != 0
+
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Next возвращает первое срабатывание строго после after в часовом поясе after.
+// false — срабатываний в обозримом будущем нет.
+func (s Schedule) Next(after time.Time) (time.Time, bool) {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(0, 0, scheduleHorizon)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
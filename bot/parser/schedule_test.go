@@ -0,0 +1,54 @@
+package parser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/parser"
+)
+
+func TestScheduleNext(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// Суббота.
+	now := time.Date(2026, time.October, 17, 10, 30, 0, 0, loc)
+
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{schedule: "0 9 * * *", want: time.Date(2026, time.October, 18, 9, 0, 0, 0, loc)},
+		{schedule: "*/15 * * * *", want: time.Date(2026, time.October, 17, 10, 45, 0, 0, loc)},
+		{schedule: "@weekly", want: time.Date(2026, time.October, 19, 9, 0, 0, 0, loc)},
+		{schedule: "еженедельно", want: time.Date(2026, time.October, 19, 9, 0, 0, 0, loc)},
+		{schedule: "@monthly", want: time.Date(2026, time.November, 1, 9, 0, 0, 0, loc)},
+		{schedule: "0 18 * * 5,7", want: time.Date(2026, time.October, 18, 18, 0, 0, 0, loc)},
+		{schedule: "30 10 17 * *", want: time.Date(2026, time.November, 17, 10, 30, 0, 0, loc)},
+		{schedule: "0 12 29 2 *", want: time.Date(2028, time.February, 29, 12, 0, 0, 0, loc)},
+		// День месяца или день недели, как в cron: 20-е число или ближайший понедельник.
+		{schedule: "0 9 20 * 1", want: time.Date(2026, time.October, 19, 9, 0, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			schedule, err := parser.ParseSchedule(tt.schedule)
+			require.NoError(t, err)
+
+			got, ok := schedule.Next(now)
+			require.True(t, ok)
+			require.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, input := range []string{"", "0 9 * *", "60 9 * * *", "0 9 0 * *", "0 9 * * 8", "0 9 */0 * *", "0 9 31 2 *", "x 9 * * *"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := parser.ParseSchedule(input)
+			require.ErrorIs(t, err, parser.ErrInvalidSchedule)
+		})
+	}
+}
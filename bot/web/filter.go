@@ -0,0 +1,200 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+const maxPageLimit = 1000
+
+var errInvalidParam = errors.New("invalid query parameter")
+
+// queryValues возвращает значения параметра, переданные как повтором (?a=1&a=2),
+// так и через запятую (?a=1,2).
+func queryValues(query url.Values, key string) []string {
+	var result []string
+
+	for _, value := range query[key] {
+		for part := range strings.SplitSeq(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+
+	return result
+}
+
+func parseIDs[T ~byte](query url.Values, key string, isValid func(T) bool) ([]T, error) {
+	values := queryValues(query, key)
+	result := make([]T, 0, len(values))
+
+	for _, value := range values {
+		id, err := strconv.ParseUint(value, 10, 8)
+		if err != nil || !isValid(T(id)) {
+			return nil, fmt.Errorf("%w: %s=%s", errInvalidParam, key, value)
+		}
+
+		result = append(result, T(id))
+	}
+
+	return result, nil
+}
+
+func parseUserIDs(query url.Values, key string) ([]int64, error) {
+	values := queryValues(query, key)
+	result := make([]int64, 0, len(values))
+
+	for _, value := range values {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s=%s", errInvalidParam, key, value)
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+// locationParam возвращает часовой пояс из параметра tz (по умолчанию defaultLoc).
+func locationParam(query url.Values, defaultLoc *time.Location) (*time.Location, error) {
+	tz := query.Get("tz")
+	if tz == "" {
+		return defaultLoc, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tz=%s", errInvalidParam, tz)
+	}
+
+	return loc, nil
+}
+
+// parseTimeParam принимает RFC3339 или дату YYYY-MM-DD в часовом поясе loc.
+// Для endOfDay дата означает конец этого дня (начало следующего).
+func parseTimeParam(query url.Values, key string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s=%s", errInvalidParam, key, value)
+	}
+
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+func parseAmountParam(query url.Values, key string) (decimal.NullDecimal, error) {
+	value := query.Get(key)
+	if value == "" {
+		return decimal.NullDecimal{}, nil
+	}
+
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.NullDecimal{}, fmt.Errorf("%w: %s=%s", errInvalidParam, key, value)
+	}
+
+	return decimal.NewNullDecimal(amount), nil
+}
+
+// parseExpenseFilter разбирает параметры выборки трат:
+// from, to, tz, category, paymentType, userId, minAmount, maxAmount, q, sort, order, limit, cursor.
+// Выборка всегда ограничена проектом запроса (см. projectMiddleware).
+func parseExpenseFilter(r *http.Request, categories *model.CategoryRegistry,
+	defaultLoc *time.Location) (database.ExpenseFilter, error) { //nolint:cyclop,funlen
+	var (
+		query  = r.URL.Query()
+		filter = database.ExpenseFilter{ProjectIDs: []model.ProjectID{projectIDFromContext(r.Context())}}
+		err    error
+	)
+
+	loc, err := locationParam(query, defaultLoc)
+	if err != nil {
+		return filter, err
+	}
+
+	if filter.From, err = parseTimeParam(query, "from", loc, false); err != nil {
+		return filter, err
+	}
+
+	if filter.To, err = parseTimeParam(query, "to", loc, true); err != nil {
+		return filter, err
+	}
+
+	if filter.Categories, err = parseIDs(query, "category", categories.IsValid); err != nil {
+		return filter, err
+	}
+
+	if filter.PaymentTypes, err = parseIDs(query, "paymentType", model.PaymentType.IsValid); err != nil {
+		return filter, err
+	}
+
+	if filter.UserIDs, err = parseUserIDs(query, "userId"); err != nil {
+		return filter, err
+	}
+
+	// Подрядчик видит только свои траты, какой бы userId ни попросил.
+	if ctx := r.Context(); !roleFromContext(ctx).Can(model.PermissionViewAll) {
+		userID, _ := userIDFromContext(ctx)
+		filter.UserIDs = []int64{userID}
+	}
+
+	if filter.MinAmount, err = parseAmountParam(query, "minAmount"); err != nil {
+		return filter, err
+	}
+
+	if filter.MaxAmount, err = parseAmountParam(query, "maxAmount"); err != nil {
+		return filter, err
+	}
+
+	filter.Description = strings.TrimSpace(query.Get("q"))
+
+	sort, ok := database.ParseSortField(query.Get("sort"))
+	if !ok {
+		return filter, fmt.Errorf("%w: sort=%s", errInvalidParam, query.Get("sort"))
+	}
+
+	filter.Sort = sort
+
+	switch order := query.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		filter.Asc = true
+	default:
+		return filter, fmt.Errorf("%w: order=%s", errInvalidParam, order)
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 || filter.Limit > maxPageLimit {
+			return filter, fmt.Errorf("%w: limit must be between 1 and %d", errInvalidParam, maxPageLimit)
+		}
+	}
+
+	filter.Cursor = query.Get("cursor")
+
+	return filter, nil
+}
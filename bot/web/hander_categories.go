@@ -2,26 +2,47 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"kudadeli/database"
 	"kudadeli/model"
 	"log/slog"
+	"math"
 	"net/http"
-	"time"
-)
-
-type categoryJSON struct {
-	ID   byte   `json:"id"`
-	Name string `json:"name"`
-}
+	"strconv"
 
-func categoriesHandler() http.HandlerFunc {
-	etag := fmt.Sprintf(`"W/%d"`, time.Now().Unix())
+	"github.com/go-chi/chi/v5"
+)
 
+// categoriesHandler отдает все категории, включая архивные: они нужны, чтобы показать старые траты.
+// ETag считается от содержимого, поэтому меняется вместе с категориями.
+func categoriesHandler(db Database) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		categories, err := db.Categories(ctx)
+		if err != nil {
+			writeDatabaseError(ctx, w, "list categories", err)
+
+			return
+		}
+
+		jsonData, err := json.Marshal(categories)
+		if err != nil {
+			slog.ErrorContext(ctx, "json marshal", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		hash := fnv.New64a()
+		_, _ = hash.Write(jsonData)
+		etag := fmt.Sprintf(`W/"%x"`, hash.Sum64())
+
 		h := w.Header()
 		h.Set("ETag", etag)
+		h.Set("Cache-Control", "private, no-cache")
 
 		if match := r.Header.Get("If-None-Match"); match == etag {
 			slog.DebugContext(ctx, "category not modified")
@@ -30,23 +51,120 @@ func categoriesHandler() http.HandlerFunc {
 			return
 		}
 
-		categories := model.Categories()
+		h.Set("Content-Type", "application/json; charset=utf-8")
+
+		if _, err := w.Write(append(jsonData, '\n')); err != nil {
+			slog.ErrorContext(ctx, "write categories", "error", err)
+		}
+	}
+}
+
+type categoryRequest struct {
+	Name      *string   `json:"name"`
+	Emoji     *string   `json:"emoji"`
+	SortOrder *int      `json:"sortOrder"`
+	Archived  *bool     `json:"archived"`
+	Keywords  *[]string `json:"keywords"`
+}
+
+func (req categoryRequest) apply(c *model.CategoryInfo) {
+	if req.Name != nil {
+		c.Name = *req.Name
+	}
+
+	if req.Emoji != nil {
+		c.Emoji = *req.Emoji
+	}
+
+	if req.SortOrder != nil {
+		c.SortOrder = *req.SortOrder
+	}
+
+	if req.Archived != nil {
+		c.Archived = *req.Archived
+	}
+
+	if req.Keywords != nil {
+		c.Keywords = *req.Keywords
+	}
+}
+
+// writeCategoryError отвечает 400 на невалидную категорию и 409 на конфликт названий
+// или ключевых слов.
+func writeCategoryError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, model.ErrInvalidCategory):
+		writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrCategoryExists),
+		errors.Is(err, model.ErrKeywordTaken),
+		errors.Is(err, database.ErrTooManyCategories):
+		writeErrorWithCode(w, err.Error(), http.StatusConflict)
+	default:
+		writeDatabaseError(r.Context(), w, op, err)
+	}
+}
+
+func createCategoryHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() { _ = r.Body.Close() }()
+
+		var req categoryRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		var category model.CategoryInfo
 
-		var jsonData = make([]categoryJSON, len(categories))
+		req.apply(&category)
 
-		for i := range categories {
-			jsonData[i] = categoryJSON{
-				ID:   byte(categories[i]),
-				Name: categories[i].String(),
-			}
+		category, err := db.AddCategory(r.Context(), category)
+		if err != nil {
+			writeCategoryError(w, r, "add category", err)
+
+			return
 		}
 
-		h.Set("Content-Type", "application/json; charset=utf-8")
-		h.Set("Cache-Control", "public, max-age=3600, must-revalidate")
+		writeJSON(w, http.StatusCreated, category)
+	}
+}
 
-		if err := json.NewEncoder(w).Encode(jsonData); err != nil {
-			slog.ErrorContext(ctx, "writeString: %w", "error", err)
-			writeError(w, err.Error())
+// updateCategoryHandler частично обновляет категорию: переданные поля заменяются.
+func updateCategoryHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() { _ = r.Body.Close() }()
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || id <= 0 || id > math.MaxUint8 {
+			writeErrorWithCode(w, "invalid category ID", http.StatusBadRequest)
+
+			return
+		}
+
+		var req categoryRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		category, ok := db.CategoryRegistry().Info(model.Category(id))
+		if !ok {
+			writeDatabaseError(r.Context(), w, "get category", model.ErrNotFound)
+
+			return
+		}
+
+		req.apply(&category)
+
+		if err := db.EditCategory(r.Context(), category); err != nil {
+			writeCategoryError(w, r, "edit category", err)
+
+			return
 		}
+
+		category, _ = db.CategoryRegistry().Info(category.ID)
+		writeJSON(w, http.StatusOK, category)
 	}
 }
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"kudadeli/model"
+)
+
+type attachmentJSON struct {
+	model.Attachment
+
+	URL string `json:"url"`
+}
+
+func attachmentsHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, ok := expenseIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		if _, err := projectExpense(ctx, db, id); err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
+		attachments, err := db.Attachments(ctx, id)
+		if err != nil {
+			writeDatabaseError(ctx, w, "list attachments", err)
+
+			return
+		}
+
+		jsonData := make([]attachmentJSON, len(attachments))
+
+		for i := range attachments {
+			jsonData[i] = attachmentJSON{
+				Attachment: attachments[i],
+				URL:        "/v1/expenses/" + id.String() + "/attachments/" + attachments[i].ID.String(),
+			}
+		}
+
+		writeJSON(w, http.StatusOK, jsonData)
+	}
+}
+
+func attachmentHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		expenseID, ok := expenseIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := uuid.Parse(chi.URLParam(r, "attachmentId"))
+		if err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if _, err := projectExpense(ctx, db, expenseID); err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
+		attachment, data, err := db.Attachment(ctx, expenseID, id)
+		if err != nil {
+			writeDatabaseError(ctx, w, "get attachment", err)
+
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", attachment.MIMEType)
+		h.Set("Content-Length", strconv.Itoa(len(data)))
+		h.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{
+			"filename": attachment.FileName,
+		}))
+		// Вложения не меняются, поэтому их можно долго хранить в кеше клиента.
+		h.Set("Cache-Control", "private, max-age=31536000, immutable")
+		h.Set("X-Content-Type-Options", "nosniff")
+
+		if _, err := w.Write(data); err != nil {
+			slog.ErrorContext(ctx, "write attachment", "error", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+)
+
+type budgetJSON struct {
+	CategoryID byte            `json:"categoryId"`
+	Category   string          `json:"category"`
+	Amount     decimal.Decimal `json:"amount"`
+	Spent      decimal.Decimal `json:"spent"`
+	Percent    decimal.Decimal `json:"percent"`
+}
+
+func budgetsHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		h := w.Header()
+
+		budgets, err := db.Budgets(ctx, projectIDFromContext(ctx))
+		if err != nil {
+			slog.ErrorContext(ctx, "db.Budgets", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		categories := db.CategoryRegistry()
+		jsonData := make([]budgetJSON, len(budgets))
+
+		for i := range budgets {
+			jsonData[i] = budgetJSON{
+				CategoryID: byte(budgets[i].Category),
+				Category:   categories.Name(budgets[i].Category),
+				Amount:     budgets[i].Amount,
+				Spent:      budgets[i].Spent,
+				Percent:    budgets[i].Percent().Round(2),
+			}
+		}
+
+		// Бюджет меняется независимо от трат, поэтому не кешируем по Last-Modified.
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		h.Set("Cache-Control", "private, no-cache")
+
+		if err := json.NewEncoder(w).Encode(jsonData); err != nil {
+			slog.ErrorContext(ctx, "json encode", "error", err)
+			writeError(w, err.Error())
+		}
+	}
+}
@@ -1,54 +1,78 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"kudadeli/database"
 	"kudadeli/model"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
-func expensesHandler(db Database) http.HandlerFunc {
+// expensesHandler отдает страницу трат по фильтру, с deleted — траты из корзины,
+// по умолчанию сначала недавно удаленные.
+func expensesHandler(db Database, loc *time.Location, deleted bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		h := w.Header()
 
-		lastModified, err := db.LatestUpdatedAt(ctx)
+		filter, err := parseExpenseFilter(r, db.CategoryRegistry(), loc)
 		if err != nil {
-			slog.ErrorContext(ctx, "db.LatestUpdatedAt:", "error", err)
-			writeError(w, err.Error())
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
 
 			return
 		}
 
-		// Проверяем If-Modified-Since
-		ifModifiedSince := r.Header.Get("If-Modified-Since")
-		if ifModifiedSince != "" {
-			clientSince, err := http.ParseTime(ifModifiedSince)
-			if err != nil {
-				slog.ErrorContext(ctx, "failed to parse If-Modified-Since", "error", err)
-			} else if isNotModified(clientSince, lastModified) {
-				slog.DebugContext(ctx, "expenses not modified", "lastModified", lastModified, "clientSince", clientSince)
-				w.WriteHeader(http.StatusNotModified)
+		if deleted {
+			filter.Deleted = true
 
-				return
+			if r.URL.Query().Get("sort") == "" {
+				filter.Sort = database.SortByDeletedAt
 			}
 		}
 
+		// Время изменения — по всему проекту: удаление или перенос траты меняют и выборки,
+		// в которых ее уже нет.
+		lastModified, err := db.ProjectChangedAt(ctx, projectIDFromContext(ctx))
+		if err != nil {
+			slog.ErrorContext(ctx, "db.ProjectChangedAt:", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		if checkNotModified(w, r, projectIDFromContext(ctx), lastModified) {
+			return
+		}
+
 		slog.DebugContext(ctx, "expenses query")
 
-		expenses, err := db.List(ctx, -1) // -1 means no limit
+		expenses, next, err := db.Find(ctx, filter)
+		if errors.Is(err, database.ErrInvalidCursor) {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
 		if err != nil {
-			slog.ErrorContext(ctx, "db.List: %w", "error", err)
+			slog.ErrorContext(ctx, "db.Find", "error", err)
 			writeError(w, err.Error())
 
 			return
 		}
 
-		lastModified = expenses.LatestUpdatedAt()
+		if next != "" {
+			h.Set("X-Next-Cursor", next)
+		}
 
 		h.Set("Content-Type", "application/json; charset=utf-8")
 		h.Set("Cache-Control", "private, must-revalidate")
@@ -66,13 +90,32 @@ func expensesHandler(db Database) http.HandlerFunc {
 			return
 		}
 
-		if err := json.NewEncoder(w).Encode(expenses); err != nil {
+		if err := json.NewEncoder(w).Encode(newExpensesResponse(db.CategoryRegistry(), expenses)); err != nil {
 			slog.ErrorContext(ctx, "writeString", "error", err)
 			writeError(w, err.Error())
 		}
 	}
 }
 
+// expenseResponse — трата в ответе API: категория отдается названием, а не ID.
+type expenseResponse struct {
+	model.Expense
+	Category string `json:"category"`
+}
+
+func newExpenseResponse(categories *model.CategoryRegistry, expense model.Expense) expenseResponse {
+	return expenseResponse{Expense: expense, Category: categories.Name(expense.Category)}
+}
+
+func newExpensesResponse(categories *model.CategoryRegistry, expenses model.Expenses) []expenseResponse {
+	result := make([]expenseResponse, len(expenses))
+	for i := range expenses {
+		result[i] = newExpenseResponse(categories, expenses[i])
+	}
+
+	return result
+}
+
 type updateExpenseCategoryRequest struct {
 	Category byte `json:"category" validate:"required,gt=0"`
 }
@@ -84,17 +127,13 @@ func updateExpenseCategoryHandler(db Database) http.HandlerFunc {
 		ctx := r.Context()
 		slog.DebugContext(ctx, "update category handler")
 
-		id, err := uuid.Parse(chi.URLParam(r, "id"))
-		if err != nil {
-			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
-
+		id, ok := expenseIDParam(w, r)
+		if !ok {
 			return
 		}
 
-		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
-
 		var req updateExpenseCategoryRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := decodeJSON(w, r, &req); err != nil {
 			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
 
 			return
@@ -102,15 +141,367 @@ func updateExpenseCategoryHandler(db Database) http.HandlerFunc {
 
 		category := model.Category(req.Category)
 
-		if !category.IsValid() {
+		if !db.CategoryRegistry().IsValid(category) {
 			writeErrorWithCode(w, "category ID must be positive byte", http.StatusBadRequest)
 
 			return
 		}
 
+		if _, err := editableExpense(ctx, db, id); err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
 		if err := db.UpdateCategory(ctx, id, model.Category(req.Category)); err != nil {
-			slog.ErrorContext(ctx, "UpdateCategory", "error", err)
-			writeError(w, "failed to update category")
+			writeDatabaseError(ctx, w, "update category", err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+var (
+	errMissingField   = errors.New("missing required field")
+	errInvalidExpense = errors.New("invalid expense")
+)
+
+type expenseRequest struct {
+	CreatedAt   *time.Time       `json:"createdAt"`
+	Category    *byte            `json:"category"`
+	PaymentType *byte            `json:"paymentType"`
+	Description *string          `json:"description"`
+	Amount      *decimal.Decimal `json:"amount"`
+	Currency    *string          `json:"currency"`
+}
+
+// apply переносит заданные поля запроса в трату. При partial=false все поля,
+// кроме даты, описания и валюты, обязательны. Без валюты у новой траты — валюта
+// проекта, у существующей — прежняя.
+func (req expenseRequest) apply(expense *model.Expense, categories *model.CategoryRegistry, partial bool) error {
+	if !partial && (req.Category == nil || req.PaymentType == nil || req.Amount == nil) {
+		return fmt.Errorf("%w: category, paymentType and amount are required", errMissingField)
+	}
+
+	if req.CreatedAt != nil {
+		expense.CreatedAt = *req.CreatedAt
+	}
+
+	if req.Category != nil {
+		expense.Category = model.Category(*req.Category)
+	}
+
+	if req.PaymentType != nil {
+		expense.PaymentType = model.PaymentType(*req.PaymentType)
+	}
+
+	if req.Description != nil {
+		expense.Description = strings.TrimSpace(*req.Description)
+	}
+
+	if req.Amount != nil {
+		expense.Amount = *req.Amount
+	}
+
+	if req.Currency != nil && *req.Currency != "" {
+		currency, ok := model.NormalizeCurrency(*req.Currency)
+		if !ok {
+			return fmt.Errorf("%w: unknown currency %q", errInvalidExpense, *req.Currency)
+		}
+
+		expense.Currency = currency
+	}
+
+	return validateExpense(*expense, categories)
+}
+
+func validateExpense(expense model.Expense, categories *model.CategoryRegistry) error {
+	if !categories.IsValid(expense.Category) {
+		return fmt.Errorf("%w: unknown category %d", errInvalidExpense, expense.Category)
+	}
+
+	if !expense.PaymentType.IsValid() {
+		return fmt.Errorf("%w: unknown payment type %d", errInvalidExpense, expense.PaymentType)
+	}
+
+	if !expense.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", errInvalidExpense)
+	}
+
+	return nil
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("json encode", "error", err)
+	}
+}
+
+// writeDatabaseError отвечает 404 для несуществующих и удаленных трат, иначе 500.
+func writeDatabaseError(ctx context.Context, w http.ResponseWriter, op string, err error) {
+	if errors.Is(err, model.ErrNotFound) {
+		writeErrorWithCode(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, model.ErrForbidden) {
+		writeErrorWithCode(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+		return
+	}
+
+	slog.ErrorContext(ctx, op, "error", err)
+	writeError(w, "failed to "+op)
+}
+
+func expenseIDParam(w http.ResponseWriter, r *http.Request) (model.ExpenseID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// projectExpense возвращает трату, только если она из проекта запроса и пользователь может ее видеть.
+func projectExpense(ctx context.Context, db Database, id model.ExpenseID) (model.Expense, error) {
+	expense, err := db.Get(ctx, id)
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	if !visibleExpense(ctx, expense) {
+		return model.Expense{}, model.ErrNotFound
+	}
+
+	return expense, nil
+}
+
+// visibleExpense сообщает, что трата из проекта запроса и пользователь может ее видеть.
+func visibleExpense(ctx context.Context, expense model.Expense) bool {
+	userID, _ := userIDFromContext(ctx)
+
+	return expense.ProjectID == projectIDFromContext(ctx) && roleFromContext(ctx).CanView(userID, expense)
+}
+
+// editableExpense возвращает трату проекта запроса, если пользователь может ее исправить,
+// иначе model.ErrForbidden.
+func editableExpense(ctx context.Context, db Database, id model.ExpenseID) (model.Expense, error) {
+	expense, err := projectExpense(ctx, db, id)
+	if err != nil {
+		return model.Expense{}, err
+	}
+
+	if !canEdit(ctx, expense) {
+		return model.Expense{}, model.ErrForbidden
+	}
+
+	return expense, nil
+}
+
+func canEdit(ctx context.Context, expense model.Expense) bool {
+	userID, _ := userIDFromContext(ctx)
+
+	return roleFromContext(ctx).CanEdit(userID, expense)
+}
+
+func getExpenseHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, ok := expenseIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		expense, err := projectExpense(ctx, db, id)
+		if err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
+		w.Header().Set("Last-Modified", expense.UpdatedAt.UTC().Format(http.TimeFormat))
+		writeJSON(w, http.StatusOK, newExpenseResponse(db.CategoryRegistry(), expense))
+	}
+}
+
+func createExpenseHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() { _ = r.Body.Close() }()
+
+		ctx := r.Context()
+
+		var req expenseRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if req.Category == nil {
+			category := byte(model.CategoryUnexpected)
+			req.Category = &category
+		}
+
+		now := time.Now().Truncate(time.Second)
+		userID, _ := userIDFromContext(ctx)
+
+		expense := model.Expense{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    userID,
+			ProjectID: projectIDFromContext(ctx),
+		}
+
+		if err := req.apply(&expense, db.CategoryRegistry(), false); err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if expense.Currency == "" {
+			project, err := db.Project(ctx, expense.ProjectID)
+			if err != nil {
+				writeDatabaseError(ctx, w, "get project", err)
+
+				return
+			}
+
+			expense.Currency = project.Currency
+		}
+
+		if err := db.Insert(ctx, expense); err != nil {
+			writeDatabaseError(ctx, w, "insert expense", err)
+
+			return
+		}
+
+		w.Header().Set("Location", "/v1/expenses/"+expense.ID.String())
+		writeJSON(w, http.StatusCreated, newExpenseResponse(db.CategoryRegistry(), expense))
+	}
+}
+
+// updateExpenseHandler обрабатывает PUT (полная замена) и PATCH (частичное обновление).
+// Дата создания и автор траты сохраняются, если не переданы явно.
+func updateExpenseHandler(db Database, partial bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() { _ = r.Body.Close() }()
+
+		ctx := r.Context()
+
+		id, ok := expenseIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		var req expenseRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		expense, err := editableExpense(ctx, db, id)
+		if err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
+		if err := req.apply(&expense, db.CategoryRegistry(), partial); err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		expense.UpdatedAt = time.Now().Truncate(time.Second)
+
+		if err := db.Update(ctx, expense); err != nil {
+			writeDatabaseError(ctx, w, "update expense", err)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newExpenseResponse(db.CategoryRegistry(), expense))
+	}
+}
+
+func restoreExpenseHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, ok := expenseIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		expense, err := db.GetDeleted(ctx, id)
+		if err == nil && !visibleExpense(ctx, expense) {
+			err = model.ErrNotFound
+		}
+
+		if err == nil && !canEdit(ctx, expense) {
+			err = model.ErrForbidden
+		}
+
+		if err != nil {
+			writeDatabaseError(ctx, w, "get deleted expense", err)
+
+			return
+		}
+
+		if err := db.Restore(ctx, id); err != nil {
+			writeDatabaseError(ctx, w, "restore expense", err)
+
+			return
+		}
+
+		expense, err = db.Get(ctx, id)
+		if err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newExpenseResponse(db.CategoryRegistry(), expense))
+	}
+}
+
+func deleteExpenseHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, ok := expenseIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		if _, err := editableExpense(ctx, db, id); err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
+		if err := db.Delete(ctx, id); err != nil {
+			writeDatabaseError(ctx, w, "delete expense", err)
 
 			return
 		}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kudadeli/database"
+	"kudadeli/export"
+)
+
+// exportHandler выгружает траты в CSV или XLSX (параметр format). Принимает те же
+// параметры фильтра, что и GET /v1/expenses; даты в файле — в часовом поясе tz.
+func exportHandler(db Database, defaultLoc *time.Location) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		format, ok := export.ParseFormat(query.Get("format"))
+		if !ok {
+			writeErrorWithCode(w, "format must be csv or xlsx", http.StatusBadRequest)
+
+			return
+		}
+
+		filter, err := parseExpenseFilter(r, db.CategoryRegistry(), defaultLoc)
+		if err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		loc, err := locationParam(query, defaultLoc)
+		if err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		expenses, _, err := db.Find(ctx, filter)
+		if errors.Is(err, database.ErrInvalidCursor) {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "db.Find", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		// Собираем файл целиком, чтобы при ошибке успеть ответить 500.
+		var buf bytes.Buffer
+
+		if err := export.Write(&buf, format, expenses, db.CategoryRegistry(), loc); err != nil {
+			slog.ErrorContext(ctx, "export.Write", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", format.ContentType())
+		h.Set("Content-Length", strconv.Itoa(buf.Len()))
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": format.FileName(time.Now().In(loc)),
+		}))
+		h.Set("Cache-Control", "no-store")
+
+		if _, err := buf.WriteTo(w); err != nil {
+			slog.ErrorContext(ctx, "write export", "error", err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"kudadeli/model"
+)
+
+// historyHandler отдает журнал изменений траты текущего проекта, в том числе удаленной.
+func historyHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, ok := expenseIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		_, err := projectExpense(ctx, db, id)
+		if errors.Is(err, model.ErrNotFound) {
+			var expense model.Expense
+
+			expense, err = db.GetDeleted(ctx, id)
+			if err == nil && !visibleExpense(ctx, expense) {
+				err = model.ErrNotFound
+			}
+		}
+
+		if err != nil {
+			writeDatabaseError(ctx, w, "get expense", err)
+
+			return
+		}
+
+		entries, err := db.History(ctx, id)
+		if err != nil {
+			writeDatabaseError(ctx, w, "get history", err)
+
+			return
+		}
+
+		if entries == nil {
+			entries = []model.HistoryEntry{}
+		}
+
+		writeJSON(w, http.StatusOK, entries)
+	}
+}
@@ -0,0 +1,21 @@
+package web
+
+import "net/http"
+
+// projectsHandler отдает проекты пользователя.
+func projectsHandler(db Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID, _ := userIDFromContext(ctx)
+
+		projects, err := db.UserProjects(ctx, userID)
+		if err != nil {
+			writeDatabaseError(ctx, w, "list projects", err)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, projects)
+	}
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"kudadeli/model"
+)
+
+// categoryTotalResponse — итог по категории в ответе API: категория отдается названием.
+type categoryTotalResponse struct {
+	model.CategoryTotal
+	Category string `json:"category"`
+}
+
+type statsResponse struct {
+	model.Stats
+	ByCategory []categoryTotalResponse `json:"byCategory"`
+}
+
+func newStatsResponse(categories *model.CategoryRegistry, stats model.Stats) statsResponse {
+	result := statsResponse{Stats: stats, ByCategory: make([]categoryTotalResponse, len(stats.ByCategory))}
+
+	for i, t := range stats.ByCategory {
+		result.ByCategory[i] = categoryTotalResponse{CategoryTotal: t, Category: categories.Name(t.Category)}
+	}
+
+	return result
+}
+
+// statsHandler отдает агрегаты трат. Принимает те же параметры фильтра, что и
+// GET /v1/expenses, часовой пояс бакетов задается параметром tz.
+func statsHandler(db Database, defaultLoc *time.Location) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		h := w.Header()
+
+		filter, err := parseExpenseFilter(r, db.CategoryRegistry(), defaultLoc)
+		if err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		loc, err := locationParam(r.URL.Query(), defaultLoc)
+		if err != nil {
+			writeErrorWithCode(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		lastModified, err := db.ProjectChangedAt(ctx, projectIDFromContext(ctx))
+		if err != nil {
+			slog.ErrorContext(ctx, "db.ProjectChangedAt:", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		// Новый курс меняет пересчитанные суммы без изменения трат.
+		ratesUpdatedAt, err := db.RatesUpdatedAt(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "db.RatesUpdatedAt:", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		if ratesUpdatedAt.After(lastModified) {
+			lastModified = ratesUpdatedAt
+		}
+
+		if checkNotModified(w, r, projectIDFromContext(ctx), lastModified) {
+			return
+		}
+
+		stats, err := db.Stats(ctx, filter, loc)
+		if err != nil {
+			slog.ErrorContext(ctx, "db.Stats", "error", err)
+			writeError(w, err.Error())
+
+			return
+		}
+
+		project, err := db.Project(ctx, projectIDFromContext(ctx))
+		if err != nil {
+			writeDatabaseError(ctx, w, "get project", err)
+
+			return
+		}
+
+		stats.Currency = project.Currency
+
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		h.Set("Cache-Control", "private, must-revalidate")
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+
+		if err := json.NewEncoder(w).Encode(newStatsResponse(db.CategoryRegistry(), stats)); err != nil {
+			slog.ErrorContext(ctx, "json encode", "error", err)
+			writeError(w, err.Error())
+		}
+	}
+}
@@ -3,6 +3,9 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"kudadeli/database"
 	"kudadeli/model"
 	"log/slog"
 	"net"
@@ -18,10 +21,34 @@ const (
 	readHeaderTimeout = 2 * time.Second
 )
 
+var ErrNoToken = errors.New("bot token is required to validate Mini App requests")
+
 type Database interface {
-	List(ctx context.Context, limit int) (model.Expenses, error)
-	LatestUpdatedAt(ctx context.Context) (time.Time, error)
+	Find(ctx context.Context, filter database.ExpenseFilter) (model.Expenses, string, error)
+	Get(ctx context.Context, id model.ExpenseID) (model.Expense, error)
+	Insert(ctx context.Context, expense model.Expense) error
+	Update(ctx context.Context, expense model.Expense) error
+	Delete(ctx context.Context, id model.ExpenseID) error
+	GetDeleted(ctx context.Context, id model.ExpenseID) (model.Expense, error)
+	Restore(ctx context.Context, id model.ExpenseID) error
+	History(ctx context.Context, id model.ExpenseID) ([]model.HistoryEntry, error)
+	ProjectChangedAt(ctx context.Context, projectID model.ProjectID) (time.Time, error)
 	UpdateCategory(ctx context.Context, expenseID model.ExpenseID, category model.Category) error
+	Budgets(ctx context.Context, projectID model.ProjectID) (model.Budgets, error)
+	CategoryRegistry() *model.CategoryRegistry
+	Stats(ctx context.Context, filter database.ExpenseFilter, loc *time.Location) (model.Stats, error)
+	RatesUpdatedAt(ctx context.Context) (time.Time, error)
+	Attachments(ctx context.Context, expenseID model.ExpenseID) ([]model.Attachment, error)
+	Attachment(ctx context.Context, expenseID model.ExpenseID, id model.AttachmentID) (model.Attachment, []byte, error)
+	Project(ctx context.Context, id model.ProjectID) (model.Project, error)
+	UserProjects(ctx context.Context, userID int64) ([]model.Project, error)
+	IsProjectMember(ctx context.Context, projectID model.ProjectID, userID int64) (bool, error)
+	ActiveProject(ctx context.Context, userID int64) (model.Project, error)
+	Categories(ctx context.Context) ([]model.CategoryInfo, error)
+	AddCategory(ctx context.Context, category model.CategoryInfo) (model.CategoryInfo, error)
+	EditCategory(ctx context.Context, category model.CategoryInfo) error
+	UserRole(ctx context.Context, userID int64) (model.Role, error)
+	APIToken(ctx context.Context, secret string) (model.APIToken, error)
 }
 
 func newServer(ctx context.Context, addr string) *http.Server {
@@ -52,25 +79,45 @@ func writeErrorWithCode(w http.ResponseWriter, err string, statusCode int) {
 	}
 }
 
-func isNotModified(clientSince, lastModified time.Time) bool {
-	if lastModified.IsZero() {
+// projectETag — валидатор ответа по проекту: без projectId в запросе один и тот же URL
+// после /project отдает другой проект, и одного времени изменения мало.
+func projectETag(projectID model.ProjectID, lastModified time.Time) string {
+	return fmt.Sprintf(`W/"%d-%d"`, projectID, lastModified.UnixMilli())
+}
+
+// checkNotModified ставит ETag проекта и отвечает 304, если он совпал с If-None-Match.
+// If-Modified-Since не проверяется: время изменения другого проекта может оказаться
+// раньше, и клиент получил бы 304 с данными прежнего проекта.
+func checkNotModified(w http.ResponseWriter, r *http.Request, projectID model.ProjectID,
+	lastModified time.Time) bool {
+	etag := projectETag(projectID, lastModified)
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") != etag {
 		return false
 	}
 
-	clientSinceUTC := clientSince.UTC()
-	lastModifiedUTC := lastModified.UTC()
+	slog.DebugContext(r.Context(), "not modified", "path", r.URL.Path, "etag", etag)
+	w.WriteHeader(http.StatusNotModified)
 
-	return !lastModifiedUTC.After(clientSinceUTC)
+	return true
 }
 
+// New создает HTTP-сервер. Все маршруты /v1 требуют авторизации: init data Mini App
+// проверяется токеном бота token, поэтому без него сервер не запускается.
 func New(ctx context.Context, db Database, addr string, allowedOrigins []string,
-	authEnable bool, allowedUsers []int64, token string) (*http.Server, error) {
+	token string, loc *time.Location) (*http.Server, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+
 	fs := http.FileServer(http.FS(publicFiles))
 
 	c := cors.New(cors.Options{
 		AllowedOrigins:   allowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Next-Cursor", "Content-Disposition"},
 		AllowCredentials: true,
 	})
 
@@ -82,16 +129,38 @@ func New(ctx context.Context, db Database, addr string, allowedOrigins []string,
 		v1.Use(c.Handler)
 		v1.Use(middleware.Timeout(2 * time.Second))
 
-		v1.Get("/expenses", expensesHandler(db))
-
-		if authEnable {
-			v1.With(authMiddleware(token, allowedUsers, time.Hour)).
-				Put("/expenses/{id}/category", updateExpenseCategoryHandler(db))
-		} else {
-			v1.Put("/expenses/{id}/category", updateExpenseCategoryHandler(db))
-		}
-
-		v1.Get("/categories", categoriesHandler())
+		auth := authMiddleware(db, token, time.Hour)
+
+		// Чтение доступно любой роли: подрядчик видит только свои траты.
+		v1.Group(func(ro chi.Router) {
+			ro.Use(auth, roleMiddleware(db), projectMiddleware(db))
+
+			ro.Get("/categories", categoriesHandler(db))
+
+			ro.Get("/projects", projectsHandler(db))
+			ro.Get("/expenses", expensesHandler(db, loc, false))
+			ro.Get("/expenses/deleted", expensesHandler(db, loc, true))
+			ro.Get("/expenses/{id}", getExpenseHandler(db))
+			ro.Get("/expenses/{id}/history", historyHandler(db))
+			ro.Get("/expenses/{id}/attachments", attachmentsHandler(db))
+			ro.Get("/expenses/{id}/attachments/{attachmentId}", attachmentHandler(db))
+			ro.With(requirePermission(model.PermissionViewAll)).Get("/budgets", budgetsHandler(db))
+			ro.Get("/stats", statsHandler(db, loc))
+			ro.Get("/export", exportHandler(db, loc))
+		})
+
+		v1.Group(func(w chi.Router) {
+			w.Use(auth, writeScopeMiddleware, roleMiddleware(db), projectMiddleware(db), actorMiddleware)
+
+			w.With(requirePermission(model.PermissionRecord)).Post("/expenses", createExpenseHandler(db))
+			w.Put("/expenses/{id}", updateExpenseHandler(db, false))
+			w.Patch("/expenses/{id}", updateExpenseHandler(db, true))
+			w.Delete("/expenses/{id}", deleteExpenseHandler(db))
+			w.Post("/expenses/{id}/restore", restoreExpenseHandler(db))
+			w.Put("/expenses/{id}/category", updateExpenseCategoryHandler(db))
+			w.With(requirePermission(model.PermissionAdmin)).Post("/categories", createCategoryHandler(db))
+			w.With(requirePermission(model.PermissionAdmin)).Patch("/categories/{id}", updateCategoryHandler(db))
+		})
 	})
 
 	srv := newServer(ctx, addr)
@@ -0,0 +1,216 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kudadeli/database"
+	"kudadeli/model"
+	"kudadeli/web"
+)
+
+func TestNewRequiresToken(t *testing.T) {
+	_, err := web.New(context.Background(), nil, ":0", nil, "", time.UTC)
+	require.ErrorIs(t, err, web.ErrNoToken)
+}
+
+func TestAuth(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_web_auth.db"
+	defer os.Remove(tmpFile)
+
+	db, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer db.Close()
+
+	require.NoError(t, db.EnsureOwners(ctx, []int64{1}))
+
+	_, secret, err := db.CreateAPIToken(ctx, 1, "ro", model.ScopeRead, nil)
+	require.NoError(t, err)
+
+	srv, err := web.New(ctx, db, ":0", nil, "test-token", time.UTC)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		auth   string
+		status int
+	}{
+		{"anonymous read", http.MethodGet, "/v1/expenses", "", "", http.StatusUnauthorized},
+		{"anonymous write", http.MethodPut, "/v1/expenses/" + uuid.NewString(), "{}", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/v1/expenses", "", "Bearer nope", http.StatusUnauthorized},
+		{"invalid init data", http.MethodGet, "/v1/expenses", "", "tma user=1", http.StatusUnauthorized},
+		{"read scope read", http.MethodGet, "/v1/expenses", "", "Bearer " + secret, http.StatusOK},
+		{"read scope write", http.MethodPut, "/v1/expenses/" + uuid.NewString(), "{}", "Bearer " + secret,
+			http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			rec := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestExpenseCategoryName(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_web_categories.db"
+	defer os.Remove(tmpFile)
+
+	db, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer db.Close()
+
+	require.NoError(t, db.EnsureOwners(ctx, []int64{1}))
+
+	_, secret, err := db.CreateAPIToken(ctx, 1, "ro", model.ScopeRead, nil)
+	require.NoError(t, err)
+
+	windows, err := db.AddCategory(ctx, model.CategoryInfo{Name: "Окна"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, db.Insert(ctx, model.Expense{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Category:    windows.ID,
+		Amount:      decimal.NewFromInt(100),
+		PaymentType: model.PaymentTypeCard,
+		UserID:      1,
+	}))
+
+	srv, err := web.New(ctx, db, ":0", nil, "test-token", time.UTC)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/expenses", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+
+	rec := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var expenses []struct {
+		Category string `json:"category"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &expenses))
+	require.Len(t, expenses, 1)
+	assert.Equal(t, "Окна", expenses[0].Category, "category name comes from the database registry")
+
+	t.Run("Rename invalidates cached responses", func(t *testing.T) {
+		for _, path := range []string{"/v1/expenses", "/v1/stats"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer "+secret)
+
+			before := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(before, req)
+			require.Equal(t, http.StatusOK, before.Code, before.Body.String())
+
+			time.Sleep(5 * time.Millisecond)
+
+			windows.Name = "Окна " + path
+			require.NoError(t, db.EditCategory(ctx, windows))
+
+			req.Header.Set("If-None-Match", before.Header().Get("ETag"))
+			req.Header.Set("If-Modified-Since", before.Header().Get("Last-Modified"))
+
+			after := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(after, req)
+			require.Equal(t, http.StatusOK, after.Code, path)
+			assert.Contains(t, after.Body.String(), windows.Name)
+		}
+	})
+}
+
+func TestNotModifiedAfterProjectSwitch(t *testing.T) {
+	ctx := context.Background()
+
+	tmpFile := "test_web_cache.db"
+	defer os.Remove(tmpFile)
+
+	db, err := database.New(ctx, tmpFile, time.UTC)
+	require.NoError(t, err, "failed to create database")
+
+	defer db.Close()
+
+	require.NoError(t, db.EnsureOwners(ctx, []int64{1}))
+
+	_, secret, err := db.CreateAPIToken(ctx, 1, "ro", model.ScopeRead, nil)
+	require.NoError(t, err)
+
+	other, err := db.CreateProject(ctx, "Дача", "RUB", 1)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, db.Insert(ctx, model.Expense{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Category:    model.CategoryMaterials,
+		Amount:      decimal.NewFromInt(100),
+		PaymentType: model.PaymentTypeCard,
+		UserID:      1,
+	}))
+
+	srv, err := web.New(ctx, db, ":0", nil, "test-token", time.UTC)
+	require.NoError(t, err)
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header = header.Clone()
+		req.Header.Set("Authorization", "Bearer "+secret)
+
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	for _, path := range []string{"/v1/expenses", "/v1/stats"} {
+		t.Run(path, func(t *testing.T) {
+			require.NoError(t, db.SetActiveProject(ctx, 1, model.DefaultProjectID))
+
+			first := get(path, http.Header{})
+			require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+			require.NotEmpty(t, first.Header().Get("ETag"))
+
+			validators := http.Header{}
+			validators.Set("If-None-Match", first.Header().Get("ETag"))
+			validators.Set("If-Modified-Since", first.Header().Get("Last-Modified"))
+
+			assert.Equal(t, http.StatusNotModified, get(path, validators).Code)
+
+			// Проект «Дача» не менялся дольше, чем проект по умолчанию, но это другие данные.
+			require.NoError(t, db.SetActiveProject(ctx, 1, other.ID))
+
+			assert.Equal(t, http.StatusOK, get(path, validators).Code)
+		})
+	}
+}
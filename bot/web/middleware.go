@@ -1,67 +1,249 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
-	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	initdata "github.com/telegram-mini-apps/init-data-golang"
+
+	"kudadeli/database"
+	"kudadeli/model"
+)
+
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	projectIDKey
+	roleKey
+	tokenScopeKey
 )
 
-func authMiddleware(token string, allowedUsers []int64, expIn time.Duration) func(next http.Handler) http.Handler {
+// userIDFromContext возвращает ID пользователя Telegram, прошедшего authMiddleware.
+func userIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey).(int64)
+
+	return userID, ok
+}
+
+// projectIDFromContext возвращает проект запроса, выбранный projectMiddleware.
+func projectIDFromContext(ctx context.Context) model.ProjectID {
+	if projectID, ok := ctx.Value(projectIDKey).(model.ProjectID); ok {
+		return projectID
+	}
+
+	return model.DefaultProjectID
+}
+
+// roleFromContext возвращает роль пользователя, найденную roleMiddleware.
+func roleFromContext(ctx context.Context) model.Role {
+	role, _ := ctx.Value(roleKey).(model.Role)
+
+	return role
+}
+
+// roleMiddleware находит роль пользователя, прошедшего авторизацию. Запрос без пользователя
+// или пользователь без роли получает 403: прав по умолчанию нет ни у кого.
+func roleMiddleware(db Database) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-
 			ctx := r.Context()
 
-			authParts := strings.Split(authHeader, " ")
-			if len(authParts) != 2 {
-				slog.ErrorContext(ctx, "malformed Authorization header: expected format '<type> <token>'")
-				writeErrorWithCode(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			userID, ok := userIDFromContext(ctx)
+			if !ok {
+				writeErrorWithCode(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 
 				return
 			}
 
-			authType := authParts[0]
-			authData := authParts[1]
+			role, err := db.UserRole(ctx, userID)
+			if errors.Is(err, model.ErrNotFound) {
+				slog.WarnContext(ctx, "forbidden: user has no role", "user_id", userID)
+				writeErrorWithCode(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 
-			if authType != "tma" {
-				slog.ErrorContext(ctx, "invalid authorization type: expected 'tma'")
-				writeErrorWithCode(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			if err != nil {
+				writeDatabaseError(ctx, w, "get user role", err)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, roleKey, role)))
+		})
+	}
+}
+
+// requirePermission пропускает запрос, только если у роли пользователя есть разрешение.
+func requirePermission(permission model.Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !roleFromContext(r.Context()).Can(permission) {
+				writeErrorWithCode(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 
 				return
 			}
 
-			// Validate init data (valid 1 hour)
-			if err := initdata.Validate(authData, token, expIn); err != nil {
-				slog.ErrorContext(ctx, "initdata.Validate", "error", err.Error())
-				writeErrorWithCode(w, err.Error(), http.StatusUnauthorized)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// projectMiddleware выбирает проект запроса: из параметра projectId, иначе активный
+// проект пользователя. Чужой проект для пользователя — 404. Идет после roleMiddleware,
+// поэтому пользователь в запросе уже есть.
+func projectMiddleware(db Database) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			userID, ok := userIDFromContext(ctx)
+			if !ok {
+				writeErrorWithCode(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 
 				return
 			}
 
-			if len(allowedUsers) > 0 {
-				// Parse init data
-				initData, err := initdata.Parse(authData)
+			param := r.URL.Query().Get("projectId")
+			if param == "" {
+				project, err := db.ActiveProject(ctx, userID)
 				if err != nil {
-					slog.ErrorContext(ctx, "initdata.Parse", "error", err.Error())
-					writeError(w, err.Error())
+					writeDatabaseError(ctx, w, "get active project", err)
 
 					return
 				}
 
-				if !slices.Contains(allowedUsers, initData.User.ID) {
-					slog.WarnContext(ctx, "unauthorized: user ID is not in the allowed users", "user_id", initData.User.ID)
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, projectIDKey, project.ID)))
+
+				return
+			}
+
+			projectID, err := strconv.ParseInt(param, 10, 64)
+			if err != nil || projectID <= 0 {
+				writeErrorWithCode(w, "invalid parameter: projectId="+param, http.StatusBadRequest)
+
+				return
+			}
+
+			isMember, err := db.IsProjectMember(ctx, projectID, userID)
+			if err != nil {
+				writeDatabaseError(ctx, w, "check project member", err)
+
+				return
+			}
+
+			if !isMember {
+				writeDatabaseError(ctx, w, "check project member", model.ErrNotFound)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, projectIDKey, projectID)))
+		})
+	}
+}
+
+// actorMiddleware помечает изменения трат в запросе пользователем веба, для журнала.
+func actorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, _ := userIDFromContext(ctx)
+
+		next.ServeHTTP(w, r.WithContext(database.WithActor(ctx, database.Actor{
+			UserID: userID,
+			Source: model.SourceWeb,
+		})))
+	})
+}
+
+// tmaUserID проверяет init data Telegram Mini App и возвращает ID пользователя.
+func tmaUserID(ctx context.Context, authData, token string, expIn time.Duration) (int64, bool) {
+	// Validate init data (valid 1 hour)
+	if err := initdata.Validate(authData, token, expIn); err != nil {
+		slog.ErrorContext(ctx, "initdata.Validate", "error", err.Error())
+
+		return 0, false
+	}
+
+	initData, err := initdata.Parse(authData)
+	if err != nil {
+		slog.ErrorContext(ctx, "initdata.Parse", "error", err.Error())
+
+		return 0, false
+	}
+
+	return initData.User.ID, true
+}
+
+// authMiddleware принимает init data Telegram Mini App («tma») и персональные
+// API-токены («Bearer»). Область токена запоминается, ее проверяет writeScopeMiddleware.
+func authMiddleware(db Database, token string, expIn time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			authType, authData, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !ok || authData == "" {
+				slog.ErrorContext(ctx, "malformed Authorization header: expected format '<type> <token>'")
+				writeErrorWithCode(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			switch authType {
+			case "tma":
+				userID, ok := tmaUserID(ctx, authData, token, expIn)
+				if !ok {
+					writeErrorWithCode(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+					return
+				}
+
+				ctx = context.WithValue(ctx, userIDKey, userID)
+			case "Bearer":
+				apiToken, err := db.APIToken(ctx, authData)
+				if errors.Is(err, model.ErrNotFound) {
+					slog.WarnContext(ctx, "unknown or expired api token")
 					writeErrorWithCode(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 
 					return
 				}
+
+				if err != nil {
+					writeDatabaseError(ctx, w, "get api token", err)
+
+					return
+				}
+
+				ctx = context.WithValue(ctx, userIDKey, apiToken.UserID)
+				ctx = context.WithValue(ctx, tokenScopeKey, apiToken.Scope)
+			default:
+				slog.ErrorContext(ctx, "invalid authorization type: expected 'tma' or 'Bearer'")
+				writeErrorWithCode(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// writeScopeMiddleware не пускает изменения по токену только для чтения.
+func writeScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scope, ok := r.Context().Value(tokenScopeKey).(model.TokenScope); ok && scope != model.ScopeWrite {
+			writeErrorWithCode(w, "token scope does not allow changes", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kudadeli/model"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		role       model.Role
+		permission model.Permission
+		status     int
+	}{
+		{model.RoleOwner, model.PermissionAdmin, http.StatusOK},
+		{model.RoleEditor, model.PermissionEditAll, http.StatusOK},
+		{model.RoleEditor, model.PermissionBudgets, http.StatusOK},
+		{model.RoleEditor, model.PermissionAdmin, http.StatusForbidden},
+		{model.RoleViewer, model.PermissionViewAll, http.StatusOK},
+		{model.RoleViewer, model.PermissionRecord, http.StatusForbidden},
+		{model.RoleViewer, model.PermissionBudgets, http.StatusForbidden},
+		{model.RoleContractor, model.PermissionRecord, http.StatusOK},
+		{model.RoleContractor, model.PermissionViewAll, http.StatusForbidden},
+		{model.RoleContractor, model.PermissionEditAll, http.StatusForbidden},
+		{"", model.PermissionViewAll, http.StatusForbidden},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(context.WithValue(req.Context(), roleKey, tt.role))
+
+			rec := httptest.NewRecorder()
+			requirePermission(tt.permission)(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code, "permission %d", tt.permission)
+		})
+	}
+}